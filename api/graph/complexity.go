@@ -0,0 +1,129 @@
+package graph
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/vapor05/financeview/graph/generated"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+// DefaultListCosts stand in for how many items each list field usually
+// returns, keyed by Type.field. A list field costs its cost times the
+// complexity of the fields selected on its items. Fields returning one
+// object that take a list's worth of work to resolve, like running a saved
+// view, cost their cost plus the complexity of the fields selected on it.
+var DefaultListCosts = map[string]int{
+	"Expense.Categories":              5,
+	"Expense.Tags":                    5,
+	"Expense.Attachments":             3,
+	"Payee.Aliases":                   5,
+	"Query.ledgers":                   5,
+	"Query.apiTokens":                 5,
+	"Query.members":                   10,
+	"Query.invitations":               10,
+	"Query.expenses":                  50,
+	"Query.searchExpenses":            20,
+	"Query.suggestCategories":         5,
+	"Query.payees":                    20,
+	"Query.summary":                   20,
+	"Query.incomes":                   20,
+	"Query.outstandingReimbursements": 20,
+	"Query.trash":                     20,
+	"Query.auditLog":                  50,
+	"Query.expenseHistory":            20,
+	"Query.savedViews":                10,
+	"Query.runSavedView":              10,
+	"Query.budgets":                   20,
+	"Query.taxSummary":                20,
+	"TaxGroup.Expenses":               20,
+	"TaxSummary.Groups":               5,
+	"ViewResult.Expenses":             50,
+	"ViewResult.Summary":              20,
+}
+
+// Complexity returns the complexity functions charging list fields their
+// cost, with costs overriding DefaultListCosts.
+func Complexity(costs map[string]int) (generated.ComplexityRoot, error) {
+	cost := make(map[string]int, len(DefaultListCosts))
+	for f, n := range DefaultListCosts {
+		cost[f] = n
+	}
+	var unknown []string
+	for f, n := range costs {
+		if _, ok := cost[f]; !ok {
+			unknown = append(unknown, f)
+			continue
+		}
+		cost[f] = n
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return generated.ComplexityRoot{}, fmt.Errorf("no list fields named %v", unknown)
+	}
+	list := func(f string) func(int) int {
+		n := cost[f]
+		return func(child int) int {
+			return 1 + n*child
+		}
+	}
+	object := func(f string) func(int) int {
+		n := cost[f]
+		return func(child int) int {
+			return n + child
+		}
+	}
+
+	var c generated.ComplexityRoot
+	c.Expense.Categories = list("Expense.Categories")
+	c.Expense.Tags = list("Expense.Tags")
+	c.Expense.Attachments = list("Expense.Attachments")
+	c.Payee.Aliases = list("Payee.Aliases")
+	c.Query.Ledgers = list("Query.ledgers")
+	c.Query.APITokens = list("Query.apiTokens")
+	c.Query.Members = list("Query.members")
+	c.Query.Invitations = list("Query.invitations")
+	c.Query.Payees = list("Query.payees")
+	c.Query.Incomes = list("Query.incomes")
+	c.Query.OutstandingReimbursements = list("Query.outstandingReimbursements")
+	c.Query.Trash = list("Query.trash")
+	c.Query.SavedViews = list("Query.savedViews")
+	c.Query.Budgets = list("Query.budgets")
+	c.ViewResult.Expenses = list("ViewResult.Expenses")
+	c.ViewResult.Summary = list("ViewResult.Summary")
+	c.TaxGroup.Expenses = list("TaxGroup.Expenses")
+	c.TaxSummary.Groups = list("TaxSummary.Groups")
+	expenses := list("Query.expenses")
+	c.Query.Expenses = func(child int, _ *model.ExpenseFilter) int {
+		return expenses(child)
+	}
+	search := list("Query.searchExpenses")
+	c.Query.SearchExpenses = func(child int, _ string, _ *model.ExpenseFilter, _ *int) int {
+		return search(child)
+	}
+	suggest := list("Query.suggestCategories")
+	c.Query.SuggestCategories = func(child int, _ string, _ *float64) int {
+		return suggest(child)
+	}
+	summary := list("Query.summary")
+	c.Query.Summary = func(child int, _ model.SummaryGroupBy, _ *model.ExpenseFilter) int {
+		return summary(child)
+	}
+	auditLog := list("Query.auditLog")
+	c.Query.AuditLog = func(child int, _ *model.AuditFilter, _ *int) int {
+		return auditLog(child)
+	}
+	history := list("Query.expenseHistory")
+	c.Query.ExpenseHistory = func(child int, _ int) int {
+		return history(child)
+	}
+	runView := object("Query.runSavedView")
+	c.Query.RunSavedView = func(child int, _ int) int {
+		return runView(child)
+	}
+	taxSummary := object("Query.taxSummary")
+	c.Query.TaxSummary = func(child int, _ int) int {
+		return taxSummary(child)
+	}
+	return c, nil
+}
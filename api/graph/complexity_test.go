@@ -0,0 +1,60 @@
+package graph
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/generated"
+)
+
+func TestComplexityLimit(t *testing.T) {
+	c, err := Complexity(nil)
+	if err != nil {
+		t.Fatalf("error running Complexity func, %v", err)
+	}
+	// The resolver has no database, so any query that isn't rejected before
+	// it runs fails.
+	h := handler.New(generated.NewExecutableSchema(generated.Config{
+		Resolvers:  &Resolver{},
+		Directives: generated.DirectiveRoot{HasRole: HasRole},
+		Complexity: c,
+	}))
+	h.AddTransport(transport.POST{})
+	h.Use(extension.FixedComplexityLimit(1000))
+	post := func(query string) map[string]interface{} {
+		b, _ := json.Marshal(map[string]string{"query": query})
+		r := httptest.NewRequest("POST", "/query", strings.NewReader(string(b)))
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		var resp map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response, %v", err)
+		}
+		return resp
+	}
+
+	resp := post(`{ expenses { Id Categories { Id Name } Tags { Id Name } Attachments { Id Url } } }`)
+	assert.Nil(t, resp["data"])
+	if errs, ok := resp["errors"].([]interface{}); assert.True(t, ok) && assert.Len(t, errs, 1) {
+		assert.Equal(t, "operation has complexity 1501, which exceeds the limit of 1000", errs[0].(map[string]interface{})["message"])
+	}
+
+	resp = post(`{ runSavedView(id: 1) { Expenses { Id Categories { Id Name } Tags { Id Name } Attachments { Id Url } } } }`)
+	if errs, ok := resp["errors"].([]interface{}); assert.True(t, ok) && assert.Len(t, errs, 1) {
+		assert.Equal(t, "operation has complexity 1511, which exceeds the limit of 1000", errs[0].(map[string]interface{})["message"])
+	}
+
+	resp = post(`{ __typename }`)
+	assert.Nil(t, resp["errors"])
+	assert.Equal(t, map[string]interface{}{"__typename": "Query"}, resp["data"])
+
+	_, err = Complexity(map[string]int{"Query.expense": 5})
+	assert.Error(t, err)
+}
@@ -0,0 +1,37 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/auth"
+	"github.com/vapor05/financeview/pkg/ledger"
+)
+
+// RoleError is returned for fields the user's role in the ledger doesn't
+// grant.
+type RoleError struct {
+	Need model.Role
+}
+
+func (e *RoleError) Error() string {
+	return fmt.Sprintf("requires the %s role in this ledger", e.Need)
+}
+
+// HasRole implements the @hasRole directive, resolving a field only when the
+// user's role in the selected ledger grants at least the required role.
+func HasRole(ctx context.Context, obj interface{}, next graphql.Resolver, role model.Role) (interface{}, error) {
+	if _, ok := auth.UserFromContext(ctx); !ok {
+		return nil, auth.ErrUnauthenticated
+	}
+	_, have, ok := ledger.FromContext(ctx)
+	if !ok {
+		return nil, ledger.ErrNoLedger
+	}
+	if !ledger.Allows(have, role) {
+		return nil, &RoleError{Need: role}
+	}
+	return next(ctx)
+}
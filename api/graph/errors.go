@@ -0,0 +1,139 @@
+package graph
+
+import (
+	"context"
+	"errors"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vapor05/financeview/pkg/attachment"
+	"github.com/vapor05/financeview/pkg/auth"
+	"github.com/vapor05/financeview/pkg/expense"
+	"github.com/vapor05/financeview/pkg/ledger"
+	"github.com/vapor05/financeview/pkg/logging"
+	"github.com/vapor05/financeview/pkg/payee"
+	"github.com/vapor05/financeview/pkg/reimbursement"
+	"github.com/vapor05/financeview/pkg/store"
+	"github.com/vapor05/financeview/pkg/view"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// Codes put in the extensions.code of resolver errors.
+const (
+	CodeBadUserInput    = "BAD_USER_INPUT"
+	CodeNotFound        = "NOT_FOUND"
+	CodeConflict        = "CONFLICT"
+	CodeUnauthenticated = "UNAUTHENTICATED"
+	CodeForbidden       = "FORBIDDEN"
+	CodeInternal        = "INTERNAL"
+	// CodeBadRequest covers requests breaking a rule of the ledger, such as
+	// removing its last owner.
+	CodeBadRequest = "BAD_REQUEST"
+)
+
+// PresentError is the gqlgen error presenter. It gives resolver errors a
+// code, replaces the chain of "failed to" messages with the cause and adds
+// the field of invalid input. Database and file errors, and any error
+// classify doesn't know, are logged and sent to the client only as an
+// internal error.
+func PresentError(ctx context.Context, err error) *gqlerror.Error {
+	path := graphql.GetPath(ctx)
+	var gerr *gqlerror.Error
+	if errors.As(err, &gerr) {
+		// graphql.AddError puts errors on a path before presenting them.
+		if gerr.Unwrap() == nil || gerr.Extensions != nil {
+			return gerr
+		}
+		err, path = gerr.Unwrap(), gerr.Path
+	}
+	gerr = gqlerror.WrapPath(path, err)
+	code, msg := classify(err)
+	if code == CodeInternal {
+		logging.FromContext(ctx).Error("internal error", "path", gerr.Path.String(), "err", err)
+	}
+	gerr.Message = msg
+	gerr.Extensions = map[string]interface{}{"code": code}
+	var ve *expense.ValidationError
+	if errors.As(err, &ve) {
+		gerr.Extensions["field"] = ve.Field
+	}
+	return gerr
+}
+
+// SplitValidationErrors is field middleware sending each of a resolver's
+// ValidationErrors as its own error, so clients get a code and field for
+// every invalid input field.
+func SplitValidationErrors(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	res, err := next(ctx)
+	var ves expense.ValidationErrors
+	if err == nil || !errors.As(err, &ves) || len(ves) == 0 {
+		return res, err
+	}
+	for _, ve := range ves[1:] {
+		graphql.AddError(ctx, ve)
+	}
+	return res, ves[0]
+}
+
+// classify returns the code and client message of an error.
+func classify(err error) (string, string) {
+	var ve *expense.ValidationError
+	var ves expense.ValidationErrors
+	var enf *expense.NotFoundError
+	var snf *store.NotFoundError
+	var ce *store.ConflictError
+	var re *RoleError
+	switch {
+	case errors.As(err, &ve):
+		return CodeBadUserInput, ve.Error()
+	case errors.As(err, &ves):
+		return CodeBadUserInput, ves.Error()
+	case errors.As(err, &enf):
+		return CodeNotFound, enf.Error()
+	case errors.As(err, &snf):
+		return CodeNotFound, snf.Error()
+	case errors.Is(err, attachment.ErrNotFound):
+		return CodeNotFound, attachment.ErrNotFound.Error()
+	case errors.Is(err, view.ErrNotFound):
+		return CodeNotFound, view.ErrNotFound.Error()
+	case errors.Is(err, payee.ErrNotFound):
+		return CodeNotFound, payee.ErrNotFound.Error()
+	case errors.Is(err, payee.ErrAliasNotFound):
+		return CodeNotFound, payee.ErrAliasNotFound.Error()
+	case errors.Is(err, reimbursement.ErrIncomeNotFound):
+		return CodeNotFound, reimbursement.ErrIncomeNotFound.Error()
+	case errors.Is(err, ledger.ErrMemberNotFound):
+		return CodeNotFound, ledger.ErrMemberNotFound.Error()
+	case errors.As(err, &ce):
+		// Violated constraints name tables and columns; conflicts the
+		// store found itself are safe to show.
+		if ce.Constraint != "" {
+			return CodeConflict, "conflicts with an existing record"
+		}
+		return CodeConflict, ce.Err.Error()
+	case errors.Is(err, auth.ErrUnauthenticated):
+		return CodeUnauthenticated, auth.ErrUnauthenticated.Error()
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		return CodeUnauthenticated, auth.ErrInvalidCredentials.Error()
+	case errors.Is(err, auth.ErrRegistrationFailed):
+		return CodeBadRequest, auth.ErrRegistrationFailed.Error()
+	case errors.Is(err, auth.ErrLogoutToken):
+		return CodeBadRequest, auth.ErrLogoutToken.Error()
+	case errors.Is(err, auth.ErrForbidden):
+		return CodeForbidden, auth.ErrForbidden.Error()
+	case errors.Is(err, auth.ErrReadOnly):
+		return CodeForbidden, auth.ErrReadOnly.Error()
+	case errors.Is(err, ledger.ErrNotMember):
+		return CodeForbidden, ledger.ErrNotMember.Error()
+	case errors.Is(err, ledger.ErrNoLedger):
+		return CodeBadRequest, ledger.ErrNoLedger.Error()
+	case errors.Is(err, ledger.ErrLastOwner):
+		return CodeBadRequest, ledger.ErrLastOwner.Error()
+	case errors.Is(err, ledger.ErrInvitation):
+		return CodeBadRequest, ledger.ErrInvitation.Error()
+	case errors.As(err, &re):
+		return CodeForbidden, re.Error()
+	}
+	// Database and file errors, and any error without a type, may hold
+	// details meant only for the logs.
+	return CodeInternal, "internal error"
+}
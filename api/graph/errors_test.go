@@ -0,0 +1,139 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/auth"
+	"github.com/vapor05/financeview/pkg/expense"
+	"github.com/vapor05/financeview/pkg/ledger"
+	"github.com/vapor05/financeview/pkg/payee"
+	"github.com/vapor05/financeview/pkg/store"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+func TestPresentError(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		code string
+		msg  string
+	}{
+		{
+			name: "validation",
+			err:  fmt.Errorf("failed to save input new expense, %w", &expense.ValidationError{Field: "date", Message: "bad"}),
+			code: CodeBadUserInput,
+			msg:  "invalid date, bad",
+		},
+		{
+			name: "validation list",
+			err:  fmt.Errorf("failed to save input new expense, %w", expense.ValidationErrors{{Field: "date", Message: "bad"}, {Field: "amount", Message: "zero"}}),
+			code: CodeBadUserInput,
+			msg:  "invalid date, bad; invalid amount, zero",
+		},
+		{
+			name: "expense not found",
+			err:  fmt.Errorf("failed to update expense, %w", &expense.NotFoundError{Id: 3}),
+			code: CodeNotFound,
+			msg:  "expense id=3 does not exist",
+		},
+		{
+			name: "store not found",
+			err:  fmt.Errorf("failed to delete payee, %w", &store.NotFoundError{Msg: "payee id=4 does not exist"}),
+			code: CodeNotFound,
+			msg:  "payee id=4 does not exist",
+		},
+		{
+			name: "constraint",
+			err:  fmt.Errorf("failed to create category, %w", &store.ConflictError{Constraint: "category_name_key", Err: errors.New("duplicate key")}),
+			code: CodeConflict,
+			msg:  "conflicts with an existing record",
+		},
+		{
+			name: "unauthenticated",
+			err:  fmt.Errorf("failed to list ledgers, %w", auth.ErrUnauthenticated),
+			code: CodeUnauthenticated,
+			msg:  "not authenticated",
+		},
+		{
+			name: "role",
+			err:  &RoleError{Need: model.RoleOwner},
+			code: CodeForbidden,
+			msg:  "requires the OWNER role in this ledger",
+		},
+		{
+			name: "database",
+			err:  fmt.Errorf("failed to query expense table, %w", fmt.Errorf("ERROR: column e.foo does not exist, %w", store.ErrInternal)),
+			code: CodeInternal,
+			msg:  "internal error",
+		},
+		{
+			name: "file",
+			err:  fmt.Errorf("failed to store attachment, %w", &fs.PathError{Op: "open", Path: "/srv/attachments/ab", Err: fs.ErrPermission}),
+			code: CodeInternal,
+			msg:  "internal error",
+		},
+		{
+			name: "rule",
+			err:  fmt.Errorf("failed to remove member, %w", ledger.ErrLastOwner),
+			code: CodeBadRequest,
+			msg:  "a ledger must keep at least one owner",
+		},
+		{
+			name: "payee not found",
+			err:  fmt.Errorf("failed to merge payees, %w", payee.ErrNotFound),
+			code: CodeNotFound,
+			msg:  "payee not found",
+		},
+		{
+			name: "untyped",
+			err:  fmt.Errorf("failed to render report, %w", errors.New("template: report:3: unexpected EOF")),
+			code: CodeInternal,
+			msg:  "internal error",
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			gerr := PresentError(context.Background(), c.err)
+			assert.Equal(t, c.msg, gerr.Message)
+			assert.Equal(t, c.code, gerr.Extensions["code"])
+		})
+	}
+	t.Run("field", func(t *testing.T) {
+		gerr := PresentError(context.Background(), &expense.ValidationError{Field: "amount", Message: "bad"})
+		assert.Equal(t, "amount", gerr.Extensions["field"])
+	})
+	t.Run("gqlgen error", func(t *testing.T) {
+		orig := gqlerror.Errorf("unknown field")
+		assert.Same(t, orig, PresentError(context.Background(), orig))
+	})
+}
+
+func TestSplitValidationErrors(t *testing.T) {
+	ctx := graphql.WithResponseContext(context.Background(), PresentError, graphql.DefaultRecover)
+	_, err := SplitValidationErrors(ctx, func(ctx context.Context) (interface{}, error) {
+		return nil, fmt.Errorf("failed to save input new expense, %w", expense.ValidationErrors{
+			{Field: "description", Message: "must not be empty"},
+			{Field: "amount", Message: "must not be zero"},
+			{Field: "categories", Message: "must not be empty"},
+		})
+	})
+	gerr := PresentError(ctx, err)
+	assert.Equal(t, "description", gerr.Extensions["field"])
+	var fields []interface{}
+	for _, e := range graphql.GetErrors(ctx) {
+		assert.Equal(t, CodeBadUserInput, e.Extensions["code"])
+		fields = append(fields, e.Extensions["field"])
+	}
+	assert.Equal(t, []interface{}{"amount", "categories"}, fields)
+
+	want := errors.New("boom")
+	_, err = SplitValidationErrors(ctx, func(ctx context.Context) (interface{}, error) { return nil, want })
+	assert.Same(t, want, err)
+}
@@ -0,0 +1,13 @@
+package model
+
+type Attachment struct {
+	Id          int
+	ExpenseId   int
+	Filename    string
+	ContentType string
+	Size        int
+	Sha256      string
+	Url         string
+	CreateDate  string
+	Key         string
+}
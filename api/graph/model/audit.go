@@ -0,0 +1,13 @@
+package model
+
+type AuditEntry struct {
+	Id         int
+	Entity     AuditEntity
+	EntityId   int
+	ExpenseId  int
+	Action     AuditAction
+	Actor      *User
+	Before     *string
+	After      *string
+	CreateDate string
+}
@@ -0,0 +1,7 @@
+package model
+
+type Budget struct {
+	Id       int
+	Category Category
+	Amount   float64
+}
@@ -1,6 +1,7 @@
 package model
 
 type Category struct {
-	Id   int
-	Name string
+	Id          int
+	Name        string
+	TaxCategory *TaxCategory
 }
@@ -6,5 +6,34 @@ type Expense struct {
 	Description string
 	Amount      float64
 	Categories  []Category
+	Tags        []Tag
 	Comment     string
+	Payee       *Payee
+
+	Reimbursable          bool
+	ReimbursementStatus   *ReimbursementStatus
+	ReimbursementIncomeId int
+	TaxCategory           *TaxCategory
+	DeletedAt             *string
+}
+
+// ExpenseChange is sent to subscribers when an expense changes. Expense is
+// nil when it was deleted.
+type ExpenseChange struct {
+	Kind      ExpenseChangeKind
+	ExpenseId int
+	Expense   *Expense
+}
+
+// CreateExpenseResult is the outcome of one createExpenses input. Expense is
+// nil when the input was not saved.
+type CreateExpenseResult struct {
+	Index   int
+	Expense *Expense
+	Errors  []*InputError
+}
+
+type InputError struct {
+	Field   string
+	Message string
 }
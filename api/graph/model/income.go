@@ -0,0 +1,9 @@
+package model
+
+type Income struct {
+	Id          int
+	Date        string
+	Description string
+	Amount      float64
+	Comment     string
+}
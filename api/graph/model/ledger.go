@@ -0,0 +1,21 @@
+package model
+
+type Ledger struct {
+	Id   int
+	Name string
+	Role Role
+}
+
+type LedgerMember struct {
+	User User
+	Role Role
+}
+
+type Invitation struct {
+	Id        int
+	LedgerId  int
+	Email     string
+	Role      Role
+	Token     string
+	ExpiresAt string
+}
@@ -2,10 +2,554 @@
 
 package model
 
+import (
+	"fmt"
+	"io"
+	"strconv"
+)
+
+type AuditFilter struct {
+	Entity   *AuditEntity `json:"entity"`
+	EntityID *int         `json:"entityId"`
+	ActorID  *int         `json:"actorId"`
+}
+
+type Credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+type ExpenseFilter struct {
+	Tags                []string              `json:"tags"`
+	ReimbursementStatus []ReimbursementStatus `json:"reimbursementStatus"`
+	Categories          []string              `json:"categories"`
+	From                *string               `json:"from"`
+	To                  *string               `json:"to"`
+	Period              *Period               `json:"period"`
+	MinAmount           *float64              `json:"minAmount"`
+	MaxAmount           *float64              `json:"maxAmount"`
+}
+
+type NewAPIToken struct {
+	Name          string     `json:"name"`
+	Scope         TokenScope `json:"scope"`
+	ExpiresInDays *int       `json:"expiresInDays"`
+}
+
 type NewExpense struct {
-	Date        string   `json:"date"`
-	Description string   `json:"description"`
-	Amount      float64  `json:"amount"`
-	Categories  []string `json:"categories"`
-	Comment     *string  `json:"comment"`
+	Date         string   `json:"date"`
+	Description  string   `json:"description"`
+	Amount       float64  `json:"amount"`
+	Categories   []string `json:"categories"`
+	Tags         []string `json:"tags"`
+	Comment      *string  `json:"comment"`
+	Reimbursable *bool    `json:"reimbursable"`
+}
+
+type NewIncome struct {
+	Date        string  `json:"date"`
+	Description string  `json:"description"`
+	Amount      float64 `json:"amount"`
+	Comment     *string `json:"comment"`
+}
+
+type NewPayee struct {
+	Name    string   `json:"name"`
+	Aliases []string `json:"aliases"`
+}
+
+type NewSavedView struct {
+	Name    string          `json:"name"`
+	Filter  *ExpenseFilter  `json:"filter"`
+	Sort    *ExpenseSort    `json:"sort"`
+	GroupBy *SummaryGroupBy `json:"groupBy"`
+}
+
+type AuditAction string
+
+const (
+	AuditActionCreate AuditAction = "CREATE"
+	AuditActionUpdate AuditAction = "UPDATE"
+	AuditActionDelete AuditAction = "DELETE"
+)
+
+var AllAuditAction = []AuditAction{
+	AuditActionCreate,
+	AuditActionUpdate,
+	AuditActionDelete,
+}
+
+func (e AuditAction) IsValid() bool {
+	switch e {
+	case AuditActionCreate, AuditActionUpdate, AuditActionDelete:
+		return true
+	}
+	return false
+}
+
+func (e AuditAction) String() string {
+	return string(e)
+}
+
+func (e *AuditAction) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = AuditAction(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid AuditAction", str)
+	}
+	return nil
+}
+
+func (e AuditAction) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type AuditEntity string
+
+const (
+	AuditEntityExpense         AuditEntity = "EXPENSE"
+	AuditEntityCategory        AuditEntity = "CATEGORY"
+	AuditEntityExpenseCategory AuditEntity = "EXPENSE_CATEGORY"
+	AuditEntityExpenseTag      AuditEntity = "EXPENSE_TAG"
+	AuditEntityBudget          AuditEntity = "BUDGET"
+)
+
+var AllAuditEntity = []AuditEntity{
+	AuditEntityExpense,
+	AuditEntityCategory,
+	AuditEntityExpenseCategory,
+	AuditEntityExpenseTag,
+	AuditEntityBudget,
+}
+
+func (e AuditEntity) IsValid() bool {
+	switch e {
+	case AuditEntityExpense, AuditEntityCategory, AuditEntityExpenseCategory, AuditEntityExpenseTag, AuditEntityBudget:
+		return true
+	}
+	return false
+}
+
+func (e AuditEntity) String() string {
+	return string(e)
+}
+
+func (e *AuditEntity) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = AuditEntity(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid AuditEntity", str)
+	}
+	return nil
+}
+
+func (e AuditEntity) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type BatchMode string
+
+const (
+	BatchModeAllOrNothing BatchMode = "ALL_OR_NOTHING"
+	BatchModeBestEffort   BatchMode = "BEST_EFFORT"
+)
+
+var AllBatchMode = []BatchMode{
+	BatchModeAllOrNothing,
+	BatchModeBestEffort,
+}
+
+func (e BatchMode) IsValid() bool {
+	switch e {
+	case BatchModeAllOrNothing, BatchModeBestEffort:
+		return true
+	}
+	return false
+}
+
+func (e BatchMode) String() string {
+	return string(e)
+}
+
+func (e *BatchMode) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = BatchMode(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid BatchMode", str)
+	}
+	return nil
+}
+
+func (e BatchMode) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ExpenseChangeKind string
+
+const (
+	ExpenseChangeKindCreated  ExpenseChangeKind = "CREATED"
+	ExpenseChangeKindUpdated  ExpenseChangeKind = "UPDATED"
+	ExpenseChangeKindDeleted  ExpenseChangeKind = "DELETED"
+	ExpenseChangeKindRestored ExpenseChangeKind = "RESTORED"
+)
+
+var AllExpenseChangeKind = []ExpenseChangeKind{
+	ExpenseChangeKindCreated,
+	ExpenseChangeKindUpdated,
+	ExpenseChangeKindDeleted,
+	ExpenseChangeKindRestored,
+}
+
+func (e ExpenseChangeKind) IsValid() bool {
+	switch e {
+	case ExpenseChangeKindCreated, ExpenseChangeKindUpdated, ExpenseChangeKindDeleted, ExpenseChangeKindRestored:
+		return true
+	}
+	return false
+}
+
+func (e ExpenseChangeKind) String() string {
+	return string(e)
+}
+
+func (e *ExpenseChangeKind) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ExpenseChangeKind(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ExpenseChangeKind", str)
+	}
+	return nil
+}
+
+func (e ExpenseChangeKind) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ExpenseSort string
+
+const (
+	ExpenseSortDateDesc   ExpenseSort = "DATE_DESC"
+	ExpenseSortDateAsc    ExpenseSort = "DATE_ASC"
+	ExpenseSortAmountDesc ExpenseSort = "AMOUNT_DESC"
+	ExpenseSortAmountAsc  ExpenseSort = "AMOUNT_ASC"
+)
+
+var AllExpenseSort = []ExpenseSort{
+	ExpenseSortDateDesc,
+	ExpenseSortDateAsc,
+	ExpenseSortAmountDesc,
+	ExpenseSortAmountAsc,
+}
+
+func (e ExpenseSort) IsValid() bool {
+	switch e {
+	case ExpenseSortDateDesc, ExpenseSortDateAsc, ExpenseSortAmountDesc, ExpenseSortAmountAsc:
+		return true
+	}
+	return false
+}
+
+func (e ExpenseSort) String() string {
+	return string(e)
+}
+
+func (e *ExpenseSort) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ExpenseSort(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ExpenseSort", str)
+	}
+	return nil
+}
+
+func (e ExpenseSort) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type Period string
+
+const (
+	PeriodThisMonth   Period = "THIS_MONTH"
+	PeriodLastMonth   Period = "LAST_MONTH"
+	PeriodThisQuarter Period = "THIS_QUARTER"
+	PeriodLastQuarter Period = "LAST_QUARTER"
+	PeriodThisYear    Period = "THIS_YEAR"
+	PeriodLastYear    Period = "LAST_YEAR"
+)
+
+var AllPeriod = []Period{
+	PeriodThisMonth,
+	PeriodLastMonth,
+	PeriodThisQuarter,
+	PeriodLastQuarter,
+	PeriodThisYear,
+	PeriodLastYear,
+}
+
+func (e Period) IsValid() bool {
+	switch e {
+	case PeriodThisMonth, PeriodLastMonth, PeriodThisQuarter, PeriodLastQuarter, PeriodThisYear, PeriodLastYear:
+		return true
+	}
+	return false
+}
+
+func (e Period) String() string {
+	return string(e)
+}
+
+func (e *Period) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Period(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Period", str)
+	}
+	return nil
+}
+
+func (e Period) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type ReimbursementStatus string
+
+const (
+	ReimbursementStatusPending    ReimbursementStatus = "PENDING"
+	ReimbursementStatusSubmitted  ReimbursementStatus = "SUBMITTED"
+	ReimbursementStatusReimbursed ReimbursementStatus = "REIMBURSED"
+)
+
+var AllReimbursementStatus = []ReimbursementStatus{
+	ReimbursementStatusPending,
+	ReimbursementStatusSubmitted,
+	ReimbursementStatusReimbursed,
+}
+
+func (e ReimbursementStatus) IsValid() bool {
+	switch e {
+	case ReimbursementStatusPending, ReimbursementStatusSubmitted, ReimbursementStatusReimbursed:
+		return true
+	}
+	return false
+}
+
+func (e ReimbursementStatus) String() string {
+	return string(e)
+}
+
+func (e *ReimbursementStatus) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ReimbursementStatus(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ReimbursementStatus", str)
+	}
+	return nil
+}
+
+func (e ReimbursementStatus) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type Role string
+
+const (
+	RoleOwner  Role = "OWNER"
+	RoleEditor Role = "EDITOR"
+	RoleViewer Role = "VIEWER"
+)
+
+var AllRole = []Role{
+	RoleOwner,
+	RoleEditor,
+	RoleViewer,
+}
+
+func (e Role) IsValid() bool {
+	switch e {
+	case RoleOwner, RoleEditor, RoleViewer:
+		return true
+	}
+	return false
+}
+
+func (e Role) String() string {
+	return string(e)
+}
+
+func (e *Role) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = Role(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid Role", str)
+	}
+	return nil
+}
+
+func (e Role) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type SummaryGroupBy string
+
+const (
+	SummaryGroupByCategory SummaryGroupBy = "CATEGORY"
+	SummaryGroupByPayee    SummaryGroupBy = "PAYEE"
+	SummaryGroupByTag      SummaryGroupBy = "TAG"
+)
+
+var AllSummaryGroupBy = []SummaryGroupBy{
+	SummaryGroupByCategory,
+	SummaryGroupByPayee,
+	SummaryGroupByTag,
+}
+
+func (e SummaryGroupBy) IsValid() bool {
+	switch e {
+	case SummaryGroupByCategory, SummaryGroupByPayee, SummaryGroupByTag:
+		return true
+	}
+	return false
+}
+
+func (e SummaryGroupBy) String() string {
+	return string(e)
+}
+
+func (e *SummaryGroupBy) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = SummaryGroupBy(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid SummaryGroupBy", str)
+	}
+	return nil
+}
+
+func (e SummaryGroupBy) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type TaxCategory string
+
+const (
+	TaxCategoryCharitable TaxCategory = "CHARITABLE"
+	TaxCategoryMedical    TaxCategory = "MEDICAL"
+	TaxCategoryBusiness   TaxCategory = "BUSINESS"
+	TaxCategoryEducation  TaxCategory = "EDUCATION"
+	TaxCategoryOther      TaxCategory = "OTHER"
+)
+
+var AllTaxCategory = []TaxCategory{
+	TaxCategoryCharitable,
+	TaxCategoryMedical,
+	TaxCategoryBusiness,
+	TaxCategoryEducation,
+	TaxCategoryOther,
+}
+
+func (e TaxCategory) IsValid() bool {
+	switch e {
+	case TaxCategoryCharitable, TaxCategoryMedical, TaxCategoryBusiness, TaxCategoryEducation, TaxCategoryOther:
+		return true
+	}
+	return false
+}
+
+func (e TaxCategory) String() string {
+	return string(e)
+}
+
+func (e *TaxCategory) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TaxCategory(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TaxCategory", str)
+	}
+	return nil
+}
+
+func (e TaxCategory) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+type TokenScope string
+
+const (
+	TokenScopeRead  TokenScope = "READ"
+	TokenScopeWrite TokenScope = "WRITE"
+	TokenScopeAdmin TokenScope = "ADMIN"
+)
+
+var AllTokenScope = []TokenScope{
+	TokenScopeRead,
+	TokenScopeWrite,
+	TokenScopeAdmin,
+}
+
+func (e TokenScope) IsValid() bool {
+	switch e {
+	case TokenScopeRead, TokenScopeWrite, TokenScopeAdmin:
+		return true
+	}
+	return false
+}
+
+func (e TokenScope) String() string {
+	return string(e)
+}
+
+func (e *TokenScope) UnmarshalGQL(v interface{}) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = TokenScope(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid TokenScope", str)
+	}
+	return nil
+}
+
+func (e TokenScope) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
 }
@@ -0,0 +1,12 @@
+package model
+
+type Payee struct {
+	Id      int
+	Name    string
+	Aliases []PayeeAlias
+}
+
+type PayeeAlias struct {
+	Id      int
+	Pattern string
+}
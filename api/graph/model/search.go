@@ -0,0 +1,7 @@
+package model
+
+type SearchResult struct {
+	Expense Expense
+	Rank    float64
+	Snippet string
+}
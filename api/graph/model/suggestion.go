@@ -0,0 +1,6 @@
+package model
+
+type CategorySuggestion struct {
+	Category Category
+	Score    float64
+}
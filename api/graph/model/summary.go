@@ -0,0 +1,7 @@
+package model
+
+type SummaryRow struct {
+	Key   string
+	Total float64
+	Count int
+}
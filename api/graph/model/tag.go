@@ -0,0 +1,6 @@
+package model
+
+type Tag struct {
+	Id   int
+	Name string
+}
@@ -0,0 +1,14 @@
+package model
+
+type TaxSummary struct {
+	Year   int
+	Total  float64
+	Groups []*TaxGroup
+}
+
+type TaxGroup struct {
+	TaxCategory TaxCategory
+	Total       float64
+	Count       int
+	Expenses    []*Expense
+}
@@ -0,0 +1,21 @@
+package model
+
+type User struct {
+	Id    int
+	Email string
+}
+
+type AuthPayload struct {
+	Token string
+	User  User
+}
+
+type ApiToken struct {
+	Id         int
+	Name       string
+	Scope      TokenScope
+	Token      string
+	ExpiresAt  *string
+	LastUsedAt *string
+	CreateDate string
+}
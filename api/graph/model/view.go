@@ -0,0 +1,15 @@
+package model
+
+type SavedView struct {
+	Id      int
+	Name    string
+	Filter  ExpenseFilter
+	Sort    *ExpenseSort
+	GroupBy *SummaryGroupBy
+}
+
+type ViewResult struct {
+	View     SavedView
+	Expenses []*Expense
+	Summary  []*SummaryRow
+}
@@ -5,9 +5,29 @@ package graph
 // It serves as dependency injection for your app, add any dependencies you require here.
 
 import (
+	"context"
+
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/blob"
+	"github.com/vapor05/financeview/pkg/changes"
+	"github.com/vapor05/financeview/pkg/ledger"
 	"github.com/vapor05/financeview/pkg/store"
+	"github.com/vapor05/financeview/pkg/suggest"
 )
 
 type Resolver struct {
-	Db *store.Database
+	Db        *store.Database
+	Suggester *suggest.Registry
+	Blobs     blob.Store
+	Changes   *changes.Broker
+}
+
+// publish tells the selected ledger's expenseChanged subscribers about a
+// change.
+func (r *Resolver) publish(ctx context.Context, kind model.ExpenseChangeKind, id int, e *model.Expense) {
+	lid, err := ledger.Id(ctx)
+	if err != nil {
+		return
+	}
+	r.Changes.Publish(ctx, lid, model.ExpenseChange{Kind: kind, ExpenseId: id, Expense: e})
 }
@@ -7,32 +7,526 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/vapor05/financeview/graph/generated"
 	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/attachment"
+	"github.com/vapor05/financeview/pkg/audit"
+	"github.com/vapor05/financeview/pkg/auth"
+	"github.com/vapor05/financeview/pkg/budget"
 	"github.com/vapor05/financeview/pkg/expense"
+	"github.com/vapor05/financeview/pkg/income"
+	"github.com/vapor05/financeview/pkg/ledger"
+	"github.com/vapor05/financeview/pkg/payee"
+	"github.com/vapor05/financeview/pkg/reimbursement"
+	"github.com/vapor05/financeview/pkg/search"
+	"github.com/vapor05/financeview/pkg/summary"
+	"github.com/vapor05/financeview/pkg/tax"
+	"github.com/vapor05/financeview/pkg/trash"
+	"github.com/vapor05/financeview/pkg/view"
 )
 
+func (r *expenseResolver) Attachments(ctx context.Context, obj *model.Expense) ([]*model.Attachment, error) {
+	as, err := attachment.ListAttachments(ctx, obj.Id, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expense attachments, %w", err)
+	}
+	return as, nil
+}
+
+func (r *expenseResolver) Reimbursement(ctx context.Context, obj *model.Expense) (*model.Income, error) {
+	if obj.ReimbursementIncomeId == 0 {
+		return nil, nil
+	}
+	i, err := income.GetIncome(ctx, obj.ReimbursementIncomeId, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expense reimbursement, %w", err)
+	}
+	return i, nil
+}
+
+func (r *mutationResolver) Register(ctx context.Context, input model.Credentials) (*model.AuthPayload, error) {
+	p, err := auth.Register(ctx, input, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register user, %w", err)
+	}
+	return &p, nil
+}
+
+func (r *mutationResolver) Login(ctx context.Context, input model.Credentials) (*model.AuthPayload, error) {
+	p, err := auth.Login(ctx, input, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to log in, %w", err)
+	}
+	return &p, nil
+}
+
+func (r *mutationResolver) Logout(ctx context.Context) (bool, error) {
+	if err := auth.Logout(ctx, r.Db); err != nil {
+		return false, fmt.Errorf("failed to log out, %w", err)
+	}
+	return true, nil
+}
+
+func (r *mutationResolver) CreateAPIToken(ctx context.Context, input model.NewAPIToken) (*model.ApiToken, error) {
+	t, err := auth.CreateAPIToken(ctx, input, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API token, %w", err)
+	}
+	return &t, nil
+}
+
+func (r *mutationResolver) RevokeAPIToken(ctx context.Context, id int) (bool, error) {
+	if err := auth.RevokeAPIToken(ctx, id, r.Db); err != nil {
+		return false, fmt.Errorf("failed to revoke API token, %w", err)
+	}
+	return true, nil
+}
+
+func (r *mutationResolver) CreateLedger(ctx context.Context, name string) (*model.Ledger, error) {
+	l, err := ledger.CreateLedger(ctx, name, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create ledger, %w", err)
+	}
+	return &l, nil
+}
+
+func (r *mutationResolver) InviteMember(ctx context.Context, email string, role model.Role) (*model.Invitation, error) {
+	inv, err := ledger.Invite(ctx, email, role, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to invite member, %w", err)
+	}
+	return &inv, nil
+}
+
+func (r *mutationResolver) RevokeInvitation(ctx context.Context, id int) (bool, error) {
+	if err := ledger.RevokeInvitation(ctx, id, r.Db); err != nil {
+		return false, fmt.Errorf("failed to revoke invitation, %w", err)
+	}
+	return true, nil
+}
+
+func (r *mutationResolver) AcceptInvitation(ctx context.Context, token string) (*model.Ledger, error) {
+	l, err := ledger.AcceptInvitation(ctx, token, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to accept invitation, %w", err)
+	}
+	return &l, nil
+}
+
+func (r *mutationResolver) SetMemberRole(ctx context.Context, userID int, role model.Role) (*model.LedgerMember, error) {
+	m, err := ledger.SetMemberRole(ctx, userID, role, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set member role, %w", err)
+	}
+	return &m, nil
+}
+
+func (r *mutationResolver) RemoveMember(ctx context.Context, userID int) (bool, error) {
+	if err := ledger.RemoveMember(ctx, userID, r.Db); err != nil {
+		return false, fmt.Errorf("failed to remove member, %w", err)
+	}
+	return true, nil
+}
+
 func (r *mutationResolver) CreateExpense(ctx context.Context, input model.NewExpense) (*model.Expense, error) {
 	ex, err := expense.SaveExpense(ctx, input, r.Db)
 	if err != nil {
 		return nil, fmt.Errorf("failed to save input new expense, %w", err)
 	}
+	r.Suggester.Observe(ctx, ex)
+	r.publish(ctx, model.ExpenseChangeKindCreated, ex.Id, &ex)
+	return &ex, nil
+}
+
+func (r *mutationResolver) CreateExpenses(ctx context.Context, inputs []*model.NewExpense, mode *model.BatchMode) ([]*model.CreateExpenseResult, error) {
+	m := model.BatchModeAllOrNothing
+	if mode != nil {
+		m = *mode
+	}
+	results, err := expense.SaveExpenses(ctx, inputs, m, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save input new expenses, %w", err)
+	}
+	for _, res := range results {
+		if res.Expense == nil {
+			continue
+		}
+		r.Suggester.Observe(ctx, *res.Expense)
+		r.publish(ctx, model.ExpenseChangeKindCreated, res.Expense.Id, res.Expense)
+	}
+	return results, nil
+}
+
+func (r *mutationResolver) UpdateExpense(ctx context.Context, id int, input model.NewExpense) (*model.Expense, error) {
+	before, _, err := r.Db.GetExpense(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expense id=%v, %w", id, err)
+	}
+	ex, err := expense.UpdateExpense(ctx, id, input, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update expense, %w", err)
+	}
+	r.Suggester.Update(ctx, before, ex)
+	r.publish(ctx, model.ExpenseChangeKindUpdated, ex.Id, &ex)
+	return &ex, nil
+}
+
+func (r *mutationResolver) DeleteExpense(ctx context.Context, id int) (bool, error) {
+	before, _, err := r.Db.GetExpense(ctx, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to get expense id=%v, %w", id, err)
+	}
+	if err := trash.DeleteExpense(ctx, id, r.Db); err != nil {
+		return false, fmt.Errorf("failed to delete expense, %w", err)
+	}
+	r.Suggester.Forget(ctx, before)
+	r.publish(ctx, model.ExpenseChangeKindDeleted, id, nil)
+	return true, nil
+}
+
+func (r *mutationResolver) RestoreExpense(ctx context.Context, id int) (*model.Expense, error) {
+	e, err := trash.RestoreExpense(ctx, id, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore expense, %w", err)
+	}
+	r.Suggester.Observe(ctx, e)
+	r.publish(ctx, model.ExpenseChangeKindRestored, e.Id, &e)
+	return &e, nil
+}
+
+func (r *mutationResolver) CreatePayee(ctx context.Context, input model.NewPayee) (*model.Payee, error) {
+	p, err := payee.SavePayee(ctx, input, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save input new payee, %w", err)
+	}
+	return &p, nil
+}
+
+func (r *mutationResolver) RenamePayee(ctx context.Context, id int, name string) (*model.Payee, error) {
+	p, err := payee.RenamePayee(ctx, id, name, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rename payee, %w", err)
+	}
+	return &p, nil
+}
+
+func (r *mutationResolver) DeletePayee(ctx context.Context, id int) (bool, error) {
+	if err := payee.DeletePayee(ctx, id, r.Db); err != nil {
+		return false, fmt.Errorf("failed to delete payee, %w", err)
+	}
+	return true, nil
+}
+
+func (r *mutationResolver) AddPayeeAlias(ctx context.Context, payeeID int, pattern string) (*model.Payee, error) {
+	p, err := payee.AddAlias(ctx, payeeID, pattern, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add payee alias, %w", err)
+	}
+	return &p, nil
+}
+
+func (r *mutationResolver) RemovePayeeAlias(ctx context.Context, payeeID int, id int) (*model.Payee, error) {
+	p, err := payee.RemoveAlias(ctx, payeeID, id, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove payee alias, %w", err)
+	}
+	return &p, nil
+}
+
+func (r *mutationResolver) MergePayees(ctx context.Context, sourceID int, targetID int) (*model.Payee, error) {
+	p, err := payee.MergePayees(ctx, sourceID, targetID, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge payees, %w", err)
+	}
+	return &p, nil
+}
+
+func (r *mutationResolver) UploadAttachment(ctx context.Context, expenseID int, file graphql.Upload) (*model.Attachment, error) {
+	a, err := attachment.SaveAttachment(ctx, expenseID, file, r.Db, r.Blobs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save uploaded attachment, %w", err)
+	}
+	return &a, nil
+}
+
+func (r *mutationResolver) DeleteAttachment(ctx context.Context, id int) (bool, error) {
+	if err := attachment.DeleteAttachment(ctx, id, r.Db, r.Blobs); err != nil {
+		return false, fmt.Errorf("failed to delete attachment, %w", err)
+	}
+	return true, nil
+}
+
+func (r *mutationResolver) CreateIncome(ctx context.Context, input model.NewIncome) (*model.Income, error) {
+	i, err := income.SaveIncome(ctx, input, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save input new income, %w", err)
+	}
+	return &i, nil
+}
+
+func (r *mutationResolver) SetReimbursementStatus(ctx context.Context, expenseID int, status model.ReimbursementStatus) (*model.Expense, error) {
+	ex, err := reimbursement.SetStatus(ctx, expenseID, status, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set reimbursement status, %w", err)
+	}
+	r.publish(ctx, model.ExpenseChangeKindUpdated, ex.Id, &ex)
+	return &ex, nil
+}
+
+func (r *mutationResolver) ReimburseExpense(ctx context.Context, expenseID int, incomeID int) (*model.Expense, error) {
+	ex, err := reimbursement.Reimburse(ctx, expenseID, incomeID, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reimburse expense, %w", err)
+	}
+	r.publish(ctx, model.ExpenseChangeKindUpdated, ex.Id, &ex)
 	return &ex, nil
 }
 
-func (r *queryResolver) Expenses(ctx context.Context) ([]*model.Expense, error) {
-	exps, err := expense.ListExpenses(ctx, r.Db)
+func (r *mutationResolver) CreateSavedView(ctx context.Context, input model.NewSavedView) (*model.SavedView, error) {
+	v, err := view.CreateView(ctx, input, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create saved view, %w", err)
+	}
+	return &v, nil
+}
+
+func (r *mutationResolver) UpdateSavedView(ctx context.Context, id int, input model.NewSavedView) (*model.SavedView, error) {
+	v, err := view.UpdateView(ctx, id, input, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update saved view, %w", err)
+	}
+	return &v, nil
+}
+
+func (r *mutationResolver) DeleteSavedView(ctx context.Context, id int) (bool, error) {
+	if err := view.DeleteView(ctx, id, r.Db); err != nil {
+		return false, fmt.Errorf("failed to delete saved view, %w", err)
+	}
+	return true, nil
+}
+
+func (r *mutationResolver) SetCategoryTaxCategory(ctx context.Context, name string, taxCategory *model.TaxCategory) (*model.Category, error) {
+	c, err := tax.SetCategory(ctx, name, taxCategory, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set category tax category, %w", err)
+	}
+	return &c, nil
+}
+
+func (r *mutationResolver) SetExpenseTaxCategory(ctx context.Context, expenseID int, taxCategory *model.TaxCategory) (*model.Expense, error) {
+	ex, err := tax.SetExpense(ctx, expenseID, taxCategory, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set expense tax category, %w", err)
+	}
+	r.publish(ctx, model.ExpenseChangeKindUpdated, ex.Id, &ex)
+	return &ex, nil
+}
+
+func (r *mutationResolver) SetBudget(ctx context.Context, category string, amount float64) (*model.Budget, error) {
+	b, err := budget.SetBudget(ctx, category, amount, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set budget, %w", err)
+	}
+	return &b, nil
+}
+
+func (r *mutationResolver) DeleteBudget(ctx context.Context, category string) (bool, error) {
+	if err := budget.DeleteBudget(ctx, category, r.Db); err != nil {
+		return false, fmt.Errorf("failed to delete budget, %w", err)
+	}
+	return true, nil
+}
+
+func (r *queryResolver) Me(ctx context.Context) (*model.User, error) {
+	u, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+	return &u, nil
+}
+
+func (r *queryResolver) Ledgers(ctx context.Context) ([]*model.Ledger, error) {
+	ls, err := ledger.ListLedgers(ctx, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledgers, %w", err)
+	}
+	return ls, nil
+}
+
+func (r *queryResolver) APITokens(ctx context.Context) ([]*model.ApiToken, error) {
+	ts, err := auth.ListAPITokens(ctx, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API tokens, %w", err)
+	}
+	return ts, nil
+}
+
+func (r *queryResolver) Members(ctx context.Context) ([]*model.LedgerMember, error) {
+	ms, err := ledger.ListMembers(ctx, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ledger members, %w", err)
+	}
+	return ms, nil
+}
+
+func (r *queryResolver) Invitations(ctx context.Context) ([]*model.Invitation, error) {
+	is, err := ledger.ListInvitations(ctx, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitations, %w", err)
+	}
+	return is, nil
+}
+
+func (r *queryResolver) Expenses(ctx context.Context, filter *model.ExpenseFilter) ([]*model.Expense, error) {
+	var f model.ExpenseFilter
+	if filter != nil {
+		f = *filter
+	}
+	exps, err := expense.FindExpenses(ctx, f, r.Db)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get expenses, %w", err)
 	}
 	return exps, nil
 }
 
+func (r *queryResolver) SearchExpenses(ctx context.Context, query string, filter *model.ExpenseFilter, limit *int) ([]*model.SearchResult, error) {
+	rs, err := search.Expenses(ctx, query, filter, limit, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search expenses, %w", err)
+	}
+	return rs, nil
+}
+
+func (r *queryResolver) SuggestCategories(ctx context.Context, description string, amount *float64) ([]*model.CategorySuggestion, error) {
+	var amt float64
+	if amount != nil {
+		amt = *amount
+	}
+	m, err := r.Suggester.Model(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category suggestion model, %w", err)
+	}
+	return m.Suggest(description, amt), nil
+}
+
+func (r *queryResolver) Payees(ctx context.Context) ([]*model.Payee, error) {
+	ps, err := payee.ListPayees(ctx, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payees, %w", err)
+	}
+	return ps, nil
+}
+
+func (r *queryResolver) Payee(ctx context.Context, id int) (*model.Payee, error) {
+	p, err := payee.GetPayee(ctx, id, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payee, %w", err)
+	}
+	return p, nil
+}
+
+func (r *queryResolver) Summary(ctx context.Context, groupBy model.SummaryGroupBy, filter *model.ExpenseFilter) ([]*model.SummaryRow, error) {
+	sum, err := summary.Summarize(ctx, groupBy, filter, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expense summary, %w", err)
+	}
+	return sum, nil
+}
+
+func (r *queryResolver) Incomes(ctx context.Context) ([]*model.Income, error) {
+	is, err := income.ListIncomes(ctx, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get income, %w", err)
+	}
+	return is, nil
+}
+
+func (r *queryResolver) OutstandingReimbursements(ctx context.Context) ([]*model.Expense, error) {
+	exps, err := reimbursement.Outstanding(ctx, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outstanding reimbursements, %w", err)
+	}
+	return exps, nil
+}
+
+func (r *queryResolver) Trash(ctx context.Context) ([]*model.Expense, error) {
+	es, err := trash.ListTrash(ctx, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trash, %w", err)
+	}
+	return es, nil
+}
+
+func (r *queryResolver) AuditLog(ctx context.Context, filter *model.AuditFilter, limit *int) ([]*model.AuditEntry, error) {
+	es, err := audit.ListEntries(ctx, filter, limit, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get audit log, %w", err)
+	}
+	return es, nil
+}
+
+func (r *queryResolver) ExpenseHistory(ctx context.Context, id int) ([]*model.AuditEntry, error) {
+	es, err := audit.ExpenseHistory(ctx, id, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expense history, %w", err)
+	}
+	return es, nil
+}
+
+func (r *queryResolver) SavedViews(ctx context.Context) ([]*model.SavedView, error) {
+	vs, err := view.ListViews(ctx, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved views, %w", err)
+	}
+	return vs, nil
+}
+
+func (r *queryResolver) RunSavedView(ctx context.Context, id int) (*model.ViewResult, error) {
+	res, err := view.RunView(ctx, id, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run saved view, %w", err)
+	}
+	return &res, nil
+}
+
+func (r *queryResolver) TaxSummary(ctx context.Context, year int) (*model.TaxSummary, error) {
+	s, err := tax.Summarize(ctx, year, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize deductible expenses, %w", err)
+	}
+	return &s, nil
+}
+
+func (r *queryResolver) Budgets(ctx context.Context) ([]*model.Budget, error) {
+	bs, err := budget.ListBudgets(ctx, r.Db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get budgets, %w", err)
+	}
+	return bs, nil
+}
+
+func (r *subscriptionResolver) ExpenseChanged(ctx context.Context) (<-chan *model.ExpenseChange, error) {
+	lid, err := ledger.Id(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return r.Changes.Subscribe(ctx, lid), nil
+}
+
+// Expense returns generated.ExpenseResolver implementation.
+func (r *Resolver) Expense() generated.ExpenseResolver { return &expenseResolver{r} }
+
 // Mutation returns generated.MutationResolver implementation.
 func (r *Resolver) Mutation() generated.MutationResolver { return &mutationResolver{r} }
 
 // Query returns generated.QueryResolver implementation.
 func (r *Resolver) Query() generated.QueryResolver { return &queryResolver{r} }
 
+// Subscription returns generated.SubscriptionResolver implementation.
+func (r *Resolver) Subscription() generated.SubscriptionResolver { return &subscriptionResolver{r} }
+
+type expenseResolver struct{ *Resolver }
 type mutationResolver struct{ *Resolver }
 type queryResolver struct{ *Resolver }
+type subscriptionResolver struct{ *Resolver }
@@ -0,0 +1,165 @@
+package attachment
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/blob"
+	"github.com/vapor05/financeview/pkg/expense"
+)
+
+// MaxSize is the largest attachment accepted, in bytes.
+const MaxSize = 10 << 20
+
+// AllowedTypes are the MIME types accepted for receipts, detected from the
+// file contents rather than trusted from the client.
+var AllowedTypes = map[string]bool{
+	"application/pdf": true,
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/gif":       true,
+	"image/webp":      true,
+}
+
+var ErrNotFound = errors.New("attachment not found")
+
+type Database interface {
+	ExpenseExists(context.Context, int) (bool, error)
+	CreateAttachment(context.Context, model.Attachment, func() error) (int, error)
+	GetAttachment(context.Context, int) (model.Attachment, bool, error)
+	ListAttachments(context.Context, int) ([]model.Attachment, error)
+	DeleteAttachment(context.Context, int) (string, bool, error)
+	ReleaseStorageKey(context.Context, string, func() error) error
+}
+
+func SaveAttachment(ctx context.Context, eid int, up graphql.Upload, db Database, bs blob.Store) (model.Attachment, error) {
+	ok, err := db.ExpenseExists(ctx, eid)
+	if err != nil {
+		return model.Attachment{}, fmt.Errorf("failed to check expense exists, %w", err)
+	}
+	if !ok {
+		return model.Attachment{}, &expense.NotFoundError{Id: eid}
+	}
+	b, err := io.ReadAll(io.LimitReader(up.File, MaxSize+1))
+	if err != nil {
+		return model.Attachment{}, fmt.Errorf("failed to read uploaded file, %w", err)
+	}
+	if len(b) > MaxSize {
+		return model.Attachment{}, expense.ValidationErrors{{Field: "file", Message: fmt.Sprintf("must not be larger than %d bytes", MaxSize)}}
+	}
+	if len(b) == 0 {
+		return model.Attachment{}, expense.ValidationErrors{{Field: "file", Message: "must not be empty"}}
+	}
+	ct := detectContentType(b)
+	if !AllowedTypes[ct] {
+		return model.Attachment{}, expense.ValidationErrors{{Field: "file", Message: fmt.Sprintf("content type %s is not allowed", ct)}}
+	}
+	sum := sha256.Sum256(b)
+	hash := hex.EncodeToString(sum[:])
+	a := model.Attachment{
+		ExpenseId:   eid,
+		Filename:    cleanFilename(up.Filename),
+		ContentType: ct,
+		Size:        len(b),
+		Sha256:      hash,
+		Key:         hash,
+	}
+	a.Id, err = db.CreateAttachment(ctx, a, func() error {
+		if err := bs.Put(ctx, hash, bytes.NewReader(b)); err != nil {
+			return fmt.Errorf("failed to store attachment, %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return model.Attachment{}, fmt.Errorf("failed to save attachment metadata, %w", err)
+	}
+	a.Url = URL(a.Id)
+	return a, nil
+}
+
+func ListAttachments(ctx context.Context, eid int, db Database) ([]*model.Attachment, error) {
+	as, err := db.ListAttachments(ctx, eid)
+	if err != nil {
+		return []*model.Attachment{}, fmt.Errorf("failed to list attachments, %w", err)
+	}
+	att := []*model.Attachment{}
+	for i := range as {
+		as[i].Url = URL(as[i].Id)
+		att = append(att, &as[i])
+	}
+	return att, nil
+}
+
+// DeleteAttachment removes the attachment and its blob, unless another
+// attachment holds identical content.
+func DeleteAttachment(ctx context.Context, id int, db Database, bs blob.Store) error {
+	key, shared, err := db.DeleteAttachment(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete attachment, %w", err)
+	}
+	if shared {
+		return nil
+	}
+	if err := db.ReleaseStorageKey(ctx, key, func() error { return bs.Delete(ctx, key) }); err != nil {
+		return fmt.Errorf("failed to delete attachment contents, %w", err)
+	}
+	return nil
+}
+
+// Open returns the attachment's metadata and contents. The caller must close
+// the reader.
+func Open(ctx context.Context, id int, db Database, bs blob.Store) (model.Attachment, io.ReadCloser, error) {
+	a, ok, err := db.GetAttachment(ctx, id)
+	if err != nil {
+		return model.Attachment{}, nil, fmt.Errorf("failed to get attachment, %w", err)
+	}
+	if !ok {
+		return model.Attachment{}, nil, ErrNotFound
+	}
+	r, err := bs.Get(ctx, a.Key)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			return model.Attachment{}, nil, ErrNotFound
+		}
+		return model.Attachment{}, nil, fmt.Errorf("failed to open attachment contents, %w", err)
+	}
+	a.Url = URL(a.Id)
+	return a, r, nil
+}
+
+// URL is the download route for an attachment served by server.go.
+func URL(id int) string {
+	return fmt.Sprintf("/attachments/%d", id)
+}
+
+func detectContentType(b []byte) string {
+	ct := http.DetectContentType(b)
+	if i := strings.Index(ct, ";"); i >= 0 {
+		ct = ct[:i]
+	}
+	return ct
+}
+
+func cleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	if name == "." || name == "/" || name == "" {
+		return "attachment"
+	}
+	return name
+}
@@ -0,0 +1,202 @@
+package attachment
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/blob"
+)
+
+type MockDatabase struct {
+	exp map[int]bool
+	att map[int]model.Attachment
+}
+
+func (mdb *MockDatabase) ExpenseExists(ctx context.Context, eid int) (bool, error) {
+	return mdb.exp[eid], nil
+}
+
+func (mdb *MockDatabase) CreateAttachment(ctx context.Context, a model.Attachment, put func() error) (int, error) {
+	if err := put(); err != nil {
+		return 0, err
+	}
+	a.Id = rand.Int()
+	mdb.att[a.Id] = a
+	return a.Id, nil
+}
+
+func (mdb *MockDatabase) GetAttachment(ctx context.Context, id int) (model.Attachment, bool, error) {
+	a, ok := mdb.att[id]
+	return a, ok, nil
+}
+
+func (mdb *MockDatabase) ListAttachments(ctx context.Context, eid int) ([]model.Attachment, error) {
+	var as []model.Attachment
+	for _, a := range mdb.att {
+		if a.ExpenseId == eid {
+			as = append(as, a)
+		}
+	}
+	return as, nil
+}
+
+func (mdb *MockDatabase) DeleteAttachment(ctx context.Context, id int) (string, bool, error) {
+	a, ok := mdb.att[id]
+	if !ok {
+		return "", false, fmt.Errorf("attachment id=%v does not exist", id)
+	}
+	delete(mdb.att, id)
+	for _, o := range mdb.att {
+		if o.Key == a.Key {
+			return a.Key, true, nil
+		}
+	}
+	return a.Key, false, nil
+}
+
+func (mdb *MockDatabase) ReleaseStorageKey(ctx context.Context, key string, del func() error) error {
+	for _, a := range mdb.att {
+		if a.Key == key {
+			return nil
+		}
+	}
+	return del()
+}
+
+type MockBlobs map[string][]byte
+
+func (mb MockBlobs) Put(ctx context.Context, key string, r io.Reader) error {
+	b, err := io.ReadAll(r)
+	mb[key] = b
+	return err
+}
+
+func (mb MockBlobs) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	b, ok := mb[key]
+	if !ok {
+		return nil, blob.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (mb MockBlobs) Delete(ctx context.Context, key string) error {
+	delete(mb, key)
+	return nil
+}
+
+var pdf = []byte("%PDF-1.4\n1 0 obj << /Type /Catalog >> endobj\n")
+
+func upload(name string, b []byte) graphql.Upload {
+	return graphql.Upload{File: bytes.NewReader(b), Filename: name, Size: int64(len(b))}
+}
+
+func TestSaveAttachment(t *testing.T) {
+	t.Run("pdf receipt", func(t *testing.T) {
+		mock := MockDatabase{exp: map[int]bool{3: true}, att: make(map[int]model.Attachment)}
+		blobs := MockBlobs{}
+		actual, err := SaveAttachment(context.Background(), 3, upload("../receipt.pdf", pdf), &mock, blobs)
+		if err != nil {
+			t.Fatalf("error running SaveAttachment func, %v", err)
+		}
+		sum := sha256.Sum256(pdf)
+		hash := hex.EncodeToString(sum[:])
+		want := model.Attachment{
+			Id:          actual.Id,
+			ExpenseId:   3,
+			Filename:    "receipt.pdf",
+			ContentType: "application/pdf",
+			Size:        len(pdf),
+			Sha256:      hash,
+			Key:         hash,
+			Url:         fmt.Sprintf("/attachments/%d", actual.Id),
+		}
+		assert.Equal(t, want, actual)
+		assert.Equal(t, pdf, blobs[hash])
+	})
+	cases := []struct {
+		name string
+		eid  int
+		file graphql.Upload
+	}{
+		{name: "missing expense", eid: 4, file: upload("receipt.pdf", pdf)},
+		{name: "empty file", eid: 3, file: upload("receipt.pdf", nil)},
+		{name: "disallowed type", eid: 3, file: upload("receipt.pdf", []byte("<html><body>hi</body></html>"))},
+		{name: "too large", eid: 3, file: upload("receipt.pdf", append(pdf, make([]byte, MaxSize)...))},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := MockDatabase{exp: map[int]bool{3: true}, att: make(map[int]model.Attachment)}
+			blobs := MockBlobs{}
+			_, err := SaveAttachment(context.Background(), c.eid, c.file, &mock, blobs)
+			assert.Error(t, err)
+			assert.Empty(t, mock.att)
+			assert.Empty(t, blobs)
+		})
+	}
+}
+
+func TestDeleteAttachment(t *testing.T) {
+	mock := MockDatabase{
+		exp: map[int]bool{3: true},
+		att: map[int]model.Attachment{
+			1: {Id: 1, ExpenseId: 3, Key: "abc"},
+			2: {Id: 2, ExpenseId: 3, Key: "abc"},
+		},
+	}
+	blobs := MockBlobs{"abc": pdf}
+	if err := DeleteAttachment(context.Background(), 1, &mock, blobs); err != nil {
+		t.Fatalf("error running DeleteAttachment func, %v", err)
+	}
+	assert.Contains(t, blobs, "abc")
+	if err := DeleteAttachment(context.Background(), 2, &mock, blobs); err != nil {
+		t.Fatalf("error running DeleteAttachment func, %v", err)
+	}
+	assert.NotContains(t, blobs, "abc")
+	assert.Error(t, DeleteAttachment(context.Background(), 2, &mock, blobs))
+}
+
+func TestOpen(t *testing.T) {
+	mock := MockDatabase{
+		att: map[int]model.Attachment{1: {Id: 1, Filename: "receipt.pdf", Key: "abc"}, 2: {Id: 2, Key: "gone"}},
+	}
+	blobs := MockBlobs{"abc": pdf}
+	a, r, err := Open(context.Background(), 1, &mock, blobs)
+	if err != nil {
+		t.Fatalf("error running Open func, %v", err)
+	}
+	defer r.Close()
+	b, _ := io.ReadAll(r)
+	assert.Equal(t, pdf, b)
+	assert.Equal(t, "/attachments/1", a.Url)
+	_, _, err = Open(context.Background(), 2, &mock, blobs)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, _, err = Open(context.Background(), 5, &mock, blobs)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func Test_cleanFilename(t *testing.T) {
+	cases := []struct {
+		input string
+		want  string
+	}{
+		{input: "receipt.pdf", want: "receipt.pdf"},
+		{input: `C:\scans\receipt.pdf`, want: "receipt.pdf"},
+		{input: "../../etc/passwd", want: "passwd"},
+		{input: "bad\"name\n.png", want: "badname.png"},
+		{input: "", want: "attachment"},
+	}
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			assert.Equal(t, c.want, cleanFilename(c.input))
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/expense"
+)
+
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+type Database interface {
+	ListAuditEntries(context.Context, model.AuditFilter, int) ([]model.AuditEntry, error)
+	ExpenseHistory(context.Context, int) ([]model.AuditEntry, error)
+}
+
+// ListEntries returns the ledger's newest audit entries matching the
+// optional filter, at most limit of them.
+func ListEntries(ctx context.Context, filter *model.AuditFilter, limit *int, db Database) ([]*model.AuditEntry, error) {
+	var f model.AuditFilter
+	if filter != nil {
+		f = *filter
+	}
+	n := DefaultLimit
+	if limit != nil {
+		if *limit <= 0 || *limit > MaxLimit {
+			return nil, expense.ValidationErrors{{Field: "limit", Message: fmt.Sprintf("must be between 1 and %d", MaxLimit)}}
+		}
+		n = *limit
+	}
+	es, err := db.ListAuditEntries(ctx, f, n)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit entries, %w", err)
+	}
+	return refs(es), nil
+}
+
+func ExpenseHistory(ctx context.Context, id int, db Database) ([]*model.AuditEntry, error) {
+	es, err := db.ExpenseHistory(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expense history, %w", err)
+	}
+	return refs(es), nil
+}
+
+func refs(es []model.AuditEntry) []*model.AuditEntry {
+	out := make([]*model.AuditEntry, len(es))
+	for i := range es {
+		out[i] = &es[i]
+	}
+	return out
+}
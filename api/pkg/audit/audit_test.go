@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+type MockDatabase struct {
+	entries []model.AuditEntry
+	limit   int
+}
+
+func (mdb *MockDatabase) ListAuditEntries(ctx context.Context, f model.AuditFilter, limit int) ([]model.AuditEntry, error) {
+	mdb.limit = limit
+	var es []model.AuditEntry
+	for i := len(mdb.entries) - 1; i >= 0 && len(es) < limit; i-- {
+		e := mdb.entries[i]
+		if f.Entity != nil && e.Entity != *f.Entity {
+			continue
+		}
+		if f.ActorID != nil && (e.Actor == nil || e.Actor.Id != *f.ActorID) {
+			continue
+		}
+		es = append(es, e)
+	}
+	return es, nil
+}
+
+func (mdb *MockDatabase) ExpenseHistory(ctx context.Context, eid int) ([]model.AuditEntry, error) {
+	var es []model.AuditEntry
+	for _, e := range mdb.entries {
+		if e.ExpenseId == eid {
+			es = append(es, e)
+		}
+	}
+	return es, nil
+}
+
+func testEntries() []model.AuditEntry {
+	bob := &model.User{Id: 1}
+	amy := &model.User{Id: 2}
+	return []model.AuditEntry{
+		{Id: 1, Entity: model.AuditEntityExpense, EntityId: 7, ExpenseId: 7, Action: model.AuditActionCreate, Actor: bob},
+		{Id: 2, Entity: model.AuditEntityCategory, EntityId: 3, Action: model.AuditActionCreate, Actor: bob},
+		{Id: 3, Entity: model.AuditEntityExpenseCategory, EntityId: 9, ExpenseId: 7, Action: model.AuditActionCreate, Actor: bob},
+		{Id: 4, Entity: model.AuditEntityExpense, EntityId: 7, ExpenseId: 7, Action: model.AuditActionUpdate, Actor: amy},
+	}
+}
+
+func TestListEntries(t *testing.T) {
+	mock := MockDatabase{entries: testEntries()}
+	actual, err := ListEntries(context.Background(), nil, nil, &mock)
+	if err != nil {
+		t.Fatalf("error running ListEntries func, %v", err)
+	}
+	assert.Equal(t, DefaultLimit, mock.limit)
+	assert.Len(t, actual, 4)
+	assert.Equal(t, 4, actual[0].Id)
+	ent := model.AuditEntityExpense
+	amy := 2
+	actual, err = ListEntries(context.Background(), &model.AuditFilter{Entity: &ent, ActorID: &amy}, nil, &mock)
+	if err != nil {
+		t.Fatalf("error running ListEntries func, %v", err)
+	}
+	assert.Len(t, actual, 1)
+	assert.Equal(t, model.AuditActionUpdate, actual[0].Action)
+	for _, n := range []int{0, MaxLimit + 1} {
+		_, err = ListEntries(context.Background(), nil, &n, &mock)
+		assert.Error(t, err)
+	}
+}
+
+func TestExpenseHistory(t *testing.T) {
+	mock := MockDatabase{entries: testEntries()}
+	actual, err := ExpenseHistory(context.Background(), 7, &mock)
+	if err != nil {
+		t.Fatalf("error running ExpenseHistory func, %v", err)
+	}
+	var ids []int
+	for _, e := range actual {
+		ids = append(ids, e.Id)
+	}
+	assert.Equal(t, []int{1, 3, 4}, ids)
+}
@@ -0,0 +1,151 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/expense"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// SessionTTL is how long a login token stays valid.
+const SessionTTL = 30 * 24 * time.Hour
+
+const minPasswordLength = 8
+
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
+// ErrRegistrationFailed is returned for registering an email that already
+// has an account, without saying so, so Register can't be used to find out
+// who has one.
+var ErrRegistrationFailed = errors.New("unable to register with this email")
+
+// ErrLogoutToken is returned for logging out with an API token, which only
+// revokeApiToken ends.
+var ErrLogoutToken = errors.New("API tokens are ended with revokeApiToken")
+
+// dummyHash is compared against when no user has the email, so failed logins
+// take the same time whether or not the account exists.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("financeview-dummy-password"), bcrypt.DefaultCost)
+
+type Database interface {
+	CreateUser(context.Context, string, string) (int, error)
+	GetUserByEmail(context.Context, string) (model.User, string, bool, error)
+	CreateSession(context.Context, int, string, time.Time) error
+	GetSessionUser(context.Context, string, time.Time) (model.User, bool, error)
+	DeleteSession(context.Context, string) error
+}
+
+func Register(ctx context.Context, c model.Credentials, db Database) (model.AuthPayload, error) {
+	email, err := NormalizeEmail(c.Email)
+	if err != nil {
+		return model.AuthPayload{}, err
+	}
+	if len(c.Password) < minPasswordLength {
+		return model.AuthPayload{}, expense.ValidationErrors{{Field: "password", Message: fmt.Sprintf("must be at least %d characters", minPasswordLength)}}
+	}
+	_, _, ok, err := db.GetUserByEmail(ctx, email)
+	if err != nil {
+		return model.AuthPayload{}, fmt.Errorf("failed to check for existing user, %w", err)
+	}
+	if ok {
+		return model.AuthPayload{}, ErrRegistrationFailed
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(c.Password), bcrypt.DefaultCost)
+	if err != nil {
+		return model.AuthPayload{}, fmt.Errorf("failed to hash password, %w", err)
+	}
+	uid, err := db.CreateUser(ctx, email, string(hash))
+	if err != nil {
+		return model.AuthPayload{}, fmt.Errorf("failed to create user, %w", err)
+	}
+	return startSession(ctx, model.User{Id: uid, Email: email}, db)
+}
+
+func Login(ctx context.Context, c model.Credentials, db Database) (model.AuthPayload, error) {
+	email, err := NormalizeEmail(c.Email)
+	if err != nil {
+		return model.AuthPayload{}, ErrInvalidCredentials
+	}
+	u, hash, ok, err := db.GetUserByEmail(ctx, email)
+	if err != nil {
+		return model.AuthPayload{}, fmt.Errorf("failed to get user, %w", err)
+	}
+	if !ok {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(c.Password))
+		return model.AuthPayload{}, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(c.Password)); err != nil {
+		return model.AuthPayload{}, ErrInvalidCredentials
+	}
+	return startSession(ctx, u, db)
+}
+
+// Logout ends the session the request was authenticated with.
+func Logout(ctx context.Context, db Database) error {
+	tok, ok := TokenFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+	if IsAPIToken(tok) {
+		return ErrLogoutToken
+	}
+	if err := db.DeleteSession(ctx, HashToken(tok)); err != nil {
+		return fmt.Errorf("failed to end session, %w", err)
+	}
+	return nil
+}
+
+// Authenticate returns the user an unexpired session token belongs to.
+func Authenticate(ctx context.Context, token string, db Database) (model.User, bool, error) {
+	u, ok, err := db.GetSessionUser(ctx, HashToken(token), time.Now().UTC())
+	if err != nil {
+		return model.User{}, false, fmt.Errorf("failed to look up session, %w", err)
+	}
+	return u, ok, nil
+}
+
+// HashToken is what gets stored in place of a token, so a database leak
+// doesn't hand out working credentials.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewToken returns a random URL-safe token.
+func NewToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token, %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func startSession(ctx context.Context, u model.User, db Database) (model.AuthPayload, error) {
+	tok, err := NewToken()
+	if err != nil {
+		return model.AuthPayload{}, err
+	}
+	if err := db.CreateSession(ctx, u.Id, HashToken(tok), time.Now().UTC().Add(SessionTTL)); err != nil {
+		return model.AuthPayload{}, fmt.Errorf("failed to create session, %w", err)
+	}
+	return model.AuthPayload{Token: tok, User: u}, nil
+}
+
+// NormalizeEmail validates a bare email address and lower cases it.
+func NormalizeEmail(email string) (string, error) {
+	a, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil || a.Address != strings.TrimSpace(email) {
+		return "", expense.ValidationErrors{{Field: "email", Message: fmt.Sprintf("%q is not an email address", email)}}
+	}
+	return strings.ToLower(a.Address), nil
+}
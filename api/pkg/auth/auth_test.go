@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/expense"
+)
+
+type session struct {
+	uid     int
+	expires time.Time
+}
+
+type MockDatabase struct {
+	users    map[string]model.User
+	hashes   map[string]string
+	sessions map[string]session
+}
+
+func newMock() *MockDatabase {
+	return &MockDatabase{
+		users:    make(map[string]model.User),
+		hashes:   make(map[string]string),
+		sessions: make(map[string]session),
+	}
+}
+
+func (mdb *MockDatabase) CreateUser(ctx context.Context, email string, hash string) (int, error) {
+	id := len(mdb.users) + 1
+	mdb.users[email] = model.User{Id: id, Email: email}
+	mdb.hashes[email] = hash
+	return id, nil
+}
+
+func (mdb *MockDatabase) GetUserByEmail(ctx context.Context, email string) (model.User, string, bool, error) {
+	u, ok := mdb.users[email]
+	return u, mdb.hashes[email], ok, nil
+}
+
+func (mdb *MockDatabase) CreateSession(ctx context.Context, uid int, hash string, exp time.Time) error {
+	mdb.sessions[hash] = session{uid: uid, expires: exp}
+	return nil
+}
+
+func (mdb *MockDatabase) GetSessionUser(ctx context.Context, hash string, now time.Time) (model.User, bool, error) {
+	s, ok := mdb.sessions[hash]
+	if !ok || !s.expires.After(now) {
+		return model.User{}, false, nil
+	}
+	for _, u := range mdb.users {
+		if u.Id == s.uid {
+			return u, true, nil
+		}
+	}
+	return model.User{}, false, nil
+}
+
+func (mdb *MockDatabase) DeleteSession(ctx context.Context, hash string) error {
+	delete(mdb.sessions, hash)
+	return nil
+}
+
+func TestRegister(t *testing.T) {
+	mock := newMock()
+	actual, err := Register(context.Background(), model.Credentials{Email: "Bob@Example.com", Password: "hunter22"}, mock)
+	if err != nil {
+		t.Fatalf("error running Register func, %v", err)
+	}
+	assert.Equal(t, model.User{Id: 1, Email: "bob@example.com"}, actual.User)
+	assert.NotEmpty(t, actual.Token)
+	assert.Contains(t, mock.sessions, HashToken(actual.Token))
+	assert.NotEqual(t, "hunter22", mock.hashes["bob@example.com"])
+	_, err = Register(context.Background(), model.Credentials{Email: "bob@example.com", Password: "hunter22"}, mock)
+	if assert.ErrorIs(t, err, ErrRegistrationFailed) {
+		assert.NotContains(t, err.Error(), "bob@example.com")
+	}
+	_, err = Register(context.Background(), model.Credentials{Email: "not an email", Password: "hunter22"}, mock)
+	assert.Error(t, err)
+	_, err = Register(context.Background(), model.Credentials{Email: "amy@example.com", Password: "short"}, mock)
+	var ves expense.ValidationErrors
+	if assert.ErrorAs(t, err, &ves) {
+		assert.Equal(t, "password", ves[0].Field)
+	}
+}
+
+func TestLogin(t *testing.T) {
+	mock := newMock()
+	_, err := Register(context.Background(), model.Credentials{Email: "bob@example.com", Password: "hunter22"}, mock)
+	if err != nil {
+		t.Fatalf("error running Register func, %v", err)
+	}
+	actual, err := Login(context.Background(), model.Credentials{Email: "bob@example.com", Password: "hunter22"}, mock)
+	if err != nil {
+		t.Fatalf("error running Login func, %v", err)
+	}
+	assert.Equal(t, model.User{Id: 1, Email: "bob@example.com"}, actual.User)
+	assert.Len(t, mock.sessions, 2)
+	_, err = Login(context.Background(), model.Credentials{Email: "bob@example.com", Password: "wrong-password"}, mock)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	_, err = Login(context.Background(), model.Credentials{Email: "amy@example.com", Password: "hunter22"}, mock)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+}
+
+func TestAuthenticate(t *testing.T) {
+	mock := newMock()
+	p, err := Register(context.Background(), model.Credentials{Email: "bob@example.com", Password: "hunter22"}, mock)
+	if err != nil {
+		t.Fatalf("error running Register func, %v", err)
+	}
+	u, ok, err := Authenticate(context.Background(), p.Token, mock)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, p.User, u)
+	_, ok, err = Authenticate(context.Background(), "bogus", mock)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	mock.sessions[HashToken(p.Token)] = session{uid: 1, expires: time.Now().Add(-time.Minute)}
+	_, ok, err = Authenticate(context.Background(), p.Token, mock)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
+
+func TestLogout(t *testing.T) {
+	mock := newMock()
+	p, err := Register(context.Background(), model.Credentials{Email: "bob@example.com", Password: "hunter22"}, mock)
+	if err != nil {
+		t.Fatalf("error running Register func, %v", err)
+	}
+	assert.ErrorIs(t, Logout(context.Background(), mock), ErrUnauthenticated)
+	ctx := WithUser(context.Background(), p.User, p.Token)
+	assert.ErrorIs(t, Logout(WithUser(context.Background(), p.User, APITokenPrefix+"secret"), mock), ErrLogoutToken)
+	assert.Nil(t, Logout(ctx, mock))
+	_, ok, _ := Authenticate(context.Background(), p.Token, mock)
+	assert.False(t, ok)
+}
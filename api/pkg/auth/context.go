@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/vapor05/financeview/graph/model"
+)
+
+var ErrUnauthenticated = errors.New("not authenticated")
+
+type contextKey int
+
+const (
+	userKey contextKey = iota
+	tokenKey
+	scopeKey
+)
+
+// WithUser returns a context carrying the authenticated user and the token
+// they authenticated with.
+func WithUser(ctx context.Context, u model.User, token string) context.Context {
+	ctx = context.WithValue(ctx, userKey, u)
+	return context.WithValue(ctx, tokenKey, token)
+}
+
+func UserFromContext(ctx context.Context) (model.User, bool) {
+	u, ok := ctx.Value(userKey).(model.User)
+	return u, ok
+}
+
+// UserId returns the authenticated user's id, or ErrUnauthenticated.
+func UserId(ctx context.Context) (int, error) {
+	u, ok := UserFromContext(ctx)
+	if !ok {
+		return 0, ErrUnauthenticated
+	}
+	return u.Id, nil
+}
+
+func TokenFromContext(ctx context.Context) (string, bool) {
+	t, ok := ctx.Value(tokenKey).(string)
+	return t, ok && t != ""
+}
+
+// WithScope marks a context as authenticated by an API token with a scope.
+func WithScope(ctx context.Context, scope model.TokenScope) context.Context {
+	return context.WithValue(ctx, scopeKey, scope)
+}
+
+// ScopeFromContext returns the scope of the API token the request was
+// authenticated with. Session logins have no scope and full access.
+func ScopeFromContext(ctx context.Context) (model.TokenScope, bool) {
+	s, ok := ctx.Value(scopeKey).(model.TokenScope)
+	return s, ok
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/expense"
+)
+
+// APITokenPrefix starts every API token, telling them apart from session
+// tokens in the Authorization header.
+const APITokenPrefix = "fvat_"
+
+var (
+	ErrForbidden = errors.New("API tokens need the ADMIN scope for this")
+	ErrReadOnly  = errors.New("API tokens need the WRITE scope for this")
+)
+
+// scopeRank orders token scopes from least to most allowed.
+var scopeRank = map[model.TokenScope]int{model.TokenScopeRead: 0, model.TokenScopeWrite: 1, model.TokenScopeAdmin: 2}
+
+type TokenDatabase interface {
+	CreateApiToken(context.Context, int, model.ApiToken, string, *time.Time) (int, error)
+	ListApiTokens(context.Context, int) ([]model.ApiToken, error)
+	DeleteApiToken(context.Context, int, int) error
+	GetApiTokenUser(context.Context, string, time.Time) (model.User, model.TokenScope, bool, error)
+}
+
+// IsAPIToken reports whether a bearer token is an API token.
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
+
+// CreateAPIToken issues the user a new API token. The returned token is the
+// only copy; just its hash is stored.
+func CreateAPIToken(ctx context.Context, input model.NewAPIToken, db TokenDatabase) (model.ApiToken, error) {
+	uid, err := requireAdmin(ctx)
+	if err != nil {
+		return model.ApiToken{}, err
+	}
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return model.ApiToken{}, expense.ValidationErrors{{Field: "name", Message: "must not be empty"}}
+	}
+	if !input.Scope.IsValid() {
+		return model.ApiToken{}, expense.ValidationErrors{{Field: "scope", Message: fmt.Sprintf("%s is not a valid token scope", input.Scope)}}
+	}
+	now := time.Now().UTC()
+	t := model.ApiToken{Name: name, Scope: input.Scope, CreateDate: now.Format(time.RFC3339)}
+	var exp *time.Time
+	if input.ExpiresInDays != nil {
+		if *input.ExpiresInDays <= 0 {
+			return model.ApiToken{}, expense.ValidationErrors{{Field: "expiresInDays", Message: "must be at least 1"}}
+		}
+		e := now.AddDate(0, 0, *input.ExpiresInDays)
+		exp = &e
+		es := e.Format(time.RFC3339)
+		t.ExpiresAt = &es
+	}
+	tok, err := NewToken()
+	if err != nil {
+		return model.ApiToken{}, err
+	}
+	t.Token = APITokenPrefix + tok
+	t.Id, err = db.CreateApiToken(ctx, uid, t, HashToken(t.Token), exp)
+	if err != nil {
+		return model.ApiToken{}, fmt.Errorf("failed to create API token, %w", err)
+	}
+	return t, nil
+}
+
+func ListAPITokens(ctx context.Context, db TokenDatabase) ([]*model.ApiToken, error) {
+	uid, err := requireAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ts, err := db.ListApiTokens(ctx, uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API tokens, %w", err)
+	}
+	out := make([]*model.ApiToken, len(ts))
+	for i := range ts {
+		out[i] = &ts[i]
+	}
+	return out, nil
+}
+
+func RevokeAPIToken(ctx context.Context, id int, db TokenDatabase) error {
+	uid, err := requireAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	if err := db.DeleteApiToken(ctx, uid, id); err != nil {
+		return fmt.Errorf("failed to revoke API token, %w", err)
+	}
+	return nil
+}
+
+// AuthenticateAPIToken returns the user and scope of an unexpired API token,
+// recording that it was used.
+func AuthenticateAPIToken(ctx context.Context, token string, db TokenDatabase) (model.User, model.TokenScope, bool, error) {
+	u, s, ok, err := db.GetApiTokenUser(ctx, HashToken(token), time.Now().UTC())
+	if err != nil {
+		return model.User{}, "", false, fmt.Errorf("failed to look up API token, %w", err)
+	}
+	return u, s, ok, nil
+}
+
+// requireAdmin returns the user's id unless the request came with an API
+// token lacking the ADMIN scope, so a leaked script token can't mint more.
+func requireAdmin(ctx context.Context) (int, error) {
+	return RequireScope(ctx, model.TokenScopeAdmin)
+}
+
+// RequireScope returns the user's id unless the request came with an API
+// token whose scope is below min. Changes outside the selected ledger, which
+// @hasRole doesn't cover, check it themselves.
+func RequireScope(ctx context.Context, min model.TokenScope) (int, error) {
+	uid, err := UserId(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if s, ok := ScopeFromContext(ctx); ok && scopeRank[s] < scopeRank[min] {
+		if min == model.TokenScopeAdmin {
+			return 0, ErrForbidden
+		}
+		return 0, ErrReadOnly
+	}
+	return uid, nil
+}
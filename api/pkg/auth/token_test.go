@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+type apiToken struct {
+	uid     int
+	token   model.ApiToken
+	expires *time.Time
+}
+
+type MockTokenDatabase struct {
+	tokens map[string]*apiToken
+}
+
+func (mdb *MockTokenDatabase) CreateApiToken(ctx context.Context, uid int, t model.ApiToken, hash string, exp *time.Time) (int, error) {
+	t.Id = len(mdb.tokens) + 1
+	t.Token = ""
+	mdb.tokens[hash] = &apiToken{uid: uid, token: t, expires: exp}
+	return t.Id, nil
+}
+
+func (mdb *MockTokenDatabase) ListApiTokens(ctx context.Context, uid int) ([]model.ApiToken, error) {
+	var ts []model.ApiToken
+	for _, t := range mdb.tokens {
+		if t.uid == uid {
+			ts = append(ts, t.token)
+		}
+	}
+	return ts, nil
+}
+
+func (mdb *MockTokenDatabase) DeleteApiToken(ctx context.Context, uid int, id int) error {
+	for h, t := range mdb.tokens {
+		if t.uid == uid && t.token.Id == id {
+			delete(mdb.tokens, h)
+		}
+	}
+	return nil
+}
+
+func (mdb *MockTokenDatabase) GetApiTokenUser(ctx context.Context, hash string, now time.Time) (model.User, model.TokenScope, bool, error) {
+	t, ok := mdb.tokens[hash]
+	if !ok || (t.expires != nil && !t.expires.After(now)) {
+		return model.User{}, "", false, nil
+	}
+	used := now.Format(time.RFC3339)
+	t.token.LastUsedAt = &used
+	return model.User{Id: t.uid}, t.token.Scope, true, nil
+}
+
+func TestCreateAPIToken(t *testing.T) {
+	mock := MockTokenDatabase{tokens: make(map[string]*apiToken)}
+	ctx := WithUser(context.Background(), model.User{Id: 1}, "session")
+	days := 30
+	actual, err := CreateAPIToken(ctx, model.NewAPIToken{Name: " nightly import ", Scope: model.TokenScopeWrite, ExpiresInDays: &days}, &mock)
+	if err != nil {
+		t.Fatalf("error running CreateAPIToken func, %v", err)
+	}
+	assert.Equal(t, "nightly import", actual.Name)
+	assert.True(t, strings.HasPrefix(actual.Token, APITokenPrefix))
+	assert.NotNil(t, actual.ExpiresAt)
+	assert.Contains(t, mock.tokens, HashToken(actual.Token))
+	_, err = CreateAPIToken(ctx, model.NewAPIToken{Name: "", Scope: model.TokenScopeRead}, &mock)
+	assert.Error(t, err)
+	zero := 0
+	_, err = CreateAPIToken(ctx, model.NewAPIToken{Name: "x", Scope: model.TokenScopeRead, ExpiresInDays: &zero}, &mock)
+	assert.Error(t, err)
+	_, err = CreateAPIToken(context.Background(), model.NewAPIToken{Name: "x", Scope: model.TokenScopeRead}, &mock)
+	assert.ErrorIs(t, err, ErrUnauthenticated)
+	scoped := WithScope(WithUser(context.Background(), model.User{Id: 1}, actual.Token), model.TokenScopeWrite)
+	_, err = CreateAPIToken(scoped, model.NewAPIToken{Name: "x", Scope: model.TokenScopeAdmin}, &mock)
+	assert.ErrorIs(t, err, ErrForbidden)
+}
+
+func TestAuthenticateAPIToken(t *testing.T) {
+	mock := MockTokenDatabase{tokens: make(map[string]*apiToken)}
+	ctx := WithUser(context.Background(), model.User{Id: 1}, "session")
+	tok, err := CreateAPIToken(ctx, model.NewAPIToken{Name: "reports", Scope: model.TokenScopeRead}, &mock)
+	if err != nil {
+		t.Fatalf("error running CreateAPIToken func, %v", err)
+	}
+	u, s, ok, err := AuthenticateAPIToken(context.Background(), tok.Token, &mock)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, u.Id)
+	assert.Equal(t, model.TokenScopeRead, s)
+	ts, err := ListAPITokens(ctx, &mock)
+	assert.Nil(t, err)
+	assert.Len(t, ts, 1)
+	assert.NotNil(t, ts[0].LastUsedAt)
+	assert.Nil(t, RevokeAPIToken(ctx, tok.Id, &mock))
+	_, _, ok, err = AuthenticateAPIToken(context.Background(), tok.Token, &mock)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,91 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps attachment contents addressed by an opaque key.
+type Store interface {
+	Put(ctx context.Context, key string, r io.Reader) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// LocalStore keeps blobs as files under a directory on the local
+// filesystem.
+type LocalStore struct {
+	Dir string
+}
+
+func NewLocalStore(dir string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory %s, %w", dir, err)
+	}
+	return &LocalStore{Dir: dir}, nil
+}
+
+// Put writes to a temporary file first so a failed upload never leaves a
+// partial blob behind under the key.
+func (ls *LocalStore) Put(ctx context.Context, key string, r io.Reader) error {
+	p, err := ls.path(key)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(ls.Dir, ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary blob file, %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write blob %s, %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write blob %s, %w", key, err)
+	}
+	if err := os.Rename(tmp.Name(), p); err != nil {
+		return fmt.Errorf("failed to store blob %s, %w", key, err)
+	}
+	return nil
+}
+
+func (ls *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	p, err := ls.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to open blob %s, %w", key, err)
+	}
+	return f, nil
+}
+
+func (ls *LocalStore) Delete(ctx context.Context, key string) error {
+	p, err := ls.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete blob %s, %w", key, err)
+	}
+	return nil
+}
+
+func (ls *LocalStore) path(key string) (string, error) {
+	if key == "" || strings.ContainsAny(key, `/\`) || strings.HasPrefix(key, ".") {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(ls.Dir, key), nil
+}
@@ -0,0 +1,47 @@
+package blob
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStore(t *testing.T) {
+	ctx := context.Background()
+	ls, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("error running NewLocalStore func, %v", err)
+	}
+	if err := ls.Put(ctx, "abc123", strings.NewReader("receipt")); err != nil {
+		t.Fatalf("error running Put func, %v", err)
+	}
+	r, err := ls.Get(ctx, "abc123")
+	if err != nil {
+		t.Fatalf("error running Get func, %v", err)
+	}
+	b, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatalf("failed to read blob, %v", err)
+	}
+	assert.Equal(t, "receipt", string(b))
+	if err := ls.Delete(ctx, "abc123"); err != nil {
+		t.Fatalf("error running Delete func, %v", err)
+	}
+	_, err = ls.Get(ctx, "abc123")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Nil(t, ls.Delete(ctx, "abc123"))
+}
+
+func TestLocalStoreInvalidKey(t *testing.T) {
+	ls, err := NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("error running NewLocalStore func, %v", err)
+	}
+	for _, k := range []string{"", "../etc/passwd", "a/b", ".hidden"} {
+		assert.Error(t, ls.Put(context.Background(), k, strings.NewReader("x")), k)
+	}
+}
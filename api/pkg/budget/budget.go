@@ -0,0 +1,57 @@
+package budget
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/expense"
+)
+
+type Database interface {
+	SetBudget(context.Context, string, float64) (model.Budget, error)
+	DeleteBudget(context.Context, string) error
+	ListBudgets(context.Context) ([]model.Budget, error)
+}
+
+// SetBudget sets how much the ledger means to spend on an existing category
+// each month.
+func SetBudget(ctx context.Context, category string, amt float64, db Database) (model.Budget, error) {
+	category = strings.TrimSpace(category)
+	var errs expense.ValidationErrors
+	if category == "" {
+		errs = append(errs, &expense.ValidationError{Field: "category", Message: "must not be empty"})
+	}
+	if math.IsNaN(amt) || math.IsInf(amt, 0) || amt < 0 {
+		errs = append(errs, &expense.ValidationError{Field: "amount", Message: "must be a number no less than 0"})
+	}
+	if len(errs) > 0 {
+		return model.Budget{}, errs
+	}
+	b, err := db.SetBudget(ctx, category, amt)
+	if err != nil {
+		return model.Budget{}, fmt.Errorf("failed to set budget, %w", err)
+	}
+	return b, nil
+}
+
+func DeleteBudget(ctx context.Context, category string, db Database) error {
+	if err := db.DeleteBudget(ctx, strings.TrimSpace(category)); err != nil {
+		return fmt.Errorf("failed to delete budget, %w", err)
+	}
+	return nil
+}
+
+func ListBudgets(ctx context.Context, db Database) ([]*model.Budget, error) {
+	bs, err := db.ListBudgets(ctx)
+	if err != nil {
+		return []*model.Budget{}, fmt.Errorf("failed to list budgets, %w", err)
+	}
+	out := []*model.Budget{}
+	for i := range bs {
+		out = append(out, &bs[i])
+	}
+	return out, nil
+}
@@ -0,0 +1,67 @@
+package budget
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/expense"
+)
+
+type MockDatabase struct {
+	budgets map[string]float64
+}
+
+func (mdb *MockDatabase) SetBudget(ctx context.Context, category string, amt float64) (model.Budget, error) {
+	mdb.budgets[category] = amt
+	return model.Budget{Id: 1, Category: model.Category{Name: category}, Amount: amt}, nil
+}
+
+func (mdb *MockDatabase) DeleteBudget(ctx context.Context, category string) error {
+	delete(mdb.budgets, category)
+	return nil
+}
+
+func (mdb *MockDatabase) ListBudgets(ctx context.Context) ([]model.Budget, error) {
+	var bs []model.Budget
+	for c, a := range mdb.budgets {
+		bs = append(bs, model.Budget{Category: model.Category{Name: c}, Amount: a})
+	}
+	return bs, nil
+}
+
+func TestSetBudget(t *testing.T) {
+	mock := &MockDatabase{budgets: make(map[string]float64)}
+	cases := []struct {
+		name     string
+		category string
+		amount   float64
+		fields   []string
+	}{
+		{name: "valid", category: " restaurants ", amount: 200},
+		{name: "zero", category: "travel", amount: 0},
+		{name: "negative", category: "travel", amount: -5, fields: []string{"amount"}},
+		{name: "not a number", category: "", amount: math.NaN(), fields: []string{"category", "amount"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			b, err := SetBudget(context.Background(), c.category, c.amount, mock)
+			if c.fields == nil {
+				assert.Nil(t, err)
+				assert.Equal(t, c.amount, b.Amount)
+				return
+			}
+			var ves expense.ValidationErrors
+			if assert.ErrorAs(t, err, &ves) {
+				var fields []string
+				for _, ve := range ves {
+					fields = append(fields, ve.Field)
+				}
+				assert.Equal(t, c.fields, fields)
+			}
+		})
+	}
+	assert.Equal(t, map[string]float64{"restaurants": 200, "travel": 0}, mock.budgets)
+}
@@ -0,0 +1,167 @@
+package changes
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/ledger"
+	"github.com/vapor05/financeview/pkg/logging"
+)
+
+// Channel is the Postgres channel changes are relayed between API
+// instances on.
+const Channel = "financeview_expense_changes"
+
+// buffer is how many changes a subscriber may fall behind by before it
+// starts missing them.
+const buffer = 16
+
+type Database interface {
+	Notify(context.Context, string, string) error
+	Listen(context.Context, string, func(string)) error
+	GetExpense(context.Context, int) (model.Expense, bool, error)
+}
+
+// Broker fans expense changes out to the subscribers of their ledger. With
+// a database it also relays them through Postgres NOTIFY, so subscribers of
+// other API instances running Listen hear about them too.
+type Broker struct {
+	db     Database
+	origin string
+
+	mu   sync.Mutex
+	subs map[int]map[chan *model.ExpenseChange]struct{}
+}
+
+// NewBroker returns a broker relaying changes through db, or keeping them
+// in process when db is nil.
+func NewBroker(db Database) *Broker {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return &Broker{
+		db:     db,
+		origin: hex.EncodeToString(b),
+		subs:   map[int]map[chan *model.ExpenseChange]struct{}{},
+	}
+}
+
+// notification is the NOTIFY payload. It names the expense rather than
+// carrying it, since payloads are capped at 8000 bytes.
+type notification struct {
+	Origin    string                  `json:"origin"`
+	LedgerId  int                     `json:"ledger_id"`
+	Kind      model.ExpenseChangeKind `json:"kind"`
+	ExpenseId int                     `json:"expense_id"`
+}
+
+// Subscribe returns a channel of the ledger's changes, closed once ctx is
+// done.
+func (b *Broker) Subscribe(ctx context.Context, lid int) <-chan *model.ExpenseChange {
+	ch := make(chan *model.ExpenseChange, buffer)
+	b.mu.Lock()
+	if b.subs[lid] == nil {
+		b.subs[lid] = map[chan *model.ExpenseChange]struct{}{}
+	}
+	b.subs[lid][ch] = struct{}{}
+	b.mu.Unlock()
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		delete(b.subs[lid], ch)
+		if len(b.subs[lid]) == 0 {
+			delete(b.subs, lid)
+		}
+		close(ch)
+		b.mu.Unlock()
+	}()
+	return ch
+}
+
+// Publish sends a change to the ledger's subscribers. It never fails the
+// change itself: relaying errors are logged.
+func (b *Broker) Publish(ctx context.Context, lid int, c model.ExpenseChange) {
+	b.deliver(ctx, lid, &c)
+	if b.db == nil {
+		return
+	}
+	payload, err := json.Marshal(notification{Origin: b.origin, LedgerId: lid, Kind: c.Kind, ExpenseId: c.ExpenseId})
+	if err == nil {
+		err = b.db.Notify(ctx, Channel, string(payload))
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to relay expense change", "expense_id", c.ExpenseId, "err", err)
+	}
+}
+
+// deliver hands a change to each subscriber, skipping any too far behind
+// rather than holding up the publisher.
+func (b *Broker) deliver(ctx context.Context, lid int, c *model.ExpenseChange) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[lid] {
+		select {
+		case ch <- c:
+		default:
+			logging.FromContext(ctx).Warn("dropped expense change for slow subscriber", "ledger_id", lid, "expense_id", c.ExpenseId)
+		}
+	}
+}
+
+func (b *Broker) subscribed(lid int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs[lid]) > 0
+}
+
+// Listen passes changes published by other API instances to this one's
+// subscribers until ctx is done, reconnecting after retry when the
+// connection fails. Changes made while it is reconnecting are missed.
+func (b *Broker) Listen(ctx context.Context, retry time.Duration) {
+	for {
+		err := b.db.Listen(ctx, Channel, func(payload string) {
+			b.receive(ctx, payload)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		logging.FromContext(ctx).Error("stopped listening for expense changes", "err", err, "retry", retry)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+	}
+}
+
+// receive delivers a relayed change, loading the expense it names once for
+// every subscriber.
+func (b *Broker) receive(ctx context.Context, payload string) {
+	l := logging.FromContext(ctx)
+	var n notification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		l.Warn("ignored malformed expense change", "err", err)
+		return
+	}
+	if n.Origin == b.origin || !b.subscribed(n.LedgerId) {
+		return
+	}
+	c := model.ExpenseChange{Kind: n.Kind, ExpenseId: n.ExpenseId}
+	if n.Kind != model.ExpenseChangeKindDeleted {
+		e, ok, err := b.db.GetExpense(ledger.WithLedger(ctx, n.LedgerId, ""), n.ExpenseId)
+		if err != nil {
+			l.Error("failed to load changed expense", "expense_id", n.ExpenseId, "err", err)
+			return
+		}
+		if !ok {
+			// Deleted again since; that change is on its way.
+			return
+		}
+		c.Expense = &e
+	}
+	b.deliver(ctx, n.LedgerId, &c)
+}
@@ -0,0 +1,126 @@
+package changes
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/ledger"
+)
+
+// MockDatabase relays notifications between every broker listening on it,
+// like Postgres does between connections.
+type MockDatabase struct {
+	exp map[int]model.Expense
+
+	mu        sync.Mutex
+	listeners []chan string
+	listening chan struct{}
+}
+
+func newMock() *MockDatabase {
+	return &MockDatabase{
+		exp:       map[int]model.Expense{1: {Id: 1, Description: "lunch"}},
+		listening: make(chan struct{}, 4),
+	}
+}
+
+func (mdb *MockDatabase) Notify(ctx context.Context, channel string, payload string) error {
+	if channel != Channel {
+		return fmt.Errorf("unexpected channel %s", channel)
+	}
+	mdb.mu.Lock()
+	defer mdb.mu.Unlock()
+	for _, l := range mdb.listeners {
+		l <- payload
+	}
+	return nil
+}
+
+func (mdb *MockDatabase) Listen(ctx context.Context, channel string, handle func(string)) error {
+	l := make(chan string, 4)
+	mdb.mu.Lock()
+	mdb.listeners = append(mdb.listeners, l)
+	mdb.mu.Unlock()
+	mdb.listening <- struct{}{}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case p := <-l:
+			handle(p)
+		}
+	}
+}
+
+func (mdb *MockDatabase) GetExpense(ctx context.Context, id int) (model.Expense, bool, error) {
+	if lid, _ := ledger.Id(ctx); lid != 10 {
+		return model.Expense{}, false, fmt.Errorf("wrong ledger %d", lid)
+	}
+	e, ok := mdb.exp[id]
+	return e, ok, nil
+}
+
+func receive(t *testing.T, ch <-chan *model.ExpenseChange) *model.ExpenseChange {
+	select {
+	case c := <-ch:
+		return c
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for change")
+		return nil
+	}
+}
+
+func TestPublishSubscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	b := NewBroker(nil)
+	ch := b.Subscribe(ctx, 10)
+	other := b.Subscribe(context.Background(), 20)
+
+	e := model.Expense{Id: 1, Description: "lunch"}
+	b.Publish(context.Background(), 10, model.ExpenseChange{Kind: model.ExpenseChangeKindCreated, ExpenseId: 1, Expense: &e})
+	assert.Equal(t, &model.ExpenseChange{Kind: model.ExpenseChangeKindCreated, ExpenseId: 1, Expense: &e}, receive(t, ch))
+	assert.Len(t, other, 0, "changes only reach the ledger's subscribers")
+
+	for i := 0; i < buffer+5; i++ {
+		b.Publish(context.Background(), 10, model.ExpenseChange{Kind: model.ExpenseChangeKindUpdated, ExpenseId: 1})
+	}
+	assert.Len(t, ch, buffer, "slow subscribers miss changes instead of blocking")
+
+	cancel()
+	for range ch {
+	}
+	assert.False(t, b.subscribed(10))
+}
+
+func TestListen(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	mdb := newMock()
+	a, b := NewBroker(mdb), NewBroker(mdb)
+	go a.Listen(ctx, time.Millisecond)
+	go b.Listen(ctx, time.Millisecond)
+	<-mdb.listening
+	<-mdb.listening
+	fromA := a.Subscribe(ctx, 10)
+	fromB := b.Subscribe(ctx, 10)
+
+	a.Publish(ctx, 10, model.ExpenseChange{Kind: model.ExpenseChangeKindUpdated, ExpenseId: 1, Expense: &model.Expense{Id: 1}})
+	assert.Equal(t, model.ExpenseChangeKindUpdated, receive(t, fromA).Kind)
+	c := receive(t, fromB)
+	assert.Equal(t, model.ExpenseChangeKindUpdated, c.Kind)
+	assert.Equal(t, "lunch", c.Expense.Description, "relayed expenses are loaded from the database")
+
+	b.Publish(ctx, 10, model.ExpenseChange{Kind: model.ExpenseChangeKindDeleted, ExpenseId: 1})
+	assert.Equal(t, &model.ExpenseChange{Kind: model.ExpenseChangeKindDeleted, ExpenseId: 1}, receive(t, fromA))
+	receive(t, fromB)
+
+	a.Publish(ctx, 10, model.ExpenseChange{Kind: model.ExpenseChangeKindRestored, ExpenseId: 1})
+	b.Publish(ctx, 10, model.ExpenseChange{Kind: model.ExpenseChangeKindDeleted, ExpenseId: 1})
+	assert.Equal(t, model.ExpenseChangeKindRestored, receive(t, fromA).Kind)
+	assert.Equal(t, model.ExpenseChangeKindDeleted, receive(t, fromA).Kind, "brokers skip their own notifications")
+}
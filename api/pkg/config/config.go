@@ -0,0 +1,528 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v2"
+)
+
+// LogLevels are the accepted log levels, least verbose last.
+var LogLevels = []string{"debug", "info", "warn", "error"}
+
+// Duration is a time.Duration written as a string such as "720h" in config
+// files.
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d *Duration) UnmarshalText(b []byte) error {
+	v, err := time.ParseDuration(string(b))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return d.UnmarshalText([]byte(s))
+}
+
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+type Database struct {
+	URL             string   `yaml:"url" toml:"url"`
+	MaxConns        int32    `yaml:"max_conns" toml:"max_conns"`
+	MinConns        int32    `yaml:"min_conns" toml:"min_conns"`
+	MaxConnLifetime Duration `yaml:"max_conn_lifetime" toml:"max_conn_lifetime"`
+	MaxConnIdleTime Duration `yaml:"max_conn_idle_time" toml:"max_conn_idle_time"`
+}
+
+// CORS lists the origins browsers may call the API from. None are allowed by
+// default; "*" must be listed to allow any origin.
+type CORS struct {
+	AllowedOrigins []string `yaml:"allowed_origins" toml:"allowed_origins"`
+}
+
+// TLS serves HTTPS when both paths are set.
+type TLS struct {
+	CertFile string `yaml:"cert_file" toml:"cert_file"`
+	KeyFile  string `yaml:"key_file" toml:"key_file"`
+}
+
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" && t.KeyFile != ""
+}
+
+// TracingExporters are the accepted places to send trace spans.
+var TracingExporters = []string{"none", "stdout", "otlp"}
+
+// Tracing picks where OpenTelemetry spans go. The otlp exporter speaks
+// OTLP over HTTP to Endpoint, a host:port such as a local collector's
+// localhost:4318.
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" toml:"exporter"`
+	Endpoint    string  `yaml:"endpoint" toml:"endpoint"`
+	Insecure    bool    `yaml:"insecure" toml:"insecure"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+}
+
+// GraphQL limits what queries the API will run. A zero limit is no limit.
+// ListCosts override graph.DefaultListCosts, keyed by Type.field. With
+// AllowlistOnly set only the queries in the PersistedQueries file, a JSON
+// object of sha256 hashes to queries, are run.
+type GraphQL struct {
+	ComplexityLimit  int            `yaml:"complexity_limit" toml:"complexity_limit"`
+	MaxDepth         int            `yaml:"max_depth" toml:"max_depth"`
+	ListCosts        map[string]int `yaml:"list_costs" toml:"list_costs"`
+	PersistedQueries string         `yaml:"persisted_queries" toml:"persisted_queries"`
+	AllowlistOnly    bool           `yaml:"allowlist_only" toml:"allowlist_only"`
+}
+
+// RateLimit caps how many GraphQL queries and mutations each user, API
+// token or, before logging in, client IP may run a minute. Bursts of up to
+// the burst size go through at once. A zero rate is no limit.
+type RateLimit struct {
+	QueriesPerMinute   int `yaml:"queries_per_minute" toml:"queries_per_minute"`
+	QueryBurst         int `yaml:"query_burst" toml:"query_burst"`
+	MutationsPerMinute int `yaml:"mutations_per_minute" toml:"mutations_per_minute"`
+	MutationBurst      int `yaml:"mutation_burst" toml:"mutation_burst"`
+}
+
+// Features switch optional parts of the server on or off. ListenNotify
+// relays live expense changes through Postgres LISTEN/NOTIFY, which every
+// API instance needs when more than one is running.
+type Features struct {
+	Playground   bool `yaml:"playground" toml:"playground"`
+	TrashPurger  bool `yaml:"trash_purger" toml:"trash_purger"`
+	ListenNotify bool `yaml:"listen_notify" toml:"listen_notify"`
+}
+
+// toggles maps each feature's name in env vars and flags to its switch.
+func (f *Features) toggles() map[string]*bool {
+	return map[string]*bool{
+		"playground":    &f.Playground,
+		"trash_purger":  &f.TrashPurger,
+		"listen_notify": &f.ListenNotify,
+	}
+}
+
+// set applies a comma separated list of name=bool feature toggles.
+func (f *Features) set(s string) error {
+	ts := f.toggles()
+	for _, kv := range strings.Split(s, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		parts := strings.SplitN(kv, "=", 2)
+		name, v := parts[0], "true"
+		if len(parts) == 2 {
+			v = parts[1]
+		}
+		p, known := ts[strings.TrimSpace(name)]
+		if !known {
+			return fmt.Errorf("unknown feature %q", name)
+		}
+		on, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("invalid value for feature %s, %w", name, err)
+		}
+		*p = on
+	}
+	return nil
+}
+
+type Config struct {
+	Listen          string    `yaml:"listen" toml:"listen"`
+	ShutdownTimeout Duration  `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	Database        Database  `yaml:"database" toml:"database"`
+	CORS            CORS      `yaml:"cors" toml:"cors"`
+	LogLevel        string    `yaml:"log_level" toml:"log_level"`
+	TLS             TLS       `yaml:"tls" toml:"tls"`
+	AttachmentDir   string    `yaml:"attachment_dir" toml:"attachment_dir"`
+	TrashRetention  Duration  `yaml:"trash_retention" toml:"trash_retention"`
+	Features        Features  `yaml:"features" toml:"features"`
+	Tracing         Tracing   `yaml:"tracing" toml:"tracing"`
+	GraphQL         GraphQL   `yaml:"graphql" toml:"graphql"`
+	RateLimit       RateLimit `yaml:"rate_limit" toml:"rate_limit"`
+}
+
+// Default returns the config used for anything not set elsewhere.
+func Default() Config {
+	return Config{
+		Listen:          ":8080",
+		ShutdownTimeout: Duration(15 * time.Second),
+		Database: Database{
+			MaxConns:        10,
+			MaxConnLifetime: Duration(time.Hour),
+			MaxConnIdleTime: Duration(30 * time.Minute),
+		},
+		LogLevel:       "info",
+		AttachmentDir:  "attachments",
+		TrashRetention: Duration(30 * 24 * time.Hour),
+		Features:       Features{Playground: true, TrashPurger: true},
+		Tracing:        Tracing{Exporter: "none", Endpoint: "localhost:4318", Insecure: true, SampleRatio: 1},
+		GraphQL:        GraphQL{ComplexityLimit: 5000, MaxDepth: 10},
+		RateLimit:      RateLimit{QueriesPerMinute: 600, QueryBurst: 100, MutationsPerMinute: 60, MutationBurst: 20},
+	}
+}
+
+// Options are the command line settings that aren't part of the config
+// itself.
+type Options struct {
+	// Path is the config file that was loaded, if any.
+	Path string
+	// PrintConfig asks for the effective config to be printed instead of
+	// starting the server.
+	PrintConfig bool
+}
+
+// Load builds the config from the defaults, then a YAML or TOML file, then
+// environment variables and finally command line flags, each overriding
+// the one before. The file comes from the -config flag or the
+// FINANCEVIEW_CONFIG variable.
+func Load(args []string, getenv func(string) string) (Config, Options, error) {
+	var opts Options
+	var listen, dbURL, origins, level, cert, key, dir, retention, features, tracing string
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.StringVar(&opts.Path, "config", getenv("FINANCEVIEW_CONFIG"), "path to a YAML or TOML config file")
+	fs.BoolVar(&opts.PrintConfig, "print-config", false, "print the effective config, redacted, and exit")
+	fs.StringVar(&listen, "listen", "", "address to listen on")
+	fs.StringVar(&dbURL, "db-url", "", "database connection URL")
+	fs.StringVar(&origins, "cors-origins", "", "comma separated allowed CORS origins")
+	fs.StringVar(&level, "log-level", "", "log level, one of "+strings.Join(LogLevels, ", "))
+	fs.StringVar(&cert, "tls-cert", "", "TLS certificate file")
+	fs.StringVar(&key, "tls-key", "", "TLS key file")
+	fs.StringVar(&dir, "attachment-dir", "", "directory attachments are stored in")
+	fs.StringVar(&retention, "trash-retention", "", "how long deleted expenses are kept, such as 720h")
+	fs.StringVar(&features, "features", "", "comma separated feature toggles, such as playground=false")
+	fs.StringVar(&tracing, "tracing-exporter", "", "where to send trace spans, one of "+strings.Join(TracingExporters, ", "))
+	if err := fs.Parse(args); err != nil {
+		return Config{}, opts, err
+	}
+
+	c := Default()
+	if opts.Path != "" {
+		if err := c.readFile(opts.Path); err != nil {
+			return Config{}, opts, err
+		}
+	}
+	if err := c.readEnv(getenv); err != nil {
+		return Config{}, opts, err
+	}
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		if err != nil {
+			return
+		}
+		switch f.Name {
+		case "listen":
+			c.Listen = listen
+		case "db-url":
+			c.Database.URL = dbURL
+		case "cors-origins":
+			c.CORS.AllowedOrigins = splitList(origins)
+		case "log-level":
+			c.LogLevel = level
+		case "tls-cert":
+			c.TLS.CertFile = cert
+		case "tls-key":
+			c.TLS.KeyFile = key
+		case "attachment-dir":
+			c.AttachmentDir = dir
+		case "trash-retention":
+			err = c.TrashRetention.UnmarshalText([]byte(retention))
+		case "features":
+			err = c.Features.set(features)
+		case "tracing-exporter":
+			c.Tracing.Exporter = tracing
+		}
+		if err != nil {
+			err = fmt.Errorf("invalid -%s flag, %w", f.Name, err)
+		}
+	})
+	if err != nil {
+		return Config{}, opts, err
+	}
+	return c, opts, nil
+}
+
+func (c *Config) readFile(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file, %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(b, c)
+	case ".toml":
+		var md toml.MetaData
+		md, err = toml.Decode(string(b), c)
+		if err == nil && len(md.Undecoded()) > 0 {
+			err = fmt.Errorf("unknown keys %v", md.Undecoded())
+		}
+	default:
+		return fmt.Errorf("config file %s must end in .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s, %w", path, err)
+	}
+	return nil
+}
+
+// readEnv applies the environment variables that are set. DBURL,
+// ATTACHMENT_DIR and TRASH_RETENTION keep the names the server always used.
+func (c *Config) readEnv(getenv func(string) string) error {
+	strs := map[string]*string{
+		"LISTEN_ADDR":               &c.Listen,
+		"DBURL":                     &c.Database.URL,
+		"LOG_LEVEL":                 &c.LogLevel,
+		"TLS_CERT_FILE":             &c.TLS.CertFile,
+		"TLS_KEY_FILE":              &c.TLS.KeyFile,
+		"ATTACHMENT_DIR":            &c.AttachmentDir,
+		"TRACING_EXPORTER":          &c.Tracing.Exporter,
+		"TRACING_ENDPOINT":          &c.Tracing.Endpoint,
+		"GRAPHQL_PERSISTED_QUERIES": &c.GraphQL.PersistedQueries,
+	}
+	for k, p := range strs {
+		if v := getenv(k); v != "" {
+			*p = v
+		}
+	}
+	ints := map[string]*int32{
+		"DB_MAX_CONNS": &c.Database.MaxConns,
+		"DB_MIN_CONNS": &c.Database.MinConns,
+	}
+	for k, p := range ints {
+		if v := getenv(k); v != "" {
+			n, err := strconv.ParseInt(v, 10, 32)
+			if err != nil {
+				return fmt.Errorf("invalid %s, %w", k, err)
+			}
+			*p = int32(n)
+		}
+	}
+	limits := map[string]*int{
+		"GRAPHQL_COMPLEXITY_LIMIT": &c.GraphQL.ComplexityLimit,
+		"GRAPHQL_MAX_DEPTH":        &c.GraphQL.MaxDepth,
+		"RATE_LIMIT_QUERIES":       &c.RateLimit.QueriesPerMinute,
+		"RATE_LIMIT_MUTATIONS":     &c.RateLimit.MutationsPerMinute,
+	}
+	for k, p := range limits {
+		if v := getenv(k); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s, %w", k, err)
+			}
+			*p = n
+		}
+	}
+	durs := map[string]*Duration{
+		"DB_MAX_CONN_LIFETIME":  &c.Database.MaxConnLifetime,
+		"DB_MAX_CONN_IDLE_TIME": &c.Database.MaxConnIdleTime,
+		"TRASH_RETENTION":       &c.TrashRetention,
+		"SHUTDOWN_TIMEOUT":      &c.ShutdownTimeout,
+	}
+	for k, p := range durs {
+		if v := getenv(k); v != "" {
+			if err := p.UnmarshalText([]byte(v)); err != nil {
+				return fmt.Errorf("invalid %s, %w", k, err)
+			}
+		}
+	}
+	if v := getenv("GRAPHQL_ALLOWLIST_ONLY"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid GRAPHQL_ALLOWLIST_ONLY, %w", err)
+		}
+		c.GraphQL.AllowlistOnly = b
+	}
+	if v := getenv("TRACING_INSECURE"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid TRACING_INSECURE, %w", err)
+		}
+		c.Tracing.Insecure = b
+	}
+	if v := getenv("TRACING_SAMPLE_RATIO"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid TRACING_SAMPLE_RATIO, %w", err)
+		}
+		c.Tracing.SampleRatio = f
+	}
+	if v := getenv("CORS_ORIGINS"); v != "" {
+		c.CORS.AllowedOrigins = splitList(v)
+	}
+	if v := getenv("FEATURES"); v != "" {
+		if err := c.Features.set(v); err != nil {
+			return fmt.Errorf("invalid FEATURES, %w", err)
+		}
+	}
+	return nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Validate reports every problem with the config at once.
+func (c Config) Validate() error {
+	var errs []string
+	if c.Listen == "" {
+		errs = append(errs, "listen address must not be empty")
+	}
+	if c.Database.URL == "" {
+		errs = append(errs, "database url must be set")
+	} else if _, err := url.Parse(c.Database.URL); err != nil {
+		errs = append(errs, "database url is not a valid URL")
+	}
+	if c.Database.MaxConns < 1 {
+		errs = append(errs, "database max_conns must be at least 1")
+	}
+	if c.Database.MinConns < 0 || c.Database.MinConns > c.Database.MaxConns {
+		errs = append(errs, "database min_conns must be between 0 and max_conns")
+	}
+	if c.Database.MaxConnLifetime < 0 || c.Database.MaxConnIdleTime < 0 {
+		errs = append(errs, "database connection lifetimes must not be negative")
+	}
+	for _, o := range c.CORS.AllowedOrigins {
+		if o == "*" && len(c.CORS.AllowedOrigins) > 1 {
+			errs = append(errs, `cors allowed_origins must not list other origins with "*"`)
+			break
+		}
+	}
+	valid := false
+	for _, l := range LogLevels {
+		valid = valid || c.LogLevel == l
+	}
+	if !valid {
+		errs = append(errs, fmt.Sprintf("log_level must be one of %s", strings.Join(LogLevels, ", ")))
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		errs = append(errs, "tls needs both cert_file and key_file")
+	}
+	if c.AttachmentDir == "" {
+		errs = append(errs, "attachment_dir must not be empty")
+	}
+	if c.ShutdownTimeout < 0 {
+		errs = append(errs, "shutdown_timeout must not be negative")
+	}
+	valid = false
+	for _, e := range TracingExporters {
+		valid = valid || c.Tracing.Exporter == e
+	}
+	if !valid {
+		errs = append(errs, fmt.Sprintf("tracing exporter must be one of %s", strings.Join(TracingExporters, ", ")))
+	}
+	if c.Tracing.Exporter == "otlp" && c.Tracing.Endpoint == "" {
+		errs = append(errs, "tracing endpoint must be set for the otlp exporter")
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, "tracing sample_ratio must be between 0 and 1")
+	}
+	if c.GraphQL.ComplexityLimit < 0 || c.GraphQL.MaxDepth < 0 {
+		errs = append(errs, "graphql limits must not be negative")
+	}
+	for f, n := range c.GraphQL.ListCosts {
+		if n < 1 {
+			errs = append(errs, fmt.Sprintf("graphql list cost of %s must be at least 1", f))
+		}
+	}
+	if c.GraphQL.AllowlistOnly && c.GraphQL.PersistedQueries == "" {
+		errs = append(errs, "graphql allowlist_only needs a persisted_queries file")
+	}
+	rl := c.RateLimit
+	if rl.QueriesPerMinute < 0 || rl.MutationsPerMinute < 0 {
+		errs = append(errs, "rate_limit rates must not be negative")
+	}
+	if rl.QueriesPerMinute > 0 && rl.QueryBurst < 1 || rl.MutationsPerMinute > 0 && rl.MutationBurst < 1 {
+		errs = append(errs, "rate_limit bursts must be at least 1")
+	}
+	if c.TrashRetention <= 0 {
+		errs = append(errs, "trash_retention must be positive")
+	}
+	if len(errs) > 0 {
+		return errors.New("invalid config: " + strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Redacted returns the config with secrets masked, fit for printing.
+func (c Config) Redacted() Config {
+	c.Database.URL = redactDSN(c.Database.URL)
+	c.Tracing.Endpoint = redactDSN(c.Tracing.Endpoint)
+	c.CORS.AllowedOrigins = append([]string(nil), c.CORS.AllowedOrigins...)
+	return c
+}
+
+// secretParams are the connection settings masked when printing, whether
+// given as URL query parameters or as key=value settings.
+var secretParams = regexp.MustCompile(`(?i)^(password|passfile|sslpassword|sslkey|token|secret|api_?key)$`)
+
+// redactDSN masks the password and any secret settings of a URL or a
+// key=value connection string such as "host=db password=secret".
+func redactDSN(s string) string {
+	if strings.Contains(s, "://") {
+		u, err := url.Parse(s)
+		if err != nil {
+			return "xxxxx"
+		}
+		q := u.Query()
+		for k := range q {
+			if secretParams.MatchString(k) {
+				q.Set(k, "xxxxx")
+			}
+		}
+		u.RawQuery = q.Encode()
+		return u.Redacted()
+	}
+	if !strings.Contains(s, "=") {
+		return s
+	}
+	fields := strings.Fields(s)
+	for i, f := range fields {
+		if k, _, ok := strings.Cut(f, "="); ok && secretParams.MatchString(k) {
+			fields[i] = k + "=xxxxx"
+		}
+	}
+	return strings.Join(fields, " ")
+}
+
+// String formats the redacted config as YAML.
+func (c Config) String() string {
+	b, err := yaml.Marshal(c.Redacted())
+	if err != nil {
+		return fmt.Sprintf("failed to format config, %v", err)
+	}
+	return string(b)
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(k string) string {
+		return vars[k]
+	}
+}
+
+func writeFile(t *testing.T, name string, content string) string {
+	p := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(p, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config file, %v", err)
+	}
+	return p
+}
+
+func TestLoadDefaults(t *testing.T) {
+	actual, opts, err := Load(nil, env(nil))
+	if err != nil {
+		t.Fatalf("error running Load func, %v", err)
+	}
+	assert.Equal(t, Default(), actual)
+	assert.Equal(t, Options{}, opts)
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	yml := writeFile(t, "config.yaml", `
+listen: ":9000"
+log_level: warn
+database:
+  url: postgres://file@localhost/db
+  max_conns: 20
+  max_conn_idle_time: 5m
+cors:
+  allowed_origins: ["https://file.example.com"]
+features:
+  playground: false
+graphql:
+  list_costs:
+    Query.expenses: 20
+`)
+	vars := map[string]string{
+		"FINANCEVIEW_CONFIG": yml,
+		"DBURL":              "postgres://env@localhost/db",
+		"LOG_LEVEL":          "debug",
+		"DB_MIN_CONNS":       "2",
+		"FEATURES":           "trash_purger=false",
+		"RATE_LIMIT_QUERIES": "0",
+	}
+	actual, opts, err := Load([]string{"-log-level", "error", "-cors-origins", "https://a.example.com, https://b.example.com"}, env(vars))
+	if err != nil {
+		t.Fatalf("error running Load func, %v", err)
+	}
+	assert.Equal(t, yml, opts.Path)
+	assert.Equal(t, ":9000", actual.Listen)
+	assert.Equal(t, "postgres://env@localhost/db", actual.Database.URL)
+	assert.Equal(t, int32(20), actual.Database.MaxConns)
+	assert.Equal(t, int32(2), actual.Database.MinConns)
+	assert.Equal(t, Duration(5*time.Minute), actual.Database.MaxConnIdleTime)
+	assert.Equal(t, Duration(time.Hour), actual.Database.MaxConnLifetime)
+	assert.Equal(t, "error", actual.LogLevel)
+	assert.Equal(t, []string{"https://a.example.com", "https://b.example.com"}, actual.CORS.AllowedOrigins)
+	assert.Equal(t, Features{Playground: false, TrashPurger: false}, actual.Features)
+	assert.Equal(t, map[string]int{"Query.expenses": 20}, actual.GraphQL.ListCosts)
+	assert.Equal(t, 5000, actual.GraphQL.ComplexityLimit)
+	assert.Equal(t, RateLimit{QueriesPerMinute: 0, QueryBurst: 100, MutationsPerMinute: 60, MutationBurst: 20}, actual.RateLimit)
+}
+
+func TestLoadTOML(t *testing.T) {
+	p := writeFile(t, "config.toml", `
+listen = "127.0.0.1:8081"
+trash_retention = "48h"
+
+[tls]
+cert_file = "cert.pem"
+key_file = "key.pem"
+`)
+	actual, _, err := Load([]string{"-config", p}, env(nil))
+	if err != nil {
+		t.Fatalf("error running Load func, %v", err)
+	}
+	assert.Equal(t, "127.0.0.1:8081", actual.Listen)
+	assert.Equal(t, Duration(48*time.Hour), actual.TrashRetention)
+	assert.True(t, actual.TLS.Enabled())
+}
+
+func TestLoadErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		args []string
+		vars map[string]string
+	}{
+		{name: "unknown yaml key", args: []string{"-config", writeFile(t, "c.yaml", "listne: ':80'\n")}},
+		{name: "unknown toml key", args: []string{"-config", writeFile(t, "c.toml", "listne = ':80'\n")}},
+		{name: "unknown extension", args: []string{"-config", writeFile(t, "c.json", "{}")}},
+		{name: "missing file", args: []string{"-config", "does-not-exist.yaml"}},
+		{name: "bad env int", vars: map[string]string{"DB_MAX_CONNS": "many"}},
+		{name: "bad env duration", vars: map[string]string{"TRASH_RETENTION": "a month"}},
+		{name: "unknown feature", vars: map[string]string{"FEATURES": "teleport"}},
+		{name: "bad sample ratio", vars: map[string]string{"TRACING_SAMPLE_RATIO": "most"}},
+		{name: "bad flag duration", args: []string{"-trash-retention", "soon"}},
+		{name: "unknown flag", args: []string{"-port", "80"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, _, err := Load(c.args, env(c.vars))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := Default()
+	valid.Database.URL = "postgres://localhost/db"
+	assert.Nil(t, valid.Validate())
+	cases := []struct {
+		name   string
+		change func(*Config)
+	}{
+		{name: "no database url", change: func(c *Config) { c.Database.URL = "" }},
+		{name: "no max conns", change: func(c *Config) { c.Database.MaxConns = 0 }},
+		{name: "min over max", change: func(c *Config) { c.Database.MinConns = 11 }},
+		{name: "bad log level", change: func(c *Config) { c.LogLevel = "loud" }},
+		{name: "tls cert only", change: func(c *Config) { c.TLS.CertFile = "cert.pem" }},
+		{name: "cors origins besides any", change: func(c *Config) { c.CORS.AllowedOrigins = []string{"*", "https://a.example.com"} }},
+		{name: "no listen", change: func(c *Config) { c.Listen = "" }},
+		{name: "zero retention", change: func(c *Config) { c.TrashRetention = 0 }},
+		{name: "negative shutdown timeout", change: func(c *Config) { c.ShutdownTimeout = -1 }},
+		{name: "unknown tracing exporter", change: func(c *Config) { c.Tracing.Exporter = "jaeger" }},
+		{name: "otlp without endpoint", change: func(c *Config) { c.Tracing.Exporter, c.Tracing.Endpoint = "otlp", "" }},
+		{name: "sample ratio over one", change: func(c *Config) { c.Tracing.SampleRatio = 2 }},
+		{name: "negative max depth", change: func(c *Config) { c.GraphQL.MaxDepth = -1 }},
+		{name: "zero list cost", change: func(c *Config) { c.GraphQL.ListCosts = map[string]int{"Query.expenses": 0} }},
+		{name: "allowlist without queries", change: func(c *Config) { c.GraphQL.AllowlistOnly = true }},
+		{name: "negative rate limit", change: func(c *Config) { c.RateLimit.MutationsPerMinute = -1 }},
+		{name: "rate limit without burst", change: func(c *Config) { c.RateLimit.QueryBurst = 0 }},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cfg := valid
+			cfg.CORS.AllowedOrigins = append([]string(nil), valid.CORS.AllowedOrigins...)
+			c.change(&cfg)
+			assert.Error(t, cfg.Validate())
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	c := Default()
+	c.Database.URL = "postgres://postgres:testing@db:5432/postgres"
+	out := c.String()
+	assert.NotContains(t, out, "testing")
+	assert.Contains(t, out, "postgres://postgres:xxxxx@db:5432/postgres")
+	assert.Contains(t, out, "trash_retention: 720h0m0s")
+	assert.True(t, strings.HasPrefix(out, "listen: :8080"))
+	assert.Equal(t, "postgres://postgres:testing@db:5432/postgres", c.Database.URL)
+
+	cases := map[string]string{
+		"postgres://db/postgres?sslmode=require&password=testing": "postgres://db/postgres?password=xxxxx&sslmode=require",
+		"host=db user=postgres password=testing dbname=postgres":  "host=db user=postgres password=xxxxx dbname=postgres",
+		"postgres://postgres:te%zzing@db/postgres":                "xxxxx",
+	}
+	for url, want := range cases {
+		c.Database.URL = url
+		assert.Equal(t, want, c.Redacted().Database.URL)
+	}
+}
@@ -0,0 +1,76 @@
+package expense
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/logging"
+)
+
+// MaxBatch is the most expenses SaveExpenses saves at once.
+const MaxBatch = 1000
+
+type BatchDatabase interface {
+	CreateExpenses(context.Context, []model.Expense) ([]model.Expense, error)
+}
+
+// SaveExpenses validates new expenses and saves them together in one
+// transaction, returning a result for each input in order. Invalid inputs
+// get their ValidationErrors in their result. In BEST_EFFORT mode the valid
+// inputs are still saved, in ALL_OR_NOTHING mode nothing is. A database error
+// fails the whole batch in either mode.
+func SaveExpenses(ctx context.Context, nes []*model.NewExpense, mode model.BatchMode, db BatchDatabase) ([]*model.CreateExpenseResult, error) {
+	if len(nes) > MaxBatch {
+		return nil, ValidationErrors{{Field: "inputs", Message: fmt.Sprintf("must not have more than %d expenses", MaxBatch)}}
+	}
+	now := time.Now().UTC()
+	results := make([]*model.CreateExpenseResult, len(nes))
+	var es []model.Expense
+	var idx []int
+	for i, ne := range nes {
+		results[i] = &model.CreateExpenseResult{Index: i, Errors: []*model.InputError{}}
+		dt, err := Validate(*ne, now)
+		var ves ValidationErrors
+		if errors.As(err, &ves) {
+			for _, ve := range ves {
+				results[i].Errors = append(results[i].Errors, &model.InputError{Field: ve.Field, Message: ve.Message})
+			}
+			continue
+		}
+		var cmt string
+		if ne.Comment != nil {
+			cmt = *ne.Comment
+		}
+		e := model.Expense{
+			Date:         dt.Format(DateLayout),
+			Description:  ne.Description,
+			Amount:       ne.Amount,
+			Comment:      cmt,
+			Reimbursable: ne.Reimbursable != nil && *ne.Reimbursable,
+		}
+		for _, c := range NormalizeCategories(ne.Categories) {
+			e.Categories = append(e.Categories, model.Category{Name: c})
+		}
+		for _, t := range NormalizeTags(ne.Tags) {
+			e.Tags = append(e.Tags, model.Tag{Name: t})
+		}
+		es = append(es, e)
+		idx = append(idx, i)
+	}
+	if len(es) == 0 || (mode == model.BatchModeAllOrNothing && len(es) < len(nes)) {
+		logging.FromContext(ctx).Debug("saved no expenses", "inputs", len(nes), "invalid", len(nes)-len(es))
+		return results, nil
+	}
+	saved, err := db.CreateExpenses(ctx, es)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save new expenses, %w", err)
+	}
+	for j := range saved {
+		results[idx[j]].Expense = &saved[j]
+	}
+	logging.FromContext(ctx).Debug("saved expenses", "inputs", len(nes), "saved", len(saved))
+	return results, nil
+}
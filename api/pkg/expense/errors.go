@@ -0,0 +1,36 @@
+package expense
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidationError rejects a NewExpense input field.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid %s, %s", e.Field, e.Message)
+}
+
+// ValidationErrors are every field error of one input.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, ve := range e {
+		msgs[i] = ve.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// NotFoundError is returned for an expense that isn't in the ledger.
+type NotFoundError struct {
+	Id int
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("expense id=%v does not exist", e.Id)
+}
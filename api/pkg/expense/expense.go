@@ -3,67 +3,207 @@ package expense
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/logging"
 )
 
 type Database interface {
 	GetDescriptionId(context.Context, string) (int, bool, error)
 	CreateDescription(context.Context, string) (int, error)
 	CreateExpense(context.Context, time.Time, int, float64, string) (int, error)
+	ExpenseExists(context.Context, int) (bool, error)
+	ReplaceExpense(context.Context, model.Expense, *bool) error
+	GetExpense(context.Context, int) (model.Expense, bool, error)
+	SetReimbursable(context.Context, int, bool) error
 	GetCategoryId(context.Context, string) (int, bool, error)
 	CreateCategory(context.Context, string) (int, error)
 	LinkExpenseCategory(context.Context, int, int) (int, error)
+	GetTagId(context.Context, string) (int, bool, error)
+	CreateTag(context.Context, string) (int, error)
+	LinkExpenseTag(context.Context, int, int) (int, error)
 	ListAllExpenses(context.Context) ([]model.Expense, error)
+	FindExpenses(context.Context, model.ExpenseFilter) ([]model.Expense, error)
 }
 
+// SaveExpense validates and saves a new expense. Invalid input is rejected
+// with ValidationErrors before anything is written.
 func SaveExpense(ctx context.Context, ne model.NewExpense, db Database) (model.Expense, error) {
-	did, ok, err := db.GetDescriptionId(ctx, ne.Description)
+	dt, err := Validate(ne, time.Now().UTC())
 	if err != nil {
-		return model.Expense{}, fmt.Errorf("failed to get description_id for new expense, %w", err)
+		return model.Expense{}, err
 	}
-	if !ok {
-		did, err = db.CreateDescription(ctx, ne.Description)
-		if err != nil {
-			return model.Expense{}, fmt.Errorf("failed to create new description, %w", err)
+	did, err := descriptionId(ctx, ne.Description, db)
+	if err != nil {
+		return model.Expense{}, err
+	}
+	var cmt string
+	if ne.Comment != nil {
+		cmt = *ne.Comment
+	}
+	eid, err := db.CreateExpense(ctx, dt, did, ne.Amount, cmt)
+	if err != nil {
+		return model.Expense{}, fmt.Errorf("failed to save new expense data, %w", err)
+	}
+	cats, err := linkCategories(ctx, eid, ne.Categories, db)
+	if err != nil {
+		return model.Expense{}, err
+	}
+	tags, err := linkTags(ctx, eid, ne.Tags, db)
+	if err != nil {
+		return model.Expense{}, err
+	}
+	e := model.Expense{
+		Id:          eid,
+		Date:        dt.Format(DateLayout),
+		Description: ne.Description,
+		Amount:      ne.Amount,
+		Categories:  cats,
+		Tags:        tags,
+		Comment:     cmt,
+	}
+	if ne.Reimbursable != nil && *ne.Reimbursable {
+		if err := db.SetReimbursable(ctx, eid, true); err != nil {
+			return model.Expense{}, fmt.Errorf("failed to mark new expense reimbursable, %w", err)
 		}
+		rs := model.ReimbursementStatusPending
+		e.Reimbursable = true
+		e.ReimbursementStatus = &rs
 	}
-	dt, err := time.Parse("01-02-2006", ne.Date)
+	logging.FromContext(ctx).Debug("saved expense", "expense_id", eid, "categories", len(cats), "tags", len(tags))
+	return e, nil
+}
+
+// UpdateExpense replaces every field of an existing expense, including its
+// categories and tags, with the validated input in one transaction. The
+// reimbursable flag is left alone when the input omits it.
+func UpdateExpense(ctx context.Context, id int, ne model.NewExpense, db Database) (model.Expense, error) {
+	dt, err := Validate(ne, time.Now().UTC())
 	if err != nil {
-		return model.Expense{}, fmt.Errorf("failed to parse new expense date, %w", err)
+		return model.Expense{}, err
 	}
-	eid, err := db.CreateExpense(ctx, dt, did, ne.Amount, *ne.Comment)
+	ok, err := db.ExpenseExists(ctx, id)
 	if err != nil {
-		return model.Expense{}, fmt.Errorf("failed to save new expense data, %w", err)
+		return model.Expense{}, fmt.Errorf("failed to check expense exists, %w", err)
+	}
+	if !ok {
+		return model.Expense{}, &NotFoundError{Id: id}
+	}
+	var cmt string
+	if ne.Comment != nil {
+		cmt = *ne.Comment
+	}
+	e := model.Expense{Id: id, Date: dt.Format(DateLayout), Description: ne.Description, Amount: ne.Amount, Comment: cmt}
+	for _, c := range NormalizeCategories(ne.Categories) {
+		e.Categories = append(e.Categories, model.Category{Name: c})
+	}
+	for _, t := range NormalizeTags(ne.Tags) {
+		e.Tags = append(e.Tags, model.Tag{Name: t})
+	}
+	if err := db.ReplaceExpense(ctx, e, ne.Reimbursable); err != nil {
+		return model.Expense{}, fmt.Errorf("failed to update expense, %w", err)
+	}
+	e, ok, err = db.GetExpense(ctx, id)
+	if err != nil {
+		return model.Expense{}, fmt.Errorf("failed to get updated expense, %w", err)
 	}
+	if !ok {
+		return model.Expense{}, &NotFoundError{Id: id}
+	}
+	logging.FromContext(ctx).Debug("updated expense", "expense_id", id)
+	return e, nil
+}
+
+func descriptionId(ctx context.Context, desc string, db Database) (int, error) {
+	did, ok, err := db.GetDescriptionId(ctx, desc)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get description_id for new expense, %w", err)
+	}
+	if !ok {
+		did, err = db.CreateDescription(ctx, desc)
+		if err != nil {
+			return 0, fmt.Errorf("failed to create new description, %w", err)
+		}
+	}
+	return did, nil
+}
+
+func linkCategories(ctx context.Context, eid int, names []string, db Database) ([]model.Category, error) {
 	var cats []model.Category
-	for _, c := range ne.Categories {
+	for _, c := range NormalizeCategories(names) {
 		cid, ok, err := db.GetCategoryId(ctx, c)
 		if err != nil {
-			return model.Expense{}, fmt.Errorf("failed to get category_id, %w", err)
+			return nil, fmt.Errorf("failed to get category_id, %w", err)
 		}
 		if !ok {
 			cid, err = db.CreateCategory(ctx, c)
 			if err != nil {
-				return model.Expense{}, fmt.Errorf("failed to create new category, %w", err)
+				return nil, fmt.Errorf("failed to create new category, %w", err)
 			}
 		}
 		_, err = db.LinkExpenseCategory(ctx, eid, cid)
 		if err != nil {
-			return model.Expense{}, fmt.Errorf("failed to link expense and category, %w", err)
+			return nil, fmt.Errorf("failed to link expense and category, %w", err)
 		}
 		cats = append(cats, model.Category{Id: cid, Name: c})
 	}
-	e := model.Expense{
-		Id:          eid,
-		Date:        dt.Format("01-02-2006"),
-		Description: ne.Description,
-		Amount:      ne.Amount,
-		Categories:  cats,
-		Comment:     *ne.Comment,
+	return cats, nil
+}
+
+func linkTags(ctx context.Context, eid int, names []string, db Database) ([]model.Tag, error) {
+	var tags []model.Tag
+	for _, t := range NormalizeTags(names) {
+		tid, ok, err := db.GetTagId(ctx, t)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get tag_id, %w", err)
+		}
+		if !ok {
+			tid, err = db.CreateTag(ctx, t)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create new tag, %w", err)
+			}
+		}
+		_, err = db.LinkExpenseTag(ctx, eid, tid)
+		if err != nil {
+			return nil, fmt.Errorf("failed to link expense and tag, %w", err)
+		}
+		tags = append(tags, model.Tag{Id: tid, Name: t})
 	}
-	return e, nil
+	return tags, nil
+}
+
+// NormalizeTags trims and lower cases tag names, dropping empty and
+// repeated ones.
+func NormalizeTags(names []string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, n := range names {
+		t := strings.ToLower(strings.TrimSpace(n))
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		tags = append(tags, t)
+	}
+	return tags
+}
+
+// NormalizeCategories trims category names, dropping empty and repeated
+// ones. Unlike tags, categories keep their case.
+func NormalizeCategories(names []string) []string {
+	var cats []string
+	seen := make(map[string]bool)
+	for _, n := range names {
+		c := strings.TrimSpace(n)
+		if c == "" || seen[c] {
+			continue
+		}
+		seen[c] = true
+		cats = append(cats, c)
+	}
+	return cats
 }
 
 func ListExpenses(ctx context.Context, db Database) ([]*model.Expense, error) {
@@ -77,3 +217,21 @@ func ListExpenses(ctx context.Context, db Database) ([]*model.Expense, error) {
 	}
 	return exp, nil
 }
+
+// FindExpenses lists the expenses matching the filter, which is checked
+// and resolved with ResolveFilter first.
+func FindExpenses(ctx context.Context, f model.ExpenseFilter, db Database) ([]*model.Expense, error) {
+	f, err := ResolveFilter(f, time.Now().UTC())
+	if err != nil {
+		return []*model.Expense{}, err
+	}
+	ex, err := db.FindExpenses(ctx, f)
+	if err != nil {
+		return []*model.Expense{}, fmt.Errorf("failed to find expenses, %w", err)
+	}
+	exp := []*model.Expense{}
+	for i := range ex {
+		exp = append(exp, &ex[i])
+	}
+	return exp, nil
+}
@@ -2,6 +2,9 @@ package expense
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"math"
 	"math/rand"
 	"sort"
 	"testing"
@@ -26,6 +29,14 @@ type MockDatabase struct {
 		Eid int
 		Cid int
 	}
+	tag     map[int]string
+	tagLink map[int]struct {
+		Id  int
+		Eid int
+		Tid int
+	}
+	reimb      map[int]bool
+	replaceErr error
 }
 
 func (mdb *MockDatabase) GetDescriptionId(ctx context.Context, d string) (int, bool, error) {
@@ -82,6 +93,131 @@ func (mdb *MockDatabase) LinkExpenseCategory(ctx context.Context, eid int, cid i
 	return id, nil
 }
 
+func (mdb *MockDatabase) ExpenseExists(ctx context.Context, eid int) (bool, error) {
+	_, ok := mdb.exp[eid]
+	return ok, nil
+}
+
+func (mdb *MockDatabase) ReplaceExpense(ctx context.Context, e model.Expense, r *bool) error {
+	if mdb.replaceErr != nil {
+		return mdb.replaceErr
+	}
+	if _, ok := mdb.exp[e.Id]; !ok {
+		return fmt.Errorf("expense id=%v does not exist", e.Id)
+	}
+	did, err := descriptionId(ctx, e.Description, mdb)
+	if err != nil {
+		return err
+	}
+	dt, _ := time.Parse(DateLayout, e.Date)
+	mdb.exp[e.Id] = struct {
+		Id      int
+		Date    time.Time
+		Did     int
+		Amount  float64
+		Comment string
+	}{e.Id, dt, did, e.Amount, e.Comment}
+	for id, l := range mdb.link {
+		if l.Eid == e.Id {
+			delete(mdb.link, id)
+		}
+	}
+	for id, l := range mdb.tagLink {
+		if l.Eid == e.Id {
+			delete(mdb.tagLink, id)
+		}
+	}
+	var cats, tags []string
+	for _, c := range e.Categories {
+		cats = append(cats, c.Name)
+	}
+	for _, t := range e.Tags {
+		tags = append(tags, t.Name)
+	}
+	if _, err := linkCategories(ctx, e.Id, cats, mdb); err != nil {
+		return err
+	}
+	if _, err := linkTags(ctx, e.Id, tags, mdb); err != nil {
+		return err
+	}
+	if r != nil {
+		return mdb.SetReimbursable(ctx, e.Id, *r)
+	}
+	return nil
+}
+
+func (mdb *MockDatabase) GetExpense(ctx context.Context, id int) (model.Expense, bool, error) {
+	exps, _ := mdb.ListAllExpenses(ctx)
+	for _, e := range exps {
+		if e.Id == id {
+			return e, true, nil
+		}
+	}
+	return model.Expense{}, false, nil
+}
+
+func (mdb *MockDatabase) SetReimbursable(ctx context.Context, eid int, r bool) error {
+	if mdb.reimb == nil {
+		mdb.reimb = make(map[int]bool)
+	}
+	mdb.reimb[eid] = r
+	return nil
+}
+
+func (mdb *MockDatabase) GetTagId(ctx context.Context, tag string) (int, bool, error) {
+	for k, v := range mdb.tag {
+		if v == tag {
+			return k, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+func (mdb *MockDatabase) CreateTag(ctx context.Context, tag string) (int, error) {
+	if mdb.tag == nil {
+		mdb.tag = make(map[int]string)
+	}
+	id := rand.Int()
+	mdb.tag[id] = tag
+	return id, nil
+}
+
+func (mdb *MockDatabase) LinkExpenseTag(ctx context.Context, eid int, tid int) (int, error) {
+	if mdb.tagLink == nil {
+		mdb.tagLink = make(map[int]struct {
+			Id  int
+			Eid int
+			Tid int
+		})
+	}
+	id := rand.Int()
+	mdb.tagLink[id] = struct {
+		Id  int
+		Eid int
+		Tid int
+	}{id, eid, tid}
+	return id, nil
+}
+
+func (mdb *MockDatabase) FindExpenses(ctx context.Context, f model.ExpenseFilter) ([]model.Expense, error) {
+	all, _ := mdb.ListAllExpenses(ctx)
+	var exps []model.Expense
+	for _, e := range all {
+		has := make(map[string]bool)
+		for _, t := range e.Tags {
+			has[t.Name] = true
+		}
+		match := true
+		for _, t := range f.Tags {
+			match = match && has[t]
+		}
+		if match {
+			exps = append(exps, e)
+		}
+	}
+	return exps, nil
+}
+
 func (mdb *MockDatabase) ListAllExpenses(ctx context.Context) ([]model.Expense, error) {
 	var exps []model.Expense
 	for eid, e := range mdb.exp {
@@ -104,6 +240,19 @@ func (mdb *MockDatabase) ListAllExpenses(ctx context.Context) ([]model.Expense,
 		sort.Slice(exp.Categories, func(i, j int) bool {
 			return exp.Categories[i].Id < exp.Categories[j].Id
 		})
+		for _, l := range mdb.tagLink {
+			if l.Eid == eid {
+				exp.Tags = append(exp.Tags, model.Tag{Id: l.Tid, Name: mdb.tag[l.Tid]})
+			}
+		}
+		sort.Slice(exp.Tags, func(i, j int) bool {
+			return exp.Tags[i].Name < exp.Tags[j].Name
+		})
+		if mdb.reimb[eid] {
+			rs := model.ReimbursementStatusPending
+			exp.Reimbursable = true
+			exp.ReimbursementStatus = &rs
+		}
 		exps = append(exps, exp)
 	}
 	sort.Slice(exps, func(i, j int) bool {
@@ -270,3 +419,444 @@ func TestListExpenses(t *testing.T) {
 	}
 	assert.Equal(t, want, actual)
 }
+
+func TestSaveExpenseTags(t *testing.T) {
+	mock := MockDatabase{
+		desc: make(map[int]string),
+		cat:  make(map[int]string),
+		exp: make(map[int]struct {
+			Id      int
+			Date    time.Time
+			Did     int
+			Amount  float64
+			Comment string
+		}),
+		link: make(map[int]struct {
+			Id  int
+			Eid int
+			Cid int
+		}),
+		tag: map[int]string{9: "reimbursable"},
+	}
+	cmt := ""
+	input := model.NewExpense{
+		Date:        "03-02-2022",
+		Description: "hotel",
+		Amount:      210,
+		Categories:  []string{" Travel "},
+		Tags:        []string{"Reimbursable", " trip-2026-lisbon ", "reimbursable", ""},
+		Comment:     &cmt,
+	}
+	actual, err := SaveExpense(context.Background(), input, &mock)
+	if err != nil {
+		t.Fatalf("error running SaveExpense func, %v", err)
+	}
+	assert.False(t, actual.Reimbursable)
+	assert.Nil(t, actual.ReimbursementStatus)
+	assert.Len(t, actual.Tags, 2)
+	assert.Equal(t, model.Tag{Id: 9, Name: "reimbursable"}, actual.Tags[0])
+	assert.Equal(t, "trip-2026-lisbon", actual.Tags[1].Name)
+	assert.Len(t, mock.tagLink, 2)
+	assert.Equal(t, "Travel", actual.Categories[0].Name, "category names are trimmed but keep their case")
+	for _, c := range mock.cat {
+		assert.Equal(t, "Travel", c)
+	}
+}
+
+func TestUpdateExpense(t *testing.T) {
+	mock := MockDatabase{
+		desc: map[int]string{2: "test desc"},
+		cat:  map[int]string{5: "test cat", 6: "old cat"},
+		exp: map[int]struct {
+			Id      int
+			Date    time.Time
+			Did     int
+			Amount  float64
+			Comment string
+		}{
+			1: {Id: 1, Date: time.Now(), Did: 2, Amount: 15.0, Comment: "test comment"},
+		},
+		link: map[int]struct {
+			Id  int
+			Eid int
+			Cid int
+		}{
+			1: {Id: 1, Eid: 1, Cid: 6},
+		},
+		tag: map[int]string{3: "old tag"},
+		tagLink: map[int]struct {
+			Id  int
+			Eid int
+			Tid int
+		}{
+			1: {Id: 1, Eid: 1, Tid: 3},
+		},
+	}
+	reimbursable := true
+	input := model.NewExpense{
+		Date:         "03-01-2022",
+		Description:  "test desc",
+		Amount:       20.5,
+		Categories:   []string{"test cat"},
+		Tags:         []string{"tax-deductible"},
+		Reimbursable: &reimbursable,
+	}
+	actual, err := UpdateExpense(context.Background(), 1, input, &mock)
+	if err != nil {
+		t.Fatalf("error running UpdateExpense func, %v", err)
+	}
+	pending := model.ReimbursementStatusPending
+	want := model.Expense{
+		Id:                  1,
+		Date:                "03-01-2022",
+		Description:         "test desc",
+		Amount:              20.5,
+		Categories:          []model.Category{{Id: 5, Name: "test cat"}},
+		Tags:                []model.Tag{{Id: actual.Tags[0].Id, Name: "tax-deductible"}},
+		Reimbursable:        true,
+		ReimbursementStatus: &pending,
+	}
+	assert.Equal(t, want, actual)
+	exps, _ := mock.ListAllExpenses(context.Background())
+	assert.Equal(t, []model.Expense{want}, exps)
+	t.Run("missing expense", func(t *testing.T) {
+		_, err := UpdateExpense(context.Background(), 99, input, &mock)
+		var nf *NotFoundError
+		assert.ErrorAs(t, err, &nf)
+	})
+	t.Run("store failure", func(t *testing.T) {
+		mock.replaceErr = errors.New("connection reset")
+		defer func() { mock.replaceErr = nil }()
+		_, err := UpdateExpense(context.Background(), 1, input, &mock)
+		assert.ErrorIs(t, err, mock.replaceErr)
+		exps, _ := mock.ListAllExpenses(context.Background())
+		assert.Equal(t, []model.Expense{want}, exps, "the expense is left as it was")
+	})
+	t.Run("bad date", func(t *testing.T) {
+		bad := input
+		bad.Date = "2022-03-01"
+		_, err := UpdateExpense(context.Background(), 1, bad, &mock)
+		var ves ValidationErrors
+		if assert.ErrorAs(t, err, &ves) {
+			assert.Equal(t, "date", ves[0].Field)
+		}
+	})
+}
+
+func TestFindExpenses(t *testing.T) {
+	nt := time.Now()
+	mock := MockDatabase{
+		desc: map[int]string{2: "test desc"},
+		exp: map[int]struct {
+			Id      int
+			Date    time.Time
+			Did     int
+			Amount  float64
+			Comment string
+		}{
+			1: {Id: 1, Date: nt, Did: 2, Amount: 15.0},
+			4: {Id: 4, Date: nt, Did: 2, Amount: 4.88},
+		},
+		tag: map[int]string{7: "reimbursable", 8: "tax-deductible"},
+		tagLink: map[int]struct {
+			Id  int
+			Eid int
+			Tid int
+		}{
+			1: {Id: 1, Eid: 1, Tid: 7},
+			2: {Id: 2, Eid: 4, Tid: 7},
+			3: {Id: 3, Eid: 4, Tid: 8},
+		},
+	}
+	actual, err := FindExpenses(context.Background(), model.ExpenseFilter{Tags: []string{"Reimbursable", "tax-deductible"}}, &mock)
+	if err != nil {
+		t.Fatalf("error running FindExpenses func, %v", err)
+	}
+	assert.Len(t, actual, 1)
+	assert.Equal(t, 4, actual[0].Id)
+	actual, err = FindExpenses(context.Background(), model.ExpenseFilter{}, &mock)
+	if err != nil {
+		t.Fatalf("error running FindExpenses func, %v", err)
+	}
+	assert.Len(t, actual, 2)
+}
+
+func newMock() *MockDatabase {
+	return &MockDatabase{
+		desc: make(map[int]string),
+		cat:  make(map[int]string),
+		exp: make(map[int]struct {
+			Id      int
+			Date    time.Time
+			Did     int
+			Amount  float64
+			Comment string
+		}),
+		link: make(map[int]struct {
+			Id  int
+			Eid int
+			Cid int
+		}),
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	valid := model.NewExpense{
+		Date:        "02-21-2022",
+		Description: "lunch",
+		Amount:      12.45,
+		Categories:  []string{"food", "work"},
+	}
+	cases := []struct {
+		name   string
+		change func(*model.NewExpense)
+		fields []string
+	}{
+		{name: "valid", change: func(ne *model.NewExpense) {}},
+		{name: "negative amount", change: func(ne *model.NewExpense) { ne.Amount = -3 }},
+		{name: "no categories", change: func(ne *model.NewExpense) { ne.Categories = nil }},
+		{name: "next month", change: func(ne *model.NewExpense) { ne.Date = "04-01-2022" }},
+		{name: "bad date", change: func(ne *model.NewExpense) { ne.Date = "2022-02-21" }, fields: []string{"date"}},
+		{name: "far future", change: func(ne *model.NewExpense) { ne.Date = "02-21-2202" }, fields: []string{"date"}},
+		{name: "empty description", change: func(ne *model.NewExpense) { ne.Description = "  " }, fields: []string{"description"}},
+		{name: "zero amount", change: func(ne *model.NewExpense) { ne.Amount = 0 }, fields: []string{"amount"}},
+		{name: "nan amount", change: func(ne *model.NewExpense) { ne.Amount = math.NaN() }, fields: []string{"amount"}},
+		{name: "infinite amount", change: func(ne *model.NewExpense) { ne.Amount = math.Inf(1) }, fields: []string{"amount"}},
+		{name: "empty category", change: func(ne *model.NewExpense) { ne.Categories = []string{"food", ""} }, fields: []string{"categories"}},
+		{name: "duplicate category", change: func(ne *model.NewExpense) { ne.Categories = []string{"food", " food"} }, fields: []string{"categories"}},
+		{
+			name: "everything",
+			change: func(ne *model.NewExpense) {
+				*ne = model.NewExpense{Date: "tomorrow", Categories: []string{""}}
+			},
+			fields: []string{"date", "description", "amount", "categories"},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ne := valid
+			c.change(&ne)
+			dt, err := Validate(ne, now)
+			if len(c.fields) == 0 {
+				assert.Nil(t, err)
+				assert.Equal(t, ne.Date, dt.Format(DateLayout))
+				return
+			}
+			var ves ValidationErrors
+			if !assert.ErrorAs(t, err, &ves) {
+				return
+			}
+			var fields []string
+			for _, ve := range ves {
+				fields = append(fields, ve.Field)
+			}
+			assert.Equal(t, c.fields, fields)
+		})
+	}
+}
+
+func TestSaveExpenseValidation(t *testing.T) {
+	cases := []struct {
+		name  string
+		input model.NewExpense
+		valid bool
+	}{
+		{
+			name:  "no comment",
+			input: model.NewExpense{Date: "02-21-2022", Description: "lunch", Amount: 9.5, Categories: []string{"food"}},
+			valid: true,
+		},
+		{
+			name:  "empty description",
+			input: model.NewExpense{Date: "02-21-2022", Description: "", Amount: 9.5, Categories: []string{"food"}},
+		},
+		{
+			name:  "duplicate categories",
+			input: model.NewExpense{Date: "02-21-2022", Description: "lunch", Amount: 9.5, Categories: []string{"food", "food"}},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := newMock()
+			e, err := SaveExpense(context.Background(), c.input, mock)
+			if c.valid {
+				assert.Nil(t, err)
+				assert.Equal(t, "", e.Comment)
+				assert.Len(t, mock.exp, 1)
+				return
+			}
+			var ves ValidationErrors
+			assert.ErrorAs(t, err, &ves)
+			assert.Empty(t, mock.desc, "nothing is written for invalid input")
+			assert.Empty(t, mock.exp)
+		})
+	}
+}
+
+type MockBatchDatabase struct {
+	saved [][]model.Expense
+	err   error
+}
+
+func (mdb *MockBatchDatabase) CreateExpenses(ctx context.Context, es []model.Expense) ([]model.Expense, error) {
+	if mdb.err != nil {
+		return nil, mdb.err
+	}
+	mdb.saved = append(mdb.saved, es)
+	out := make([]model.Expense, len(es))
+	for i, e := range es {
+		e.Id = 100 + i
+		out[i] = e
+	}
+	return out, nil
+}
+
+func TestSaveExpenses(t *testing.T) {
+	yes := true
+	valid := &model.NewExpense{Date: "02-21-2022", Description: "lunch", Amount: 12.5, Categories: []string{" food "}, Tags: []string{" Trip ", "trip"}, Reimbursable: &yes}
+	invalid := &model.NewExpense{Date: "02-21-2022", Description: "", Amount: 0, Categories: []string{"food"}}
+	cases := []struct {
+		name    string
+		inputs  []*model.NewExpense
+		mode    model.BatchMode
+		saved   []int
+		invalid []int
+	}{
+		{name: "all valid", inputs: []*model.NewExpense{valid, valid}, mode: model.BatchModeAllOrNothing, saved: []int{0, 1}},
+		{name: "all or nothing", inputs: []*model.NewExpense{valid, invalid, valid}, mode: model.BatchModeAllOrNothing, invalid: []int{1}},
+		{name: "best effort", inputs: []*model.NewExpense{valid, invalid, valid}, mode: model.BatchModeBestEffort, saved: []int{0, 2}, invalid: []int{1}},
+		{name: "none valid", inputs: []*model.NewExpense{invalid}, mode: model.BatchModeBestEffort, invalid: []int{0}},
+		{name: "empty", mode: model.BatchModeBestEffort},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := &MockBatchDatabase{}
+			results, err := SaveExpenses(context.Background(), c.inputs, c.mode, mock)
+			if !assert.Nil(t, err) {
+				return
+			}
+			assert.Len(t, results, len(c.inputs))
+			var saved, invalid []int
+			for i, r := range results {
+				assert.Equal(t, i, r.Index)
+				if r.Expense != nil {
+					saved = append(saved, i)
+				}
+				if len(r.Errors) > 0 {
+					invalid = append(invalid, i)
+				}
+			}
+			assert.Equal(t, c.saved, saved)
+			assert.Equal(t, c.invalid, invalid)
+			if len(c.saved) == 0 {
+				assert.Empty(t, mock.saved, "nothing is written")
+			} else {
+				assert.Len(t, mock.saved, 1, "expenses are saved in one batch")
+			}
+		})
+	}
+	t.Run("input", func(t *testing.T) {
+		mock := &MockBatchDatabase{}
+		results, _ := SaveExpenses(context.Background(), []*model.NewExpense{invalid, valid}, model.BatchModeBestEffort, mock)
+		assert.Equal(t, []*model.InputError{
+			{Field: "description", Message: "must not be empty"},
+			{Field: "amount", Message: "must not be zero"},
+		}, results[0].Errors)
+		assert.Equal(t, &model.Expense{
+			Id:           100,
+			Date:         "02-21-2022",
+			Description:  "lunch",
+			Amount:       12.5,
+			Categories:   []model.Category{{Name: "food"}},
+			Tags:         []model.Tag{{Name: "trip"}},
+			Reimbursable: true,
+		}, results[1].Expense)
+	})
+	t.Run("too many", func(t *testing.T) {
+		inputs := make([]*model.NewExpense, MaxBatch+1)
+		_, err := SaveExpenses(context.Background(), inputs, model.BatchModeBestEffort, &MockBatchDatabase{})
+		var ves ValidationErrors
+		assert.ErrorAs(t, err, &ves)
+	})
+	t.Run("database error", func(t *testing.T) {
+		_, err := SaveExpenses(context.Background(), []*model.NewExpense{valid}, model.BatchModeBestEffort, &MockBatchDatabase{err: errors.New("boom")})
+		assert.NotNil(t, err)
+	})
+}
+
+func TestResolveFilter(t *testing.T) {
+	now := time.Date(2022, 5, 17, 12, 0, 0, 0, time.UTC)
+	str := func(s string) *string { return &s }
+	num := func(f float64) *float64 { return &f }
+	quarter := model.PeriodThisQuarter
+	cases := []struct {
+		name   string
+		filter model.ExpenseFilter
+		want   model.ExpenseFilter
+		fields []string
+	}{
+		{name: "empty"},
+		{
+			name:   "tags",
+			filter: model.ExpenseFilter{Tags: []string{" Trip", "trip"}},
+			want:   model.ExpenseFilter{Tags: []string{"trip"}},
+		},
+		{
+			name:   "period",
+			filter: model.ExpenseFilter{Period: &quarter, MinAmount: num(50)},
+			want:   model.ExpenseFilter{From: str("04-01-2022"), To: str("06-30-2022"), MinAmount: num(50)},
+		},
+		{
+			name:   "dates",
+			filter: model.ExpenseFilter{From: str("01-01-2022"), To: str("01-31-2022")},
+			want:   model.ExpenseFilter{From: str("01-01-2022"), To: str("01-31-2022")},
+		},
+		{name: "bad date", filter: model.ExpenseFilter{From: str("2022-01-01")}, fields: []string{"from"}},
+		{name: "backwards dates", filter: model.ExpenseFilter{From: str("02-01-2022"), To: str("01-01-2022")}, fields: []string{"to"}},
+		{name: "period and dates", filter: model.ExpenseFilter{Period: &quarter, To: str("01-01-2022")}, fields: []string{"period"}},
+		{name: "nan amount", filter: model.ExpenseFilter{MinAmount: num(math.NaN())}, fields: []string{"minAmount"}},
+		{name: "backwards amounts", filter: model.ExpenseFilter{MinAmount: num(50), MaxAmount: num(10)}, fields: []string{"maxAmount"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f, err := ResolveFilter(c.filter, now)
+			if len(c.fields) == 0 {
+				assert.Nil(t, err)
+				assert.Equal(t, c.want, f)
+				return
+			}
+			var ves ValidationErrors
+			if !assert.ErrorAs(t, err, &ves) {
+				return
+			}
+			var fields []string
+			for _, ve := range ves {
+				fields = append(fields, ve.Field)
+			}
+			assert.Equal(t, c.fields, fields)
+		})
+	}
+}
+
+func TestPeriodDates(t *testing.T) {
+	now := time.Date(2022, 1, 17, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		period   model.Period
+		from, to string
+	}{
+		{model.PeriodThisMonth, "01-01-2022", "01-31-2022"},
+		{model.PeriodLastMonth, "12-01-2021", "12-31-2021"},
+		{model.PeriodThisQuarter, "01-01-2022", "03-31-2022"},
+		{model.PeriodLastQuarter, "10-01-2021", "12-31-2021"},
+		{model.PeriodThisYear, "01-01-2022", "12-31-2022"},
+		{model.PeriodLastYear, "01-01-2021", "12-31-2021"},
+	}
+	for _, c := range cases {
+		t.Run(c.period.String(), func(t *testing.T) {
+			from, to := PeriodDates(c.period, now)
+			assert.Equal(t, c.from, from.Format(DateLayout))
+			assert.Equal(t, c.to, to.Format(DateLayout))
+		})
+	}
+}
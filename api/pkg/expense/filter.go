@@ -0,0 +1,81 @@
+package expense
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/vapor05/financeview/graph/model"
+)
+
+// ResolveFilter checks an expense filter and readies it for the database on
+// the day of now. Tags are normalized, and a period is replaced by the from
+// and to dates it covers.
+func ResolveFilter(f model.ExpenseFilter, now time.Time) (model.ExpenseFilter, error) {
+	var errs ValidationErrors
+	add := func(field string, format string, args ...interface{}) {
+		errs = append(errs, &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	f.Tags = NormalizeTags(f.Tags)
+	date := func(field string, d *string) (time.Time, bool) {
+		if d == nil {
+			return time.Time{}, false
+		}
+		dt, err := time.Parse(DateLayout, *d)
+		if err != nil {
+			add(field, "%q is not a MM-DD-YYYY date", *d)
+			return time.Time{}, false
+		}
+		return dt, true
+	}
+	from, okFrom := date("from", f.From)
+	to, okTo := date("to", f.To)
+	if okFrom && okTo && to.Before(from) {
+		add("to", "must not be before from")
+	}
+	if f.Period != nil {
+		if f.From != nil || f.To != nil {
+			add("period", "can't be combined with from or to")
+		} else {
+			start, end := PeriodDates(*f.Period, now)
+			s, e := start.Format(DateLayout), end.Format(DateLayout)
+			f.From, f.To, f.Period = &s, &e, nil
+		}
+	}
+	if a := f.MinAmount; a != nil && (math.IsNaN(*a) || math.IsInf(*a, 0)) {
+		add("minAmount", "must be a number")
+	}
+	if a := f.MaxAmount; a != nil && (math.IsNaN(*a) || math.IsInf(*a, 0)) {
+		add("maxAmount", "must be a number")
+	}
+	if f.MinAmount != nil && f.MaxAmount != nil && *f.MaxAmount < *f.MinAmount {
+		add("maxAmount", "must not be less than minAmount")
+	}
+	if len(errs) > 0 {
+		return model.ExpenseFilter{}, errs
+	}
+	return f, nil
+}
+
+// PeriodDates returns the first and last day of a period counted from the
+// day of now.
+func PeriodDates(p model.Period, now time.Time) (time.Time, time.Time) {
+	y, m, _ := now.Date()
+	var start time.Time
+	months := 1
+	switch p {
+	case model.PeriodThisMonth, model.PeriodLastMonth:
+		start = time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	case model.PeriodThisQuarter, model.PeriodLastQuarter:
+		start = time.Date(y, (m-1)/3*3+1, 1, 0, 0, 0, 0, time.UTC)
+		months = 3
+	default:
+		start = time.Date(y, time.January, 1, 0, 0, 0, 0, time.UTC)
+		months = 12
+	}
+	switch p {
+	case model.PeriodLastMonth, model.PeriodLastQuarter, model.PeriodLastYear:
+		start = start.AddDate(0, -months, 0)
+	}
+	return start, start.AddDate(0, months, -1)
+}
@@ -0,0 +1,57 @@
+package expense
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/vapor05/financeview/graph/model"
+)
+
+// DateLayout is how expense dates are written, MM-DD-YYYY.
+const DateLayout = "01-02-2006"
+
+// MaxFuture is how far ahead of today an expense may be dated, leaving
+// room for scheduled payments but not for typos in the year.
+const MaxFuture = 366 * 24 * time.Hour
+
+// Validate checks every field of an expense input, returning its date and
+// ValidationErrors naming each field that is wrong.
+func Validate(ne model.NewExpense, now time.Time) (time.Time, error) {
+	var errs ValidationErrors
+	add := func(field string, format string, args ...interface{}) {
+		errs = append(errs, &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	dt, err := time.Parse(DateLayout, ne.Date)
+	switch {
+	case err != nil:
+		add("date", "%q is not a MM-DD-YYYY date", ne.Date)
+	case dt.After(now.Add(MaxFuture)):
+		add("date", "%s is more than a year from now", ne.Date)
+	}
+	if strings.TrimSpace(ne.Description) == "" {
+		add("description", "must not be empty")
+	}
+	switch {
+	case math.IsNaN(ne.Amount) || math.IsInf(ne.Amount, 0):
+		add("amount", "must be a number")
+	case ne.Amount == 0:
+		add("amount", "must not be zero")
+	}
+	seen := make(map[string]bool)
+	for _, c := range ne.Categories {
+		name := strings.TrimSpace(c)
+		switch {
+		case name == "":
+			add("categories", "names must not be empty")
+		case seen[name]:
+			add("categories", "%q is listed more than once", name)
+		}
+		seen[name] = true
+	}
+	if len(errs) > 0 {
+		return time.Time{}, errs
+	}
+	return dt, nil
+}
@@ -0,0 +1,167 @@
+package gqlguard
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+const (
+	errDepthLimit = "DEPTH_LIMIT_EXCEEDED"
+	errNotAllowed = "PERSISTED_QUERY_NOT_ALLOWED"
+)
+
+// DepthLimit rejects operations nesting fields deeper than Max.
+// Introspection fields don't count, so tools can still load the schema.
+type DepthLimit struct {
+	Max int
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = DepthLimit{}
+
+func (DepthLimit) ExtensionName() string {
+	return "DepthLimit"
+}
+
+func (DepthLimit) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (d DepthLimit) MutateOperationContext(ctx context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	if d.Max <= 0 || rc.Operation == nil {
+		return nil
+	}
+	if n := Depth(rc.Doc, rc.Operation.SelectionSet); n > d.Max {
+		err := gqlerror.Errorf("operation has depth %d, which exceeds the limit of %d", n, d.Max)
+		errcode.Set(err, errDepthLimit)
+		return err
+	}
+	return nil
+}
+
+// Depth returns how deeply fields are nested in a selection set, following
+// fragments.
+func Depth(doc *ast.QueryDocument, set ast.SelectionSet) int {
+	return depth(doc, set, map[string]bool{})
+}
+
+func depth(doc *ast.QueryDocument, set ast.SelectionSet, visiting map[string]bool) int {
+	max := 0
+	for _, sel := range set {
+		var n int
+		switch s := sel.(type) {
+		case *ast.Field:
+			if strings.HasPrefix(s.Name, "__") {
+				continue
+			}
+			n = 1 + depth(doc, s.SelectionSet, visiting)
+		case *ast.InlineFragment:
+			n = depth(doc, s.SelectionSet, visiting)
+		case *ast.FragmentSpread:
+			f := doc.Fragments.ForName(s.Name)
+			if f == nil || visiting[s.Name] {
+				continue
+			}
+			visiting[s.Name] = true
+			n = depth(doc, f.SelectionSet, visiting)
+			delete(visiting, s.Name)
+		}
+		if n > max {
+			max = n
+		}
+	}
+	return max
+}
+
+// Allowlist maps the sha256 hashes of persisted queries to the queries.
+type Allowlist map[string]string
+
+// LoadAllowlist reads a JSON object of sha256 hashes to queries, checking
+// every hash.
+func LoadAllowlist(path string) (Allowlist, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read persisted queries, %w", err)
+	}
+	var al Allowlist
+	if err := json.Unmarshal(b, &al); err != nil {
+		return nil, fmt.Errorf("failed to parse persisted queries, %w", err)
+	}
+	for h, q := range al {
+		if Hash(q) != h {
+			return nil, fmt.Errorf("persisted query %s does not match its hash", h)
+		}
+	}
+	return al, nil
+}
+
+// Hash returns the hex sha256 hash APQ clients send for a query.
+func Hash(query string) string {
+	b := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(b[:])
+}
+
+// Cache serves persisted queries to gqlgen's AutomaticPersistedQuery
+// extension from the allowlist first, then from Fallback. Queries clients
+// register are only kept when there is a fallback.
+type Cache struct {
+	Allowlist Allowlist
+	Fallback  graphql.Cache
+}
+
+func (c Cache) Get(ctx context.Context, hash string) (interface{}, bool) {
+	if q, ok := c.Allowlist[hash]; ok {
+		return q, true
+	}
+	if c.Fallback == nil {
+		return nil, false
+	}
+	return c.Fallback.Get(ctx, hash)
+}
+
+func (c Cache) Add(ctx context.Context, hash string, query interface{}) {
+	if c.Fallback != nil {
+		c.Fallback.Add(ctx, hash, query)
+	}
+}
+
+// AllowlistOnly rejects any query not in the allowlist. It must be added
+// after AutomaticPersistedQuery so queries sent by hash are filled in
+// first.
+type AllowlistOnly struct {
+	Allowlist Allowlist
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationParameterMutator
+} = AllowlistOnly{}
+
+func (AllowlistOnly) ExtensionName() string {
+	return "AllowlistOnly"
+}
+
+func (AllowlistOnly) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (a AllowlistOnly) MutateOperationParameters(ctx context.Context, rawParams *graphql.RawParams) *gqlerror.Error {
+	if _, ok := a.Allowlist[Hash(rawParams.Query)]; ok {
+		return nil
+	}
+	err := gqlerror.Errorf("only persisted queries are allowed")
+	errcode.Set(err, errNotAllowed)
+	return err
+}
@@ -0,0 +1,91 @@
+package gqlguard
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/stretchr/testify/assert"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+func TestDepth(t *testing.T) {
+	cases := []struct {
+		name  string
+		query string
+		want  int
+	}{
+		{name: "flat", query: `{ ledgers { id } }`, want: 2},
+		{name: "nested", query: `{ expenses { payee { aliases } } }`, want: 3},
+		{name: "inline fragment", query: `{ expenses { ... on Expense { payee { id } } } }`, want: 3},
+		{name: "fragment", query: `{ expenses { ...E } } fragment E on Expense { payee { id } }`, want: 3},
+		{name: "introspection", query: `{ __schema { types { fields { type { name } } } } }`, want: 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			doc, err := parser.ParseQuery(&ast.Source{Input: c.query})
+			assert.Nil(t, err)
+			assert.Equal(t, c.want, Depth(doc, doc.Operations[0].SelectionSet))
+		})
+	}
+}
+
+func TestDepthLimit(t *testing.T) {
+	doc, err := parser.ParseQuery(&ast.Source{Input: `{ expenses { payee { aliases } } }`})
+	assert.Nil(t, err)
+	rc := &graphql.OperationContext{Doc: doc, Operation: doc.Operations[0]}
+	assert.Nil(t, DepthLimit{Max: 3}.MutateOperationContext(context.Background(), rc))
+	assert.Nil(t, DepthLimit{}.MutateOperationContext(context.Background(), rc))
+	gerr := DepthLimit{Max: 2}.MutateOperationContext(context.Background(), rc)
+	assert.NotNil(t, gerr)
+	assert.Equal(t, errDepthLimit, gerr.Extensions["code"])
+}
+
+func TestLoadAllowlist(t *testing.T) {
+	dir := t.TempDir()
+	q := `{ ledgers { id } }`
+	good := filepath.Join(dir, "good.json")
+	assert.Nil(t, os.WriteFile(good, []byte(`{"`+Hash(q)+`": "{ ledgers { id } }"}`), 0o600))
+	al, err := LoadAllowlist(good)
+	assert.Nil(t, err)
+	assert.Equal(t, Allowlist{Hash(q): q}, al)
+
+	bad := filepath.Join(dir, "bad.json")
+	assert.Nil(t, os.WriteFile(bad, []byte(`{"`+Hash(q)+`": "{ trash { id } }"}`), 0o600))
+	_, err = LoadAllowlist(bad)
+	assert.NotNil(t, err)
+
+	_, err = LoadAllowlist(filepath.Join(dir, "missing.json"))
+	assert.NotNil(t, err)
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	q := `{ ledgers { id } }`
+	c := Cache{Allowlist: Allowlist{Hash(q): q}}
+	got, ok := c.Get(ctx, Hash(q))
+	assert.True(t, ok)
+	assert.Equal(t, q, got)
+	c.Add(ctx, "other", "{ trash { id } }")
+	_, ok = c.Get(ctx, "other")
+	assert.False(t, ok)
+
+	c.Fallback = lru.New(10)
+	c.Add(ctx, "other", "{ trash { id } }")
+	got, ok = c.Get(ctx, "other")
+	assert.True(t, ok)
+	assert.Equal(t, "{ trash { id } }", got)
+}
+
+func TestAllowlistOnly(t *testing.T) {
+	q := `{ ledgers { id } }`
+	a := AllowlistOnly{Allowlist: Allowlist{Hash(q): q}}
+	assert.Nil(t, a.MutateOperationParameters(context.Background(), &graphql.RawParams{Query: q}))
+	gerr := a.MutateOperationParameters(context.Background(), &graphql.RawParams{Query: `{ trash { id } }`})
+	assert.NotNil(t, gerr)
+	assert.Equal(t, errNotAllowed, gerr.Extensions["code"])
+}
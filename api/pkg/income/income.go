@@ -0,0 +1,62 @@
+package income
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vapor05/financeview/graph/model"
+)
+
+type Database interface {
+	CreateIncome(context.Context, time.Time, string, float64, string) (int, error)
+	GetIncome(context.Context, int) (model.Income, bool, error)
+	ListIncomes(context.Context) ([]model.Income, error)
+}
+
+func SaveIncome(ctx context.Context, ni model.NewIncome, db Database) (model.Income, error) {
+	dt, err := time.Parse("01-02-2006", ni.Date)
+	if err != nil {
+		return model.Income{}, fmt.Errorf("failed to parse new income date, %w", err)
+	}
+	var cmt string
+	if ni.Comment != nil {
+		cmt = *ni.Comment
+	}
+	id, err := db.CreateIncome(ctx, dt, ni.Description, ni.Amount, cmt)
+	if err != nil {
+		return model.Income{}, fmt.Errorf("failed to save new income data, %w", err)
+	}
+	i := model.Income{
+		Id:          id,
+		Date:        dt.Format("01-02-2006"),
+		Description: ni.Description,
+		Amount:      ni.Amount,
+		Comment:     cmt,
+	}
+	return i, nil
+}
+
+// GetIncome returns nil if no income has the id.
+func GetIncome(ctx context.Context, id int, db Database) (*model.Income, error) {
+	i, ok, err := db.GetIncome(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get income id=%v, %w", id, err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	return &i, nil
+}
+
+func ListIncomes(ctx context.Context, db Database) ([]*model.Income, error) {
+	is, err := db.ListIncomes(ctx)
+	if err != nil {
+		return []*model.Income{}, fmt.Errorf("failed to list income, %w", err)
+	}
+	inc := []*model.Income{}
+	for i := range is {
+		inc = append(inc, &is[i])
+	}
+	return inc, nil
+}
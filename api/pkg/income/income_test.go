@@ -0,0 +1,60 @@
+package income
+
+import (
+	"context"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+type MockDatabase struct {
+	inc map[int]model.Income
+}
+
+func (mdb *MockDatabase) CreateIncome(ctx context.Context, dt time.Time, desc string, amt float64, cmt string) (int, error) {
+	id := rand.Int()
+	mdb.inc[id] = model.Income{Id: id, Date: dt.Format("01-02-2006"), Description: desc, Amount: amt, Comment: cmt}
+	return id, nil
+}
+
+func (mdb *MockDatabase) GetIncome(ctx context.Context, id int) (model.Income, bool, error) {
+	i, ok := mdb.inc[id]
+	return i, ok, nil
+}
+
+func (mdb *MockDatabase) ListIncomes(ctx context.Context) ([]model.Income, error) {
+	var is []model.Income
+	for _, i := range mdb.inc {
+		is = append(is, i)
+	}
+	return is, nil
+}
+
+func TestSaveIncome(t *testing.T) {
+	mock := MockDatabase{inc: make(map[int]model.Income)}
+	input := model.NewIncome{Date: "03-15-2022", Description: "expense report", Amount: 455}
+	actual, err := SaveIncome(context.Background(), input, &mock)
+	if err != nil {
+		t.Fatalf("error running SaveIncome func, %v", err)
+	}
+	want := model.Income{Id: actual.Id, Date: "03-15-2022", Description: "expense report", Amount: 455}
+	assert.Equal(t, want, actual)
+	assert.Equal(t, want, mock.inc[actual.Id])
+	_, err = SaveIncome(context.Background(), model.NewIncome{Date: "2022-03-15"}, &mock)
+	assert.Error(t, err)
+}
+
+func TestGetIncome(t *testing.T) {
+	mock := MockDatabase{inc: map[int]model.Income{3: {Id: 3, Amount: 10}}}
+	actual, err := GetIncome(context.Background(), 3, &mock)
+	if err != nil {
+		t.Fatalf("error running GetIncome func, %v", err)
+	}
+	assert.Equal(t, &model.Income{Id: 3, Amount: 10}, actual)
+	actual, err = GetIncome(context.Background(), 4, &mock)
+	assert.Nil(t, err)
+	assert.Nil(t, actual)
+}
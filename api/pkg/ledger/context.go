@@ -0,0 +1,58 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+
+	"github.com/vapor05/financeview/graph/model"
+)
+
+var ErrNoLedger = errors.New("no ledger selected")
+
+type contextKey int
+
+const (
+	ledgerKey contextKey = iota
+	roleKey
+)
+
+// WithLedger returns a context acting on a ledger with the user's role in it.
+func WithLedger(ctx context.Context, id int, role model.Role) context.Context {
+	ctx = context.WithValue(ctx, ledgerKey, id)
+	return context.WithValue(ctx, roleKey, role)
+}
+
+func FromContext(ctx context.Context) (int, model.Role, bool) {
+	id, ok := ctx.Value(ledgerKey).(int)
+	if !ok {
+		return 0, "", false
+	}
+	role, _ := ctx.Value(roleKey).(model.Role)
+	return id, role, true
+}
+
+// Id returns the selected ledger's id, or ErrNoLedger.
+func Id(ctx context.Context) (int, error) {
+	id, _, ok := FromContext(ctx)
+	if !ok {
+		return 0, ErrNoLedger
+	}
+	return id, nil
+}
+
+// Allows reports whether a role grants at least the access of another.
+func Allows(have model.Role, need model.Role) bool {
+	return rank(have) >= rank(need) && rank(need) > 0
+}
+
+func rank(r model.Role) int {
+	switch r {
+	case model.RoleOwner:
+		return 3
+	case model.RoleEditor:
+		return 2
+	case model.RoleViewer:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,304 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/auth"
+	"github.com/vapor05/financeview/pkg/expense"
+)
+
+// InvitationTTL is how long an invitation can be accepted for.
+const InvitationTTL = 7 * 24 * time.Hour
+
+// DefaultName names the ledger each user is given when they register.
+const DefaultName = "Personal"
+
+var (
+	ErrNotMember  = errors.New("not a member of the ledger")
+	ErrLastOwner  = errors.New("a ledger must keep at least one owner")
+	ErrInvitation = errors.New("invitation is invalid or has expired")
+	// ErrMemberNotFound is returned for changing a user who isn't a
+	// member of the selected ledger.
+	ErrMemberNotFound = errors.New("user is not a member of the ledger")
+)
+
+type Database interface {
+	CreateLedger(context.Context, string, int) (int, error)
+	ListLedgers(context.Context, int) ([]model.Ledger, error)
+	GetLedgerRole(context.Context, int, int) (model.Role, bool, error)
+	ListMembers(context.Context, int) ([]model.LedgerMember, error)
+	SetMemberRole(context.Context, int, int, model.Role) error
+	DeleteMember(context.Context, int, int) error
+	CreateInvitation(context.Context, model.Invitation, string, int, time.Time) (int, error)
+	GetInvitation(context.Context, string, time.Time) (model.Invitation, bool, error)
+	ListInvitations(context.Context, int, time.Time) ([]model.Invitation, error)
+	AcceptInvitation(context.Context, model.Invitation, int) error
+	DeleteInvitation(context.Context, int, int) error
+}
+
+// Select returns the context acting on the ledger with the id, or on the
+// user's first ledger when id is zero. A user who isn't a member of any
+// ledger is left without one until they create one. Requests made with an
+// API token act with no more than the token's scope allows.
+func Select(ctx context.Context, id int, db Database) (context.Context, error) {
+	uid, err := auth.UserId(ctx)
+	if err != nil {
+		return ctx, err
+	}
+	if id != 0 {
+		role, ok, err := db.GetLedgerRole(ctx, id, uid)
+		if err != nil {
+			return ctx, fmt.Errorf("failed to get ledger role, %w", err)
+		}
+		if !ok {
+			return ctx, ErrNotMember
+		}
+		return WithLedger(ctx, id, capRole(ctx, role)), nil
+	}
+	ls, err := db.ListLedgers(ctx, uid)
+	if err != nil {
+		return ctx, fmt.Errorf("failed to list ledgers, %w", err)
+	}
+	if len(ls) == 0 {
+		return ctx, nil
+	}
+	return WithLedger(ctx, ls[0].Id, capRole(ctx, ls[0].Role)), nil
+}
+
+// capRole lowers a role to the most an API token's scope allows.
+func capRole(ctx context.Context, role model.Role) model.Role {
+	s, ok := auth.ScopeFromContext(ctx)
+	if !ok {
+		return role
+	}
+	var max model.Role
+	switch s {
+	case model.TokenScopeAdmin:
+		return role
+	case model.TokenScopeWrite:
+		max = model.RoleEditor
+	default:
+		max = model.RoleViewer
+	}
+	if Allows(role, max) {
+		return max
+	}
+	return role
+}
+
+func CreateLedger(ctx context.Context, name string, db Database) (model.Ledger, error) {
+	uid, err := auth.RequireScope(ctx, model.TokenScopeWrite)
+	if err != nil {
+		return model.Ledger{}, err
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return model.Ledger{}, expense.ValidationErrors{{Field: "name", Message: "must not be empty"}}
+	}
+	id, err := db.CreateLedger(ctx, name, uid)
+	if err != nil {
+		return model.Ledger{}, fmt.Errorf("failed to create ledger, %w", err)
+	}
+	return model.Ledger{Id: id, Name: name, Role: model.RoleOwner}, nil
+}
+
+func ListLedgers(ctx context.Context, db Database) ([]*model.Ledger, error) {
+	uid, err := auth.UserId(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ls, err := db.ListLedgers(ctx, uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ledgers, %w", err)
+	}
+	out := make([]*model.Ledger, len(ls))
+	for i := range ls {
+		out[i] = &ls[i]
+	}
+	return out, nil
+}
+
+func ListMembers(ctx context.Context, db Database) ([]*model.LedgerMember, error) {
+	lid, err := Id(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ms, err := db.ListMembers(ctx, lid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ledger members, %w", err)
+	}
+	out := make([]*model.LedgerMember, len(ms))
+	for i := range ms {
+		out[i] = &ms[i]
+	}
+	return out, nil
+}
+
+// Invite creates an invitation to the selected ledger. The returned token
+// is the only copy; just its hash is stored.
+func Invite(ctx context.Context, email string, role model.Role, db Database) (model.Invitation, error) {
+	lid, err := Id(ctx)
+	if err != nil {
+		return model.Invitation{}, err
+	}
+	uid, err := auth.UserId(ctx)
+	if err != nil {
+		return model.Invitation{}, err
+	}
+	email, err = auth.NormalizeEmail(email)
+	if err != nil {
+		return model.Invitation{}, err
+	}
+	if !role.IsValid() {
+		return model.Invitation{}, expense.ValidationErrors{{Field: "role", Message: fmt.Sprintf("%s is not a valid role", role)}}
+	}
+	tok, err := auth.NewToken()
+	if err != nil {
+		return model.Invitation{}, err
+	}
+	exp := time.Now().UTC().Add(InvitationTTL)
+	inv := model.Invitation{LedgerId: lid, Email: email, Role: role, Token: tok, ExpiresAt: exp.Format(time.RFC3339)}
+	inv.Id, err = db.CreateInvitation(ctx, inv, auth.HashToken(tok), uid, exp)
+	if err != nil {
+		return model.Invitation{}, fmt.Errorf("failed to create invitation, %w", err)
+	}
+	return inv, nil
+}
+
+func ListInvitations(ctx context.Context, db Database) ([]*model.Invitation, error) {
+	lid, err := Id(ctx)
+	if err != nil {
+		return nil, err
+	}
+	is, err := db.ListInvitations(ctx, lid, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invitations, %w", err)
+	}
+	out := make([]*model.Invitation, len(is))
+	for i := range is {
+		out[i] = &is[i]
+	}
+	return out, nil
+}
+
+func RevokeInvitation(ctx context.Context, id int, db Database) error {
+	lid, err := Id(ctx)
+	if err != nil {
+		return err
+	}
+	if err := db.DeleteInvitation(ctx, lid, id); err != nil {
+		return fmt.Errorf("failed to revoke invitation, %w", err)
+	}
+	return nil
+}
+
+// AcceptInvitation adds the user to the ledger an invitation was sent for.
+// Only the invited email address can accept it, and not with a READ scoped
+// API token.
+func AcceptInvitation(ctx context.Context, token string, db Database) (model.Ledger, error) {
+	if _, err := auth.RequireScope(ctx, model.TokenScopeWrite); err != nil {
+		return model.Ledger{}, err
+	}
+	u, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return model.Ledger{}, auth.ErrUnauthenticated
+	}
+	inv, ok, err := db.GetInvitation(ctx, auth.HashToken(token), time.Now().UTC())
+	if err != nil {
+		return model.Ledger{}, fmt.Errorf("failed to get invitation, %w", err)
+	}
+	if !ok || !strings.EqualFold(inv.Email, u.Email) {
+		return model.Ledger{}, ErrInvitation
+	}
+	if err := db.AcceptInvitation(ctx, inv, u.Id); err != nil {
+		return model.Ledger{}, fmt.Errorf("failed to accept invitation, %w", err)
+	}
+	ls, err := db.ListLedgers(ctx, u.Id)
+	if err != nil {
+		return model.Ledger{}, fmt.Errorf("failed to list ledgers, %w", err)
+	}
+	for _, l := range ls {
+		if l.Id == inv.LedgerId {
+			return l, nil
+		}
+	}
+	return model.Ledger{}, ErrNotMember
+}
+
+func SetMemberRole(ctx context.Context, uid int, role model.Role, db Database) (model.LedgerMember, error) {
+	if !role.IsValid() {
+		return model.LedgerMember{}, expense.ValidationErrors{{Field: "role", Message: fmt.Sprintf("%s is not a valid role", role)}}
+	}
+	lid, m, err := member(ctx, uid, db)
+	if err != nil {
+		return model.LedgerMember{}, err
+	}
+	if m.Role == model.RoleOwner && role != model.RoleOwner {
+		if err := keepOwner(ctx, lid, db); err != nil {
+			return model.LedgerMember{}, err
+		}
+	}
+	if err := db.SetMemberRole(ctx, lid, uid, role); err != nil {
+		return model.LedgerMember{}, fmt.Errorf("failed to set member role, %w", err)
+	}
+	m.Role = role
+	return m, nil
+}
+
+func RemoveMember(ctx context.Context, uid int, db Database) error {
+	lid, m, err := member(ctx, uid, db)
+	if err != nil {
+		return err
+	}
+	if m.Role == model.RoleOwner {
+		if err := keepOwner(ctx, lid, db); err != nil {
+			return err
+		}
+	}
+	if err := db.DeleteMember(ctx, lid, uid); err != nil {
+		return fmt.Errorf("failed to remove member, %w", err)
+	}
+	return nil
+}
+
+// member returns the selected ledger's id and its member with the user id.
+func member(ctx context.Context, uid int, db Database) (int, model.LedgerMember, error) {
+	lid, err := Id(ctx)
+	if err != nil {
+		return 0, model.LedgerMember{}, err
+	}
+	ms, err := db.ListMembers(ctx, lid)
+	if err != nil {
+		return 0, model.LedgerMember{}, fmt.Errorf("failed to list ledger members, %w", err)
+	}
+	for _, m := range ms {
+		if m.User.Id == uid {
+			return lid, m, nil
+		}
+	}
+	return 0, model.LedgerMember{}, ErrMemberNotFound
+}
+
+// keepOwner fails unless the ledger has more than one owner, so one can be
+// demoted or removed.
+func keepOwner(ctx context.Context, lid int, db Database) error {
+	ms, err := db.ListMembers(ctx, lid)
+	if err != nil {
+		return fmt.Errorf("failed to list ledger members, %w", err)
+	}
+	n := 0
+	for _, m := range ms {
+		if m.Role == model.RoleOwner {
+			n++
+		}
+	}
+	if n < 2 {
+		return ErrLastOwner
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package ledger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/auth"
+)
+
+type invitation struct {
+	inv      model.Invitation
+	expires  time.Time
+	accepted bool
+}
+
+type MockDatabase struct {
+	names   map[int]string
+	members map[int][]model.LedgerMember
+	invs    map[string]*invitation
+}
+
+func newMock() *MockDatabase {
+	return &MockDatabase{
+		names:   make(map[int]string),
+		members: make(map[int][]model.LedgerMember),
+		invs:    make(map[string]*invitation),
+	}
+}
+
+func (mdb *MockDatabase) CreateLedger(ctx context.Context, name string, uid int) (int, error) {
+	id := len(mdb.names) + 1
+	mdb.names[id] = name
+	mdb.members[id] = []model.LedgerMember{{User: model.User{Id: uid}, Role: model.RoleOwner}}
+	return id, nil
+}
+
+func (mdb *MockDatabase) ListLedgers(ctx context.Context, uid int) ([]model.Ledger, error) {
+	var ls []model.Ledger
+	for id := 1; id <= len(mdb.names); id++ {
+		for _, m := range mdb.members[id] {
+			if m.User.Id == uid {
+				ls = append(ls, model.Ledger{Id: id, Name: mdb.names[id], Role: m.Role})
+			}
+		}
+	}
+	return ls, nil
+}
+
+func (mdb *MockDatabase) GetLedgerRole(ctx context.Context, lid int, uid int) (model.Role, bool, error) {
+	for _, m := range mdb.members[lid] {
+		if m.User.Id == uid {
+			return m.Role, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+func (mdb *MockDatabase) ListMembers(ctx context.Context, lid int) ([]model.LedgerMember, error) {
+	return mdb.members[lid], nil
+}
+
+func (mdb *MockDatabase) SetMemberRole(ctx context.Context, lid int, uid int, role model.Role) error {
+	for i, m := range mdb.members[lid] {
+		if m.User.Id == uid {
+			mdb.members[lid][i].Role = role
+		}
+	}
+	return nil
+}
+
+func (mdb *MockDatabase) DeleteMember(ctx context.Context, lid int, uid int) error {
+	var ms []model.LedgerMember
+	for _, m := range mdb.members[lid] {
+		if m.User.Id != uid {
+			ms = append(ms, m)
+		}
+	}
+	mdb.members[lid] = ms
+	return nil
+}
+
+func (mdb *MockDatabase) CreateInvitation(ctx context.Context, inv model.Invitation, hash string, by int, exp time.Time) (int, error) {
+	inv.Id = len(mdb.invs) + 1
+	inv.Token = ""
+	mdb.invs[hash] = &invitation{inv: inv, expires: exp}
+	return inv.Id, nil
+}
+
+func (mdb *MockDatabase) GetInvitation(ctx context.Context, hash string, now time.Time) (model.Invitation, bool, error) {
+	i, ok := mdb.invs[hash]
+	if !ok || i.accepted || !i.expires.After(now) {
+		return model.Invitation{}, false, nil
+	}
+	return i.inv, true, nil
+}
+
+func (mdb *MockDatabase) ListInvitations(ctx context.Context, lid int, now time.Time) ([]model.Invitation, error) {
+	var is []model.Invitation
+	for _, i := range mdb.invs {
+		if i.inv.LedgerId == lid && !i.accepted && i.expires.After(now) {
+			is = append(is, i.inv)
+		}
+	}
+	return is, nil
+}
+
+func (mdb *MockDatabase) AcceptInvitation(ctx context.Context, inv model.Invitation, uid int) error {
+	for _, i := range mdb.invs {
+		if i.inv.Id == inv.Id {
+			i.accepted = true
+		}
+	}
+	mdb.members[inv.LedgerId] = append(mdb.members[inv.LedgerId], model.LedgerMember{User: model.User{Id: uid}, Role: inv.Role})
+	return nil
+}
+
+func (mdb *MockDatabase) DeleteInvitation(ctx context.Context, lid int, id int) error {
+	for h, i := range mdb.invs {
+		if i.inv.Id == id && i.inv.LedgerId == lid {
+			delete(mdb.invs, h)
+		}
+	}
+	return nil
+}
+
+func userCtx(id int, email string) context.Context {
+	return auth.WithUser(context.Background(), model.User{Id: id, Email: email}, "token")
+}
+
+func TestAllows(t *testing.T) {
+	cases := []struct {
+		have model.Role
+		need model.Role
+		want bool
+	}{
+		{have: model.RoleOwner, need: model.RoleOwner, want: true},
+		{have: model.RoleOwner, need: model.RoleViewer, want: true},
+		{have: model.RoleEditor, need: model.RoleEditor, want: true},
+		{have: model.RoleEditor, need: model.RoleOwner, want: false},
+		{have: model.RoleViewer, need: model.RoleEditor, want: false},
+		{have: "", need: model.RoleViewer, want: false},
+	}
+	for _, c := range cases {
+		t.Run(string(c.have)+">="+string(c.need), func(t *testing.T) {
+			assert.Equal(t, c.want, Allows(c.have, c.need))
+		})
+	}
+}
+
+func TestSelect(t *testing.T) {
+	mock := newMock()
+	_, err := Select(context.Background(), 0, mock)
+	assert.ErrorIs(t, err, auth.ErrUnauthenticated)
+	ctx, err := Select(userCtx(1, "bob@example.com"), 0, mock)
+	if err != nil {
+		t.Fatalf("error running Select func, %v", err)
+	}
+	_, _, ok := FromContext(ctx)
+	assert.False(t, ok, "no ledger is made on the user's behalf")
+	assert.Empty(t, mock.names)
+	mock.CreateLedger(context.Background(), DefaultName, 1)
+	ctx, err = Select(userCtx(1, "bob@example.com"), 0, mock)
+	if err != nil {
+		t.Fatalf("error running Select func, %v", err)
+	}
+	lid, role, ok := FromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, model.RoleOwner, role)
+	assert.Equal(t, DefaultName, mock.names[lid])
+	ctx, err = Select(userCtx(1, "bob@example.com"), 0, mock)
+	if err != nil {
+		t.Fatalf("error running Select func, %v", err)
+	}
+	again, _, _ := FromContext(ctx)
+	assert.Equal(t, lid, again)
+	assert.Len(t, mock.names, 1)
+	_, err = Select(userCtx(2, "amy@example.com"), lid, mock)
+	assert.ErrorIs(t, err, ErrNotMember)
+}
+
+func TestInvitation(t *testing.T) {
+	mock := newMock()
+	mock.CreateLedger(context.Background(), DefaultName, 1)
+	owner, err := Select(userCtx(1, "bob@example.com"), 0, mock)
+	if err != nil {
+		t.Fatalf("error running Select func, %v", err)
+	}
+	inv, err := Invite(owner, "Amy@Example.com", model.RoleViewer, mock)
+	if err != nil {
+		t.Fatalf("error running Invite func, %v", err)
+	}
+	assert.Equal(t, "amy@example.com", inv.Email)
+	assert.NotEmpty(t, inv.Token)
+	_, err = Invite(owner, "not an email", model.RoleViewer, mock)
+	assert.Error(t, err)
+	_, err = AcceptInvitation(userCtx(3, "eve@example.com"), inv.Token, mock)
+	assert.ErrorIs(t, err, ErrInvitation)
+	_, err = AcceptInvitation(userCtx(2, "amy@example.com"), "bogus", mock)
+	assert.ErrorIs(t, err, ErrInvitation)
+	_, err = AcceptInvitation(auth.WithScope(userCtx(2, "amy@example.com"), model.TokenScopeRead), inv.Token, mock)
+	assert.ErrorIs(t, err, auth.ErrReadOnly)
+	actual, err := AcceptInvitation(userCtx(2, "amy@example.com"), inv.Token, mock)
+	if err != nil {
+		t.Fatalf("error running AcceptInvitation func, %v", err)
+	}
+	assert.Equal(t, model.Ledger{Id: 1, Name: DefaultName, Role: model.RoleViewer}, actual)
+	_, err = AcceptInvitation(userCtx(2, "amy@example.com"), inv.Token, mock)
+	assert.ErrorIs(t, err, ErrInvitation)
+	is, err := ListInvitations(owner, mock)
+	assert.Nil(t, err)
+	assert.Empty(t, is)
+}
+
+func TestMemberRoles(t *testing.T) {
+	mock := newMock()
+	mock.CreateLedger(context.Background(), DefaultName, 1)
+	owner, err := Select(userCtx(1, "bob@example.com"), 0, mock)
+	if err != nil {
+		t.Fatalf("error running Select func, %v", err)
+	}
+	mock.members[1] = append(mock.members[1], model.LedgerMember{User: model.User{Id: 2}, Role: model.RoleViewer})
+	_, err = SetMemberRole(owner, 1, model.RoleEditor, mock)
+	assert.ErrorIs(t, err, ErrLastOwner)
+	assert.ErrorIs(t, RemoveMember(owner, 1, mock), ErrLastOwner)
+	m, err := SetMemberRole(owner, 2, model.RoleOwner, mock)
+	if err != nil {
+		t.Fatalf("error running SetMemberRole func, %v", err)
+	}
+	assert.Equal(t, model.RoleOwner, m.Role)
+	assert.Nil(t, RemoveMember(owner, 1, mock))
+	ms, err := ListMembers(owner, mock)
+	assert.Nil(t, err)
+	assert.Equal(t, []*model.LedgerMember{{User: model.User{Id: 2}, Role: model.RoleOwner}}, ms)
+	assert.ErrorIs(t, RemoveMember(owner, 3, mock), ErrMemberNotFound)
+}
+
+func TestSelectScope(t *testing.T) {
+	mock := newMock()
+	mock.CreateLedger(context.Background(), DefaultName, 1)
+	cases := []struct {
+		scope model.TokenScope
+		want  model.Role
+	}{
+		{scope: model.TokenScopeRead, want: model.RoleViewer},
+		{scope: model.TokenScopeWrite, want: model.RoleEditor},
+		{scope: model.TokenScopeAdmin, want: model.RoleOwner},
+	}
+	for _, c := range cases {
+		t.Run(string(c.scope), func(t *testing.T) {
+			ctx, err := Select(auth.WithScope(userCtx(1, "bob@example.com"), c.scope), 0, mock)
+			if err != nil {
+				t.Fatalf("error running Select func, %v", err)
+			}
+			_, role, _ := FromContext(ctx)
+			assert.Equal(t, c.want, role)
+		})
+	}
+	mock.members[1] = append(mock.members[1], model.LedgerMember{User: model.User{Id: 2}, Role: model.RoleViewer})
+	ctx, err := Select(auth.WithScope(userCtx(2, "amy@example.com"), model.TokenScopeWrite), 1, mock)
+	if err != nil {
+		t.Fatalf("error running Select func, %v", err)
+	}
+	_, role, _ := FromContext(ctx)
+	assert.Equal(t, model.RoleViewer, role)
+
+	_, err = CreateLedger(auth.WithScope(userCtx(1, "bob@example.com"), model.TokenScopeRead), "scripts", mock)
+	assert.ErrorIs(t, err, auth.ErrReadOnly)
+	l, err := CreateLedger(auth.WithScope(userCtx(1, "bob@example.com"), model.TokenScopeWrite), "scripts", mock)
+	assert.Nil(t, err)
+	assert.Equal(t, "scripts", l.Name)
+}
@@ -0,0 +1,84 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+type ctxKey int
+
+const (
+	loggerKey ctxKey = iota
+	requestIdKey
+)
+
+// New returns a JSON logger writing records at or above the level, which is
+// one of config.LogLevels.
+func New(w io.Writer, level string) *slog.Logger {
+	var l slog.Level
+	switch strings.ToLower(level) {
+	case "debug":
+		l = slog.LevelDebug
+	case "warn":
+		l = slog.LevelWarn
+	case "error":
+		l = slog.LevelError
+	default:
+		l = slog.LevelInfo
+	}
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{Level: l}))
+}
+
+// WithLogger returns a copy of ctx carrying the logger.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// FromContext returns the context's logger, or the default logger when it
+// has none.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+// WithRequestId returns a copy of ctx carrying the request id, with its
+// logger tagging every record with it.
+func WithRequestId(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, requestIdKey, id)
+	return WithLogger(ctx, FromContext(ctx).With("request_id", id))
+}
+
+// RequestId returns the context's request id, if it has one.
+func RequestId(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey).(string)
+	return id
+}
+
+// NewRequestId returns a random request id.
+func NewRequestId() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// ValidRequestId reports whether a client supplied request id is safe to
+// reuse: short and made of letters, digits, dashes and underscores.
+func ValidRequestId(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithRequestId(t *testing.T) {
+	var buf bytes.Buffer
+	ctx := WithRequestId(WithLogger(context.Background(), New(&buf, "info")), "abc123")
+	assert.Equal(t, "abc123", RequestId(ctx))
+	FromContext(ctx).Info("hello", "n", 1)
+	FromContext(ctx).Debug("hidden")
+	var rec map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &rec); err != nil {
+		t.Fatalf("failed to decode log record, %v", err)
+	}
+	assert.Equal(t, "abc123", rec["request_id"])
+	assert.Equal(t, "hello", rec["msg"])
+	assert.NotContains(t, buf.String(), "hidden")
+}
+
+func TestFromContextDefault(t *testing.T) {
+	assert.Equal(t, slog.Default(), FromContext(context.Background()))
+	assert.Equal(t, "", RequestId(context.Background()))
+}
+
+func TestValidRequestId(t *testing.T) {
+	cases := []struct {
+		id   string
+		want bool
+	}{
+		{id: NewRequestId(), want: true},
+		{id: "req-42_A", want: true},
+		{id: "", want: false},
+		{id: "has space", want: false},
+		{id: "new\nline", want: false},
+		{id: strings.Repeat("a", 65), want: false},
+	}
+	for _, c := range cases {
+		t.Run(c.id, func(t *testing.T) {
+			assert.Equal(t, c.want, ValidRequestId(c.id))
+		})
+	}
+}
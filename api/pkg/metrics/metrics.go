@@ -0,0 +1,217 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/vapor05/financeview/pkg/logging"
+)
+
+const namespace = "financeview"
+
+// Registry holds every metric served on /metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status.",
+	}, []string{"method", "path", "status"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "path"})
+	operations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "graphql_operations_total",
+		Help:      "GraphQL operations by operation name and result.",
+	}, []string{"operation", "result"})
+	operationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "graphql_operation_duration_seconds",
+		Help:      "GraphQL operation latency by operation name.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation"})
+	resolverDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "graphql_resolver_duration_seconds",
+		Help:      "GraphQL field resolver latency by object and field.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"object", "field"})
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "sql_query_duration_seconds",
+		Help:      "SQL statement latency by the store method that ran it and result.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"statement", "result"})
+	expensesCreatedToday = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "expenses_created_today",
+		Help:      "Expenses created since midnight UTC, as of the last count.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		operations,
+		operationDuration,
+		resolverDuration,
+		queryDuration,
+		expensesCreatedToday,
+	)
+}
+
+// Handler serves the registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+func ObserveRequest(method string, path string, status int, d time.Duration) {
+	if path == "" {
+		path = "unmatched"
+	}
+	httpRequests.WithLabelValues(method, path, strconv.Itoa(status)).Inc()
+	httpDuration.WithLabelValues(method, path).Observe(d.Seconds())
+}
+
+func ObserveQuery(statement string, d time.Duration, err error) {
+	queryDuration.WithLabelValues(statement, result(err == nil)).Observe(d.Seconds())
+}
+
+func result(ok bool) string {
+	if ok {
+		return "ok"
+	}
+	return "error"
+}
+
+var operationName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+
+// OperationLabel turns a client chosen operation name into a label value,
+// folding missing and odd names together so they can't blow up the number
+// of series.
+func OperationLabel(name string) string {
+	switch {
+	case name == "":
+		return "anonymous"
+	case !operationName.MatchString(name):
+		return "invalid"
+	}
+	return name
+}
+
+// Operations is gqlgen response middleware counting and timing operations.
+func Operations(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	resp := next(ctx)
+	if !graphql.HasOperationContext(ctx) {
+		operations.WithLabelValues("invalid", "error").Inc()
+		return resp
+	}
+	oc := graphql.GetOperationContext(ctx)
+	op := OperationLabel(oc.OperationName)
+	operations.WithLabelValues(op, result(resp == nil || len(resp.Errors) == 0)).Inc()
+	operationDuration.WithLabelValues(op).Observe(time.Since(oc.Stats.OperationStart).Seconds())
+	return resp
+}
+
+// Resolvers is gqlgen field middleware timing fields that have a resolver,
+// leaving out plain struct fields.
+func Resolvers(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || !fc.IsResolver {
+		return next(ctx)
+	}
+	start := time.Now()
+	res, err := next(ctx)
+	resolverDuration.WithLabelValues(fc.Object, fc.Field.Name).Observe(time.Since(start).Seconds())
+	return res, err
+}
+
+// poolCollector reports pgx connection pool statistics at scrape time.
+type poolCollector struct {
+	stat     func() *pgxpool.Stat
+	acquired *prometheus.Desc
+	idle     *prometheus.Desc
+	total    *prometheus.Desc
+	max      *prometheus.Desc
+	acquires *prometheus.Desc
+	waited   *prometheus.Desc
+	empty    *prometheus.Desc
+}
+
+// NewPoolCollector reports the statistics of the pool stat returns, which
+// may return nil when there is no pool.
+func NewPoolCollector(stat func() *pgxpool.Stat) prometheus.Collector {
+	d := func(name string, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+	return &poolCollector{
+		stat:     stat,
+		acquired: d("acquired_conns", "Connections currently in use."),
+		idle:     d("idle_conns", "Connections currently idle."),
+		total:    d("total_conns", "Connections currently open."),
+		max:      d("max_conns", "Most connections the pool will open."),
+		acquires: d("acquires_total", "Connections acquired from the pool."),
+		waited:   d("acquire_duration_seconds_total", "Time spent waiting to acquire connections."),
+		empty:    d("empty_acquires_total", "Acquires that had to wait because the pool was empty."),
+	}
+}
+
+func (pc *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{pc.acquired, pc.idle, pc.total, pc.max, pc.acquires, pc.waited, pc.empty} {
+		ch <- d
+	}
+}
+
+func (pc *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := pc.stat()
+	if s == nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(pc.acquired, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(pc.idle, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(pc.total, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(pc.max, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(pc.acquires, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(pc.waited, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(pc.empty, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+}
+
+// CountExpensesCreatedToday sets the gauge of expenses created across every
+// ledger since midnight UTC from count every interval until the context is
+// done, so scrapes never wait on the database. A failed count leaves the
+// last one in place.
+func CountExpensesCreatedToday(ctx context.Context, every time.Duration, count func(context.Context, time.Time) (int, error)) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		cctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		n, err := count(cctx, time.Now().UTC().Truncate(24*time.Hour))
+		cancel()
+		if err != nil {
+			logging.FromContext(ctx).Error("failed to count expenses created today", "err", err)
+		} else {
+			expensesCreatedToday.Set(float64(n))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperationLabel(t *testing.T) {
+	cases := []struct {
+		name string
+		want string
+	}{
+		{name: "GetExpenses", want: "GetExpenses"},
+		{name: "", want: "anonymous"},
+		{name: "drop table", want: "invalid"},
+		{name: strings.Repeat("a", 65), want: "invalid"},
+	}
+	for _, c := range cases {
+		t.Run(c.want, func(t *testing.T) {
+			assert.Equal(t, c.want, OperationLabel(c.name))
+		})
+	}
+}
+
+func TestObserve(t *testing.T) {
+	ObserveRequest("POST", "/query", 200, time.Millisecond)
+	ObserveRequest("GET", "", 404, time.Millisecond)
+	assert.Equal(t, 1.0, testutil.ToFloat64(httpRequests.WithLabelValues("POST", "/query", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(httpRequests.WithLabelValues("GET", "unmatched", "404")))
+	ObserveQuery("CreateExpense", time.Millisecond, nil)
+	ObserveQuery("CreateExpense", time.Millisecond, errors.New("boom"))
+	assert.Equal(t, 2, testutil.CollectAndCount(queryDuration, namespace+"_sql_query_duration_seconds"))
+}
+
+func TestCountExpensesCreatedToday(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var since time.Time
+	CountExpensesCreatedToday(ctx, time.Hour, func(ctx context.Context, t time.Time) (int, error) {
+		since = t
+		return 7, nil
+	})
+	assert.Equal(t, 7.0, testutil.ToFloat64(expensesCreatedToday))
+	assert.Equal(t, time.Now().UTC().Truncate(24*time.Hour), since)
+	CountExpensesCreatedToday(ctx, time.Hour, func(ctx context.Context, t time.Time) (int, error) {
+		return 0, errors.New("boom")
+	})
+	assert.Equal(t, 7.0, testutil.ToFloat64(expensesCreatedToday), "a failed count keeps the last")
+}
+
+func TestHandler(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	assert.Nil(t, reg.Register(NewPoolCollector(func() *pgxpool.Stat { return nil })))
+	ObserveRequest("GET", "/healthz", 200, time.Millisecond)
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), namespace+"_http_requests_total")
+	assert.Contains(t, w.Body.String(), "go_goroutines")
+}
@@ -0,0 +1,177 @@
+package payee
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/expense"
+)
+
+var (
+	ErrNotFound      = errors.New("payee not found")
+	ErrAliasNotFound = errors.New("payee alias not found")
+)
+
+type Database interface {
+	CreatePayee(context.Context, string) (int, error)
+	GetPayeeId(context.Context, string) (int, bool, error)
+	GetPayee(context.Context, int) (model.Payee, bool, error)
+	ListPayees(context.Context) ([]model.Payee, error)
+	RenamePayee(context.Context, int, string) error
+	DeletePayee(context.Context, int) error
+	CreatePayeeAlias(context.Context, int, string) (int, error)
+	DeletePayeeAlias(context.Context, int) error
+	AssignDescriptionPayee(context.Context, int, string) (int, error)
+	MergePayees(context.Context, int, int) error
+}
+
+func SavePayee(ctx context.Context, np model.NewPayee, db Database) (model.Payee, error) {
+	name := strings.TrimSpace(np.Name)
+	if name == "" {
+		return model.Payee{}, expense.ValidationErrors{{Field: "name", Message: "must not be empty"}}
+	}
+	_, ok, err := db.GetPayeeId(ctx, name)
+	if err != nil {
+		return model.Payee{}, fmt.Errorf("failed to check for existing payee, %w", err)
+	}
+	if ok {
+		return model.Payee{}, expense.ValidationErrors{{Field: "name", Message: fmt.Sprintf("payee %q already exists", name)}}
+	}
+	pid, err := db.CreatePayee(ctx, name)
+	if err != nil {
+		return model.Payee{}, fmt.Errorf("failed to create new payee, %w", err)
+	}
+	for _, a := range np.Aliases {
+		if err := addAlias(ctx, pid, a, db); err != nil {
+			return model.Payee{}, err
+		}
+	}
+	return getPayee(ctx, pid, db)
+}
+
+func ListPayees(ctx context.Context, db Database) ([]*model.Payee, error) {
+	ps, err := db.ListPayees(ctx)
+	if err != nil {
+		return []*model.Payee{}, fmt.Errorf("failed to list payees, %w", err)
+	}
+	pay := []*model.Payee{}
+	for i := range ps {
+		pay = append(pay, &ps[i])
+	}
+	return pay, nil
+}
+
+// GetPayee returns nil if no payee has the id.
+func GetPayee(ctx context.Context, id int, db Database) (*model.Payee, error) {
+	p, ok, err := db.GetPayee(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payee id=%v, %w", id, err)
+	}
+	if !ok {
+		return nil, nil
+	}
+	return &p, nil
+}
+
+func RenamePayee(ctx context.Context, id int, name string, db Database) (model.Payee, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return model.Payee{}, expense.ValidationErrors{{Field: "name", Message: "must not be empty"}}
+	}
+	eid, ok, err := db.GetPayeeId(ctx, name)
+	if err != nil {
+		return model.Payee{}, fmt.Errorf("failed to check for existing payee, %w", err)
+	}
+	if ok && eid != id {
+		return model.Payee{}, expense.ValidationErrors{{Field: "name", Message: fmt.Sprintf("payee %q already exists", name)}}
+	}
+	if err := db.RenamePayee(ctx, id, name); err != nil {
+		return model.Payee{}, fmt.Errorf("failed to rename payee, %w", err)
+	}
+	return getPayee(ctx, id, db)
+}
+
+func DeletePayee(ctx context.Context, id int, db Database) error {
+	if err := db.DeletePayee(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete payee, %w", err)
+	}
+	return nil
+}
+
+// AddAlias adds an alias pattern to a payee and links every description
+// without a payee that matches it.
+func AddAlias(ctx context.Context, pid int, pattern string, db Database) (model.Payee, error) {
+	if _, err := getPayee(ctx, pid, db); err != nil {
+		return model.Payee{}, err
+	}
+	if err := addAlias(ctx, pid, pattern, db); err != nil {
+		return model.Payee{}, err
+	}
+	return getPayee(ctx, pid, db)
+}
+
+func RemoveAlias(ctx context.Context, pid int, aid int, db Database) (model.Payee, error) {
+	p, err := getPayee(ctx, pid, db)
+	if err != nil {
+		return model.Payee{}, err
+	}
+	found := false
+	for _, a := range p.Aliases {
+		if a.Id == aid {
+			found = true
+		}
+	}
+	if !found {
+		return model.Payee{}, ErrAliasNotFound
+	}
+	if err := db.DeletePayeeAlias(ctx, aid); err != nil {
+		return model.Payee{}, fmt.Errorf("failed to remove payee alias, %w", err)
+	}
+	return getPayee(ctx, pid, db)
+}
+
+// MergePayees folds the source payee into the target, which keeps its name
+// and gains the source's aliases and descriptions.
+func MergePayees(ctx context.Context, src int, dst int, db Database) (model.Payee, error) {
+	if src == dst {
+		return model.Payee{}, expense.ValidationErrors{{Field: "targetId", Message: "must not be the payee merged into it"}}
+	}
+	if _, err := getPayee(ctx, src, db); err != nil {
+		return model.Payee{}, err
+	}
+	if _, err := getPayee(ctx, dst, db); err != nil {
+		return model.Payee{}, err
+	}
+	if err := db.MergePayees(ctx, src, dst); err != nil {
+		return model.Payee{}, fmt.Errorf("failed to merge payees, %w", err)
+	}
+	return getPayee(ctx, dst, db)
+}
+
+func addAlias(ctx context.Context, pid int, pattern string, db Database) error {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return expense.ValidationErrors{{Field: "pattern", Message: "must not be empty"}}
+	}
+	if _, err := db.CreatePayeeAlias(ctx, pid, pattern); err != nil {
+		return fmt.Errorf("failed to create payee alias, %w", err)
+	}
+	if _, err := db.AssignDescriptionPayee(ctx, pid, pattern); err != nil {
+		return fmt.Errorf("failed to link descriptions to payee alias, %w", err)
+	}
+	return nil
+}
+
+func getPayee(ctx context.Context, id int, db Database) (model.Payee, error) {
+	p, ok, err := db.GetPayee(ctx, id)
+	if err != nil {
+		return model.Payee{}, fmt.Errorf("failed to get payee id=%v, %w", id, err)
+	}
+	if !ok {
+		return model.Payee{}, ErrNotFound
+	}
+	return p, nil
+}
@@ -0,0 +1,276 @@
+package payee
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/expense"
+)
+
+type MockDatabase struct {
+	payee map[int]string
+	alias map[int]struct {
+		Pid     int
+		Pattern string
+	}
+	desc map[int]struct {
+		Description string
+		Pid         int
+	}
+}
+
+func newMock() *MockDatabase {
+	return &MockDatabase{
+		payee: make(map[int]string),
+		alias: make(map[int]struct {
+			Pid     int
+			Pattern string
+		}),
+		desc: make(map[int]struct {
+			Description string
+			Pid         int
+		}),
+	}
+}
+
+func (mdb *MockDatabase) CreatePayee(ctx context.Context, name string) (int, error) {
+	id := rand.Int()
+	mdb.payee[id] = name
+	return id, nil
+}
+
+func (mdb *MockDatabase) GetPayeeId(ctx context.Context, name string) (int, bool, error) {
+	for k, v := range mdb.payee {
+		if v == name {
+			return k, true, nil
+		}
+	}
+	return 0, false, nil
+}
+
+func (mdb *MockDatabase) GetPayee(ctx context.Context, id int) (model.Payee, bool, error) {
+	name, ok := mdb.payee[id]
+	if !ok {
+		return model.Payee{}, false, nil
+	}
+	p := model.Payee{Id: id, Name: name}
+	for aid, a := range mdb.alias {
+		if a.Pid == id {
+			p.Aliases = append(p.Aliases, model.PayeeAlias{Id: aid, Pattern: a.Pattern})
+		}
+	}
+	sort.Slice(p.Aliases, func(i, j int) bool {
+		return p.Aliases[i].Id < p.Aliases[j].Id
+	})
+	return p, true, nil
+}
+
+func (mdb *MockDatabase) ListPayees(ctx context.Context) ([]model.Payee, error) {
+	var ps []model.Payee
+	for id := range mdb.payee {
+		p, _, _ := mdb.GetPayee(ctx, id)
+		ps = append(ps, p)
+	}
+	sort.Slice(ps, func(i, j int) bool {
+		return ps[i].Name < ps[j].Name
+	})
+	return ps, nil
+}
+
+func (mdb *MockDatabase) RenamePayee(ctx context.Context, id int, name string) error {
+	if _, ok := mdb.payee[id]; !ok {
+		return fmt.Errorf("payee id=%v does not exist", id)
+	}
+	mdb.payee[id] = name
+	return nil
+}
+
+func (mdb *MockDatabase) DeletePayee(ctx context.Context, id int) error {
+	if _, ok := mdb.payee[id]; !ok {
+		return fmt.Errorf("payee id=%v does not exist", id)
+	}
+	for did, d := range mdb.desc {
+		if d.Pid == id {
+			d.Pid = 0
+			mdb.desc[did] = d
+		}
+	}
+	for aid, a := range mdb.alias {
+		if a.Pid == id {
+			delete(mdb.alias, aid)
+		}
+	}
+	delete(mdb.payee, id)
+	return nil
+}
+
+func (mdb *MockDatabase) CreatePayeeAlias(ctx context.Context, pid int, pattern string) (int, error) {
+	id := rand.Int()
+	mdb.alias[id] = struct {
+		Pid     int
+		Pattern string
+	}{pid, pattern}
+	return id, nil
+}
+
+func (mdb *MockDatabase) DeletePayeeAlias(ctx context.Context, id int) error {
+	delete(mdb.alias, id)
+	return nil
+}
+
+// AssignDescriptionPayee approximates ILIKE by translating % and _ into a
+// case-insensitive regular expression.
+func (mdb *MockDatabase) AssignDescriptionPayee(ctx context.Context, pid int, pattern string) (int, error) {
+	re := "(?i)^" + strings.NewReplacer("%", ".*", "_", ".").Replace(regexp.QuoteMeta(pattern)) + "$"
+	n := 0
+	for did, d := range mdb.desc {
+		if d.Pid == 0 && regexp.MustCompile(re).MatchString(d.Description) {
+			d.Pid = pid
+			mdb.desc[did] = d
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (mdb *MockDatabase) MergePayees(ctx context.Context, src int, dst int) error {
+	for did, d := range mdb.desc {
+		if d.Pid == src {
+			d.Pid = dst
+			mdb.desc[did] = d
+		}
+	}
+	for aid, a := range mdb.alias {
+		if a.Pid == src {
+			a.Pid = dst
+			mdb.alias[aid] = a
+		}
+	}
+	delete(mdb.payee, src)
+	return nil
+}
+
+func TestSavePayee(t *testing.T) {
+	t.Run("new payee with aliases", func(t *testing.T) {
+		mock := newMock()
+		mock.desc[1] = struct {
+			Description string
+			Pid         int
+		}{"AMZN Mktp US*2K3", 0}
+		mock.desc[2] = struct {
+			Description string
+			Pid         int
+		}{"Trader Joe's", 0}
+		input := model.NewPayee{Name: " Amazon ", Aliases: []string{"amzn mktp%", "Amazon.com%"}}
+		actual, err := SavePayee(context.Background(), input, mock)
+		if err != nil {
+			t.Fatalf("error running SavePayee func, %v", err)
+		}
+		assert.Equal(t, "Amazon", actual.Name)
+		assert.Len(t, actual.Aliases, 2)
+		assert.Equal(t, actual.Id, mock.desc[1].Pid)
+		assert.Equal(t, 0, mock.desc[2].Pid)
+	})
+	t.Run("duplicate name", func(t *testing.T) {
+		mock := newMock()
+		mock.payee[3] = "Amazon"
+		_, err := SavePayee(context.Background(), model.NewPayee{Name: "Amazon"}, mock)
+		assert.Error(t, err)
+	})
+	t.Run("empty name", func(t *testing.T) {
+		_, err := SavePayee(context.Background(), model.NewPayee{Name: "  "}, newMock())
+		assert.Error(t, err)
+	})
+	t.Run("empty alias", func(t *testing.T) {
+		_, err := SavePayee(context.Background(), model.NewPayee{Name: "Amazon", Aliases: []string{""}}, newMock())
+		assert.Error(t, err)
+	})
+}
+
+func TestRenamePayee(t *testing.T) {
+	mock := newMock()
+	mock.payee[1] = "Amzn"
+	mock.payee[2] = "Target"
+	actual, err := RenamePayee(context.Background(), 1, "Amazon", mock)
+	if err != nil {
+		t.Fatalf("error running RenamePayee func, %v", err)
+	}
+	assert.Equal(t, model.Payee{Id: 1, Name: "Amazon"}, actual)
+	_, err = RenamePayee(context.Background(), 1, "Target", mock)
+	assert.Error(t, err)
+}
+
+func TestRemoveAlias(t *testing.T) {
+	mock := newMock()
+	mock.payee[1] = "Amazon"
+	mock.payee[2] = "Target"
+	mock.alias[10] = struct {
+		Pid     int
+		Pattern string
+	}{1, "amzn%"}
+	_, err := RemoveAlias(context.Background(), 2, 10, mock)
+	assert.ErrorIs(t, err, ErrAliasNotFound)
+	actual, err := RemoveAlias(context.Background(), 1, 10, mock)
+	if err != nil {
+		t.Fatalf("error running RemoveAlias func, %v", err)
+	}
+	assert.Equal(t, model.Payee{Id: 1, Name: "Amazon"}, actual)
+}
+
+func TestMergePayees(t *testing.T) {
+	mock := newMock()
+	mock.payee[1] = "Amazon"
+	mock.payee[2] = "Amazon Marketplace"
+	mock.alias[10] = struct {
+		Pid     int
+		Pattern string
+	}{2, "amzn mktp%"}
+	mock.desc[5] = struct {
+		Description string
+		Pid         int
+	}{"AMZN Mktp US*2K3", 2}
+	actual, err := MergePayees(context.Background(), 2, 1, mock)
+	if err != nil {
+		t.Fatalf("error running MergePayees func, %v", err)
+	}
+	want := model.Payee{
+		Id:      1,
+		Name:    "Amazon",
+		Aliases: []model.PayeeAlias{{Id: 10, Pattern: "amzn mktp%"}},
+	}
+	assert.Equal(t, want, actual)
+	assert.Equal(t, 1, mock.desc[5].Pid)
+	_, ok := mock.payee[2]
+	assert.False(t, ok)
+	t.Run("into itself", func(t *testing.T) {
+		_, err := MergePayees(context.Background(), 1, 1, mock)
+		var ves expense.ValidationErrors
+		if assert.ErrorAs(t, err, &ves) {
+			assert.Equal(t, "targetId", ves[0].Field)
+		}
+	})
+	t.Run("missing payee", func(t *testing.T) {
+		_, err := MergePayees(context.Background(), 99, 1, mock)
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
+
+func TestGetPayee(t *testing.T) {
+	mock := newMock()
+	mock.payee[1] = "Amazon"
+	actual, err := GetPayee(context.Background(), 1, mock)
+	if err != nil {
+		t.Fatalf("error running GetPayee func, %v", err)
+	}
+	assert.Equal(t, &model.Payee{Id: 1, Name: "Amazon"}, actual)
+	actual, err = GetPayee(context.Background(), 2, mock)
+	assert.Nil(t, err)
+	assert.Nil(t, actual)
+}
@@ -0,0 +1,259 @@
+package ratelimit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/vapor05/financeview/pkg/auth"
+	"github.com/vapor05/financeview/pkg/gqlguard"
+	"github.com/vapor05/financeview/pkg/logging"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+const errRateLimited = "RATE_LIMITED"
+
+// Limit is a token bucket holding up to Burst tokens, refilled at Rate
+// tokens a second.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// PerMinute returns the limit letting n operations through a minute with
+// bursts of up to burst.
+func PerMinute(n int, burst int) Limit {
+	return Limit{Rate: float64(n) / 60, Burst: burst}
+}
+
+// Backend keeps the token buckets. Take removes a token from the bucket
+// named by key, reporting how long until one is free when it is empty.
+type Backend interface {
+	Take(ctx context.Context, key string, l Limit, now time.Time) (bool, time.Duration, error)
+}
+
+// Memory is a Backend keeping buckets in process, so each server instance
+// limits on its own.
+type Memory struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+func NewMemory() *Memory {
+	return &Memory{buckets: map[string]*bucket{}}
+}
+
+func (m *Memory) Take(ctx context.Context, key string, l Limit, now time.Time) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(l.Burst), last: now}
+		m.buckets[key] = b
+	}
+	b.limit = l
+	b.refill(now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+	wait := time.Duration((1 - b.tokens) / l.Rate * float64(time.Second))
+	return false, wait, nil
+}
+
+// sweep drops the buckets that have filled back up, at most once a minute,
+// so clients that went away don't keep theirs forever.
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.swept) < time.Minute {
+		return
+	}
+	m.swept = now
+	for k, b := range m.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Burst) {
+			delete(m.buckets, k)
+		}
+	}
+}
+
+func (b *bucket) refill(now time.Time) {
+	if now.After(b.last) {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate)
+		b.last = now
+	}
+}
+
+// Limiter limits GraphQL queries and mutations separately. A limit with no
+// rate lets everything through. Persisted queries sent by hash alone are
+// looked up in Allowlist to tell what they run.
+type Limiter struct {
+	Backend   Backend
+	Queries   Limit
+	Mutations Limit
+	Allowlist gqlguard.Allowlist
+}
+
+// Allow takes a token for an operation of the given type by the client
+// named by key. Subscriptions count as queries.
+func (l Limiter) Allow(ctx context.Context, key string, op ast.Operation) (bool, time.Duration, error) {
+	lim, kind := l.Queries, "query"
+	if op == ast.Mutation {
+		lim, kind = l.Mutations, "mutation"
+	}
+	if lim.Rate <= 0 {
+		return true, 0, nil
+	}
+	ok, wait, err := l.Backend.Take(ctx, kind+":"+key, lim, time.Now())
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to take rate limit token, %w", err)
+	}
+	return ok, wait, nil
+}
+
+// Key names who a request is limited as: its user for session logins, its
+// token for API tokens, and otherwise the client's IP.
+func Key(ctx context.Context, clientIP string) string {
+	u, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return "ip:" + clientIP
+	}
+	if tok, ok := auth.TokenFromContext(ctx); ok && auth.IsAPIToken(tok) {
+		return "token:" + auth.HashToken(tok)
+	}
+	return fmt.Sprintf("user:%d", u.Id)
+}
+
+// MaxBodySize is the largest GraphQL request body read, in bytes. Uploads
+// are left for the handler to limit.
+const MaxBodySize = 1 << 20
+
+// Operation reads the type of the GraphQL operation a request runs,
+// leaving the body for the handler to read again. Websocket upgrades are
+// charged as subscriptions, with each operation sent over the socket
+// charged again by Websocket, and uploads are always mutations. Persisted queries sent by
+// hash alone are looked up in the allowlist. Requests it can't tell, such as
+// hashes of queries outside the allowlist, count as mutations so they fall
+// under the tighter limit. Bodies over MaxBodySize fail with an
+// *http.MaxBytesError.
+func Operation(w http.ResponseWriter, r *http.Request, allow gqlguard.Allowlist) (ast.Operation, error) {
+	if r.Header.Get("Upgrade") != "" {
+		return ast.Subscription, nil
+	}
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "multipart/form-data" {
+		return ast.Mutation, nil
+	}
+	var params struct {
+		Query         string `json:"query"`
+		OperationName string `json:"operationName"`
+		Extensions    struct {
+			PersistedQuery struct {
+				Sha256Hash string `json:"sha256Hash"`
+			} `json:"persistedQuery"`
+		} `json:"extensions"`
+	}
+	if r.Method == http.MethodGet {
+		params.Query = r.URL.Query().Get("query")
+		params.OperationName = r.URL.Query().Get("operationName")
+		if ext := r.URL.Query().Get("extensions"); ext != "" {
+			json.Unmarshal([]byte(ext), &params.Extensions)
+		}
+	} else {
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxBodySize))
+		if err != nil {
+			return "", fmt.Errorf("failed to read request body, %w", err)
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		if err := json.Unmarshal(body, &params); err != nil {
+			return ast.Mutation, nil
+		}
+	}
+	if params.Query == "" {
+		params.Query = allow[params.Extensions.PersistedQuery.Sha256Hash]
+	}
+	if params.Query == "" {
+		return ast.Mutation, nil
+	}
+	doc, gerr := parser.ParseQuery(&ast.Source{Input: params.Query})
+	if gerr != nil {
+		return ast.Mutation, nil
+	}
+	var op *ast.OperationDefinition
+	if params.OperationName == "" && len(doc.Operations) == 1 {
+		op = doc.Operations[0]
+	} else {
+		op = doc.Operations.ForName(params.OperationName)
+	}
+	if op == nil {
+		return ast.Mutation, nil
+	}
+	return op.Operation, nil
+}
+
+type websocketKey struct{}
+
+// WithWebsocket marks ctx as belonging to a websocket upgraded from the
+// client at clientIP, so Websocket charges the operations sent over it.
+func WithWebsocket(ctx context.Context, clientIP string) context.Context {
+	return context.WithValue(ctx, websocketKey{}, clientIP)
+}
+
+// Websocket charges each operation sent over a websocket to the Limiter,
+// by its parsed type, since the upgrade is only charged once however many
+// queries and mutations the socket then runs. Operations on other
+// transports are left to the HTTP middleware. If the limiter's backend
+// fails operations are let through.
+type Websocket struct {
+	Limiter Limiter
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = Websocket{}
+
+func (Websocket) ExtensionName() string {
+	return "WebsocketRateLimit"
+}
+
+func (Websocket) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (w Websocket) MutateOperationContext(ctx context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	ip, ok := ctx.Value(websocketKey{}).(string)
+	if !ok || rc.Operation == nil {
+		return nil
+	}
+	ok, wait, err := w.Limiter.Allow(ctx, Key(ctx, ip), rc.Operation.Operation)
+	if err != nil {
+		logging.FromContext(ctx).Error("failed to check rate limit", "err", err)
+		return nil
+	}
+	if !ok {
+		gerr := gqlerror.Errorf("rate limit exceeded, retry later")
+		errcode.Set(gerr, errRateLimited)
+		gerr.Extensions["retryAfter"] = int(math.Ceil(wait.Seconds()))
+		return gerr
+	}
+	return nil
+}
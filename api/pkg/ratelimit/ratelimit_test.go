@@ -0,0 +1,154 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/auth"
+	"github.com/vapor05/financeview/pkg/gqlguard"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+func TestMemory(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemory()
+	l := Limit{Rate: 1, Burst: 2}
+	now := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		ok, _, err := m.Take(ctx, "a", l, now)
+		assert.Nil(t, err)
+		assert.True(t, ok)
+	}
+	ok, wait, err := m.Take(ctx, "a", l, now)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, time.Second, wait)
+
+	ok, _, _ = m.Take(ctx, "b", l, now)
+	assert.True(t, ok, "buckets are per key")
+
+	ok, wait, _ = m.Take(ctx, "a", l, now.Add(500*time.Millisecond))
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, wait)
+	ok, _, _ = m.Take(ctx, "a", l, now.Add(time.Second))
+	assert.True(t, ok)
+
+	m.Take(ctx, "c", l, now.Add(2*time.Minute))
+	assert.Len(t, m.buckets, 1, "full buckets are swept")
+}
+
+type failingBackend struct{}
+
+func (failingBackend) Take(context.Context, string, Limit, time.Time) (bool, time.Duration, error) {
+	return false, 0, errors.New("boom")
+}
+
+func TestLimiter(t *testing.T) {
+	ctx := context.Background()
+	l := Limiter{Backend: NewMemory(), Queries: Limit{Rate: 1, Burst: 1}, Mutations: Limit{Rate: 1, Burst: 1}}
+	ok, _, _ := l.Allow(ctx, "ip:1.2.3.4", ast.Query)
+	assert.True(t, ok)
+	ok, _, _ = l.Allow(ctx, "ip:1.2.3.4", ast.Mutation)
+	assert.True(t, ok, "mutations have their own bucket")
+	ok, _, _ = l.Allow(ctx, "ip:1.2.3.4", ast.Subscription)
+	assert.False(t, ok, "subscriptions count as queries")
+
+	l.Queries = Limit{}
+	ok, _, _ = l.Allow(ctx, "ip:1.2.3.4", ast.Query)
+	assert.True(t, ok, "no rate is no limit")
+
+	l.Backend = failingBackend{}
+	_, _, err := l.Allow(ctx, "ip:1.2.3.4", ast.Mutation)
+	assert.NotNil(t, err)
+}
+
+func TestWebsocket(t *testing.T) {
+	ctx := context.Background()
+	w := Websocket{Limiter: Limiter{Backend: NewMemory(), Queries: Limit{Rate: 1, Burst: 1}, Mutations: Limit{Rate: 1, Burst: 1}}}
+	doc, gerr := parser.ParseQuery(&ast.Source{Input: `mutation { deleteExpense(id: 1) { id } }`})
+	assert.Nil(t, gerr)
+	rc := &graphql.OperationContext{Doc: doc, Operation: doc.Operations[0]}
+	assert.Nil(t, w.MutateOperationContext(ctx, rc))
+	assert.Nil(t, w.MutateOperationContext(ctx, rc), "operations off a websocket are left to the middleware")
+
+	ctx = WithWebsocket(ctx, "1.2.3.4")
+	assert.Nil(t, w.MutateOperationContext(ctx, rc))
+	gerr = w.MutateOperationContext(ctx, rc)
+	assert.NotNil(t, gerr, "each mutation over the socket is charged")
+	assert.Equal(t, errRateLimited, gerr.Extensions["code"])
+	assert.Equal(t, 1, gerr.Extensions["retryAfter"])
+}
+
+func TestKey(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, "ip:1.2.3.4", Key(ctx, "1.2.3.4"))
+	u := model.User{Id: 7}
+	assert.Equal(t, "user:7", Key(auth.WithUser(ctx, u, "session"), "1.2.3.4"))
+	tok := auth.APITokenPrefix + "secret"
+	assert.Equal(t, "token:"+auth.HashToken(tok), Key(auth.WithUser(ctx, u, tok), "1.2.3.4"))
+}
+
+func TestOperation(t *testing.T) {
+	persisted := `{ ledgers { id } }`
+	allow := gqlguard.Allowlist{gqlguard.Hash(persisted): persisted}
+	cases := []struct {
+		name        string
+		contentType string
+		body        string
+		want        ast.Operation
+	}{
+		{name: "query", body: `{"query": "{ ledgers { id } }"}`, want: ast.Query},
+		{name: "mutation", body: `{"query": "mutation { logout }"}`, want: ast.Mutation},
+		{name: "named", body: `{"query": "query A { ledgers { id } } mutation B { logout }", "operationName": "A"}`, want: ast.Query},
+		{name: "missing name", body: `{"query": "query A { ledgers { id } } mutation B { logout }"}`, want: ast.Mutation},
+		{name: "allowlisted hash", body: `{"extensions": {"persistedQuery": {"version": 1, "sha256Hash": "` + gqlguard.Hash(persisted) + `"}}}`, want: ast.Query},
+		{name: "unknown hash", body: `{"extensions": {"persistedQuery": {"version": 1, "sha256Hash": "abc"}}}`, want: ast.Mutation},
+		{name: "not json", body: `query`, want: ast.Mutation},
+		{name: "bad query", body: `{"query": "{ ledgers"}`, want: ast.Mutation},
+		{name: "upload", contentType: "multipart/form-data; boundary=x", body: `--x--`, want: ast.Mutation},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/query", strings.NewReader(c.body))
+			if c.contentType != "" {
+				r.Header.Set("Content-Type", c.contentType)
+			}
+			op, err := Operation(httptest.NewRecorder(), r, allow)
+			assert.Nil(t, err)
+			assert.Equal(t, c.want, op)
+			body, _ := io.ReadAll(r.Body)
+			assert.Equal(t, c.body, string(body))
+		})
+	}
+
+	r := httptest.NewRequest("GET", "/query?query=%7B+ledgers+%7B+id+%7D+%7D", nil)
+	op, err := Operation(httptest.NewRecorder(), r, allow)
+	assert.Nil(t, err)
+	assert.Equal(t, ast.Query, op)
+	r = httptest.NewRequest("GET", "/query", nil)
+	r.Header.Set("Upgrade", "websocket")
+	op, err = Operation(httptest.NewRecorder(), r, allow)
+	assert.Nil(t, err)
+	assert.Equal(t, ast.Subscription, op)
+	ext := url.QueryEscape(`{"persistedQuery": {"version": 1, "sha256Hash": "` + gqlguard.Hash(persisted) + `"}}`)
+	r = httptest.NewRequest("GET", "/query?extensions="+ext, nil)
+	op, err = Operation(httptest.NewRecorder(), r, allow)
+	assert.Nil(t, err)
+	assert.Equal(t, ast.Query, op)
+
+	r = httptest.NewRequest("POST", "/query", strings.NewReader(`{"query": "`+strings.Repeat(" ", MaxBodySize)+`{ ledgers { id } }"}`))
+	_, err = Operation(httptest.NewRecorder(), r, allow)
+	var tooLarge *http.MaxBytesError
+	assert.ErrorAs(t, err, &tooLarge)
+}
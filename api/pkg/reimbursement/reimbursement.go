@@ -0,0 +1,95 @@
+package reimbursement
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/expense"
+)
+
+var ErrIncomeNotFound = errors.New("income not found")
+
+type Database interface {
+	GetExpense(context.Context, int) (model.Expense, bool, error)
+	GetIncome(context.Context, int) (model.Income, bool, error)
+	FindExpenses(context.Context, model.ExpenseFilter) ([]model.Expense, error)
+	SetReimbursementStatus(context.Context, int, model.ReimbursementStatus) error
+	LinkReimbursement(context.Context, int, int) error
+}
+
+// SetStatus moves a reimbursable expense between pending and submitted.
+// Expenses only become reimbursed through Reimburse, which links the income.
+func SetStatus(ctx context.Context, eid int, rs model.ReimbursementStatus, db Database) (model.Expense, error) {
+	if rs == model.ReimbursementStatusReimbursed {
+		return model.Expense{}, expense.ValidationErrors{{Field: "status", Message: "is set by linking the income that paid the expense back"}}
+	}
+	if _, err := reimbursableExpense(ctx, eid, db); err != nil {
+		return model.Expense{}, err
+	}
+	if err := db.SetReimbursementStatus(ctx, eid, rs); err != nil {
+		return model.Expense{}, fmt.Errorf("failed to set reimbursement status, %w", err)
+	}
+	return getExpense(ctx, eid, db)
+}
+
+// Reimburse links the income entry that paid back an expense, marking the
+// expense reimbursed.
+func Reimburse(ctx context.Context, eid int, iid int, db Database) (model.Expense, error) {
+	if _, err := reimbursableExpense(ctx, eid, db); err != nil {
+		return model.Expense{}, err
+	}
+	_, ok, err := db.GetIncome(ctx, iid)
+	if err != nil {
+		return model.Expense{}, fmt.Errorf("failed to get income id=%v, %w", iid, err)
+	}
+	if !ok {
+		return model.Expense{}, ErrIncomeNotFound
+	}
+	if err := db.LinkReimbursement(ctx, eid, iid); err != nil {
+		return model.Expense{}, fmt.Errorf("failed to link reimbursement, %w", err)
+	}
+	return getExpense(ctx, eid, db)
+}
+
+// Outstanding lists reimbursable expenses that have not been paid back.
+func Outstanding(ctx context.Context, db Database) ([]*model.Expense, error) {
+	f := model.ExpenseFilter{
+		ReimbursementStatus: []model.ReimbursementStatus{
+			model.ReimbursementStatusPending,
+			model.ReimbursementStatusSubmitted,
+		},
+	}
+	ex, err := db.FindExpenses(ctx, f)
+	if err != nil {
+		return []*model.Expense{}, fmt.Errorf("failed to find outstanding reimbursements, %w", err)
+	}
+	exp := []*model.Expense{}
+	for i := range ex {
+		exp = append(exp, &ex[i])
+	}
+	return exp, nil
+}
+
+func reimbursableExpense(ctx context.Context, eid int, db Database) (model.Expense, error) {
+	e, err := getExpense(ctx, eid, db)
+	if err != nil {
+		return model.Expense{}, err
+	}
+	if !e.Reimbursable {
+		return model.Expense{}, expense.ValidationErrors{{Field: "expenseId", Message: fmt.Sprintf("expense id=%v is not reimbursable", eid)}}
+	}
+	return e, nil
+}
+
+func getExpense(ctx context.Context, eid int, db Database) (model.Expense, error) {
+	e, ok, err := db.GetExpense(ctx, eid)
+	if err != nil {
+		return model.Expense{}, fmt.Errorf("failed to get expense id=%v, %w", eid, err)
+	}
+	if !ok {
+		return model.Expense{}, &expense.NotFoundError{Id: eid}
+	}
+	return e, nil
+}
@@ -0,0 +1,125 @@
+package reimbursement
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/expense"
+)
+
+type MockDatabase struct {
+	exp map[int]model.Expense
+	inc map[int]model.Income
+}
+
+func (mdb *MockDatabase) GetExpense(ctx context.Context, id int) (model.Expense, bool, error) {
+	e, ok := mdb.exp[id]
+	return e, ok, nil
+}
+
+func (mdb *MockDatabase) GetIncome(ctx context.Context, id int) (model.Income, bool, error) {
+	i, ok := mdb.inc[id]
+	return i, ok, nil
+}
+
+func (mdb *MockDatabase) FindExpenses(ctx context.Context, f model.ExpenseFilter) ([]model.Expense, error) {
+	var exps []model.Expense
+	for _, e := range mdb.exp {
+		for _, rs := range f.ReimbursementStatus {
+			if e.Reimbursable && e.ReimbursementStatus != nil && *e.ReimbursementStatus == rs {
+				exps = append(exps, e)
+			}
+		}
+	}
+	sort.Slice(exps, func(i, j int) bool {
+		return exps[i].Id < exps[j].Id
+	})
+	return exps, nil
+}
+
+func (mdb *MockDatabase) SetReimbursementStatus(ctx context.Context, eid int, rs model.ReimbursementStatus) error {
+	e := mdb.exp[eid]
+	e.ReimbursementStatus = &rs
+	e.ReimbursementIncomeId = 0
+	mdb.exp[eid] = e
+	return nil
+}
+
+func (mdb *MockDatabase) LinkReimbursement(ctx context.Context, eid int, iid int) error {
+	e := mdb.exp[eid]
+	rs := model.ReimbursementStatusReimbursed
+	e.ReimbursementStatus = &rs
+	e.ReimbursementIncomeId = iid
+	mdb.exp[eid] = e
+	return nil
+}
+
+func status(rs model.ReimbursementStatus) *model.ReimbursementStatus {
+	return &rs
+}
+
+func newMock() *MockDatabase {
+	return &MockDatabase{
+		exp: map[int]model.Expense{
+			1: {Id: 1, Description: "flight", Amount: 420, Reimbursable: true, ReimbursementStatus: status(model.ReimbursementStatusPending)},
+			2: {Id: 2, Description: "hotel", Amount: 300, Reimbursable: true, ReimbursementStatus: status(model.ReimbursementStatusSubmitted)},
+			3: {Id: 3, Description: "taxi", Amount: 35, Reimbursable: true, ReimbursementStatus: status(model.ReimbursementStatusReimbursed), ReimbursementIncomeId: 8},
+			4: {Id: 4, Description: "groceries", Amount: 80},
+		},
+		inc: map[int]model.Income{
+			8: {Id: 8, Description: "expense report 1", Amount: 35},
+			9: {Id: 9, Description: "expense report 2", Amount: 420},
+		},
+	}
+}
+
+func TestSetStatus(t *testing.T) {
+	mock := newMock()
+	actual, err := SetStatus(context.Background(), 1, model.ReimbursementStatusSubmitted, mock)
+	if err != nil {
+		t.Fatalf("error running SetStatus func, %v", err)
+	}
+	assert.Equal(t, status(model.ReimbursementStatusSubmitted), actual.ReimbursementStatus)
+	t.Run("reimbursed needs income", func(t *testing.T) {
+		_, err := SetStatus(context.Background(), 1, model.ReimbursementStatusReimbursed, mock)
+		assert.Error(t, err)
+	})
+	t.Run("not reimbursable", func(t *testing.T) {
+		_, err := SetStatus(context.Background(), 4, model.ReimbursementStatusSubmitted, mock)
+		assert.Error(t, err)
+	})
+	t.Run("missing expense", func(t *testing.T) {
+		_, err := SetStatus(context.Background(), 99, model.ReimbursementStatusSubmitted, mock)
+		var nf *expense.NotFoundError
+		assert.ErrorAs(t, err, &nf)
+	})
+}
+
+func TestReimburse(t *testing.T) {
+	mock := newMock()
+	actual, err := Reimburse(context.Background(), 1, 9, mock)
+	if err != nil {
+		t.Fatalf("error running Reimburse func, %v", err)
+	}
+	assert.Equal(t, status(model.ReimbursementStatusReimbursed), actual.ReimbursementStatus)
+	assert.Equal(t, 9, actual.ReimbursementIncomeId)
+	_, err = Reimburse(context.Background(), 2, 99, mock)
+	assert.ErrorIs(t, err, ErrIncomeNotFound)
+	_, err = Reimburse(context.Background(), 4, 9, mock)
+	assert.Error(t, err)
+}
+
+func TestOutstanding(t *testing.T) {
+	actual, err := Outstanding(context.Background(), newMock())
+	if err != nil {
+		t.Fatalf("error running Outstanding func, %v", err)
+	}
+	var ids []int
+	for _, e := range actual {
+		ids = append(ids, e.Id)
+	}
+	assert.Equal(t, []int{1, 2}, ids)
+}
@@ -0,0 +1,50 @@
+package report
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/vapor05/financeview/graph/model"
+)
+
+// money formats an amount in dollars, e.g. $1,234.50 or -$5.00.
+func money(a float64) string {
+	s := strconv.FormatFloat(math.Abs(a), 'f', 2, 64)
+	whole, frac := s[:len(s)-3], s[len(s)-3:]
+	for i := len(whole) - 3; i > 0; i -= 3 {
+		whole = whole[:i] + "," + whole[i:]
+	}
+	if a < 0 && s != "0.00" {
+		return "-$" + whole + frac
+	}
+	return "$" + whole + frac
+}
+
+// change describes the change from prev to cur as a percentage, e.g. +12%.
+func change(cur, prev float64) string {
+	switch {
+	case prev == 0 && cur == 0:
+		return "-"
+	case prev == 0:
+		return "new"
+	}
+	return fmt.Sprintf("%+.0f%%", (cur-prev)/prev*100)
+}
+
+// categoryName names the row of uncategorized expenses.
+func categoryName(name string) string {
+	if name == "" {
+		return "Uncategorized"
+	}
+	return name
+}
+
+func categoryNames(e model.Expense) string {
+	var names []string
+	for _, c := range e.Categories {
+		names = append(names, c.Name)
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,28 @@
+package report
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"time"
+)
+
+//go:embed monthly.html
+var monthlyHTML string
+
+var monthlyTemplate = template.Must(template.New("monthly").Funcs(template.FuncMap{
+	"money":      money,
+	"change":     change,
+	"category":   categoryName,
+	"categories": categoryNames,
+	"month":      func(t time.Time) string { return t.Format("January 2006") },
+}).Parse(monthlyHTML))
+
+// WriteHTML writes the report as a standalone page styled for printing.
+func WriteHTML(w io.Writer, r Monthly) error {
+	if err := monthlyTemplate.Execute(w, r); err != nil {
+		return fmt.Errorf("failed to write monthly report html, %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package report
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/jung-kurt/gofpdf"
+)
+
+// WritePDF writes the report as an A4 document with the same sections as
+// the HTML page.
+func WritePDF(w io.Writer, r Monthly) error {
+	const rowH = 6.0
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	// Core fonts are encoded in cp1252, not UTF-8.
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	month, previous := r.Month.Format("January 2006"), r.Previous.Format("January 2006")
+	pdf.SetTitle("Monthly report, "+month, true)
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 10, "Monthly report, "+month, "", 1, "L", false, 0, "")
+
+	// table writes a section of rows under a header. The first left columns
+	// are text, and the rest amounts aligned right.
+	table := func(title string, widths []float64, left int, header []string, rows [][]string) {
+		pdf.Ln(4)
+		if title != "" {
+			pdf.SetFont("Helvetica", "B", 12)
+			pdf.CellFormat(0, 8, title, "", 1, "L", false, 0, "")
+		}
+		align := func(i int) string {
+			if i < left {
+				return "L"
+			}
+			return "R"
+		}
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetFillColor(230, 230, 230)
+		for i, h := range header {
+			pdf.CellFormat(widths[i], rowH, h, "B", 0, align(i), true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", 9)
+		if len(rows) == 0 {
+			pdf.CellFormat(0, rowH, "None.", "", 1, "L", false, 0, "")
+		}
+		for _, row := range rows {
+			for i, cell := range row {
+				pdf.CellFormat(widths[i], rowH, fit(pdf, tr(cell), widths[i]), "", 0, align(i), false, 0, "")
+			}
+			pdf.Ln(-1)
+		}
+	}
+
+	table("", []float64{70, 40, 40, 40}, 1, []string{"", month, previous, "Change"}, [][]string{
+		{fmt.Sprintf("Spent, %d expenses", r.Count), money(r.Total), money(r.PreviousTotal), change(r.Total, r.PreviousTotal)},
+	})
+
+	var rows [][]string
+	for _, c := range r.Categories {
+		rows = append(rows, []string{categoryName(c.Name), money(c.Total), money(c.Previous), change(c.Total, c.Previous)})
+	}
+	table("By category", []float64{70, 40, 40, 40}, 1, []string{"Category", month, previous, "Change"}, rows)
+
+	rows = nil
+	for _, b := range r.Budgets {
+		rows = append(rows, []string{b.Category, money(b.Budget), money(b.Actual), money(b.Remaining())})
+	}
+	table("Budget vs. actual", []float64{70, 40, 40, 40}, 1, []string{"Category", "Budget", "Actual", "Remaining"}, rows)
+
+	rows = nil
+	for _, m := range r.Merchants {
+		rows = append(rows, []string{m.Key, fmt.Sprint(m.Count), money(m.Total)})
+	}
+	table("Top merchants", []float64{110, 40, 40}, 1, []string{"Merchant", "Expenses", "Total"}, rows)
+
+	rows = nil
+	for _, e := range r.Largest {
+		rows = append(rows, []string{e.Date, e.Description, categoryNames(e), money(e.Amount)})
+	}
+	table("Largest expenses", []float64{25, 90, 50, 25}, 3, []string{"Date", "Description", "Categories", "Amount"}, rows)
+
+	if err := pdf.Output(w); err != nil {
+		return fmt.Errorf("failed to write monthly report pdf, %w", err)
+	}
+	return nil
+}
+
+// fit shortens s with an ellipsis until it fits a width in the current font.
+func fit(pdf *gofpdf.Fpdf, s string, width float64) string {
+	width -= 2 * pdf.GetCellMargin()
+	if pdf.GetStringWidth(s) <= width {
+		return s
+	}
+	for len(s) > 0 && pdf.GetStringWidth(s+"...") > width {
+		s = s[:len(s)-1]
+	}
+	return s + "..."
+}
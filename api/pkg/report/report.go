@@ -0,0 +1,195 @@
+package report
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/expense"
+	"github.com/vapor05/financeview/pkg/view"
+)
+
+// MonthLayout is how months are written in routes and flags, e.g. 03-2022.
+const MonthLayout = "01-2006"
+
+const (
+	TopMerchants    = 5
+	LargestExpenses = 10
+)
+
+type Database interface {
+	FindExpenses(context.Context, model.ExpenseFilter) ([]model.Expense, error)
+	SummarizeExpenses(context.Context, model.SummaryGroupBy, model.ExpenseFilter) ([]model.SummaryRow, error)
+	ListBudgets(context.Context) ([]model.Budget, error)
+}
+
+// Monthly is a month's statement of personal spending, leaving out
+// reimbursed expenses as summaries do.
+type Monthly struct {
+	// Month and Previous are the first days of the month and the one
+	// before, which it is compared with.
+	Month         time.Time
+	Previous      time.Time
+	Total         float64
+	PreviousTotal float64
+	Count         int
+	// Categories are largest first. Expenses in several categories count
+	// toward each of them.
+	Categories []CategoryTotal
+	// Budgets are by category name.
+	Budgets   []BudgetLine
+	Merchants []model.SummaryRow
+	Largest   []model.Expense
+}
+
+type CategoryTotal struct {
+	Name     string
+	Total    float64
+	Previous float64
+}
+
+type BudgetLine struct {
+	Category string
+	Budget   float64
+	Actual   float64
+}
+
+// Remaining is what is left of the budget, negative when it was overspent.
+func (b BudgetLine) Remaining() float64 {
+	return cents(b.Budget - b.Actual)
+}
+
+// ParseMonth returns the first day of a MonthLayout month.
+func ParseMonth(s string) (time.Time, error) {
+	m, err := time.Parse(MonthLayout, s)
+	if err != nil {
+		return time.Time{}, expense.ValidationErrors{{Field: "month", Message: fmt.Sprintf("%q is not a MM-YYYY month", s)}}
+	}
+	return m, nil
+}
+
+// Build gathers the statement of the month month is in.
+func Build(ctx context.Context, month time.Time, db Database) (Monthly, error) {
+	y, m, _ := month.Date()
+	r := Monthly{Month: time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)}
+	r.Previous = r.Month.AddDate(0, -1, 0)
+	cur, prev := monthFilter(r.Month), monthFilter(r.Previous)
+
+	exps, err := personal(ctx, cur, db)
+	if err != nil {
+		return Monthly{}, err
+	}
+	prevExps, err := personal(ctx, prev, db)
+	if err != nil {
+		return Monthly{}, err
+	}
+	for _, e := range exps {
+		r.Total += e.Amount
+	}
+	for _, e := range prevExps {
+		r.PreviousTotal += e.Amount
+	}
+	r.Total, r.PreviousTotal, r.Count = cents(r.Total), cents(r.PreviousTotal), len(exps)
+
+	cats, err := db.SummarizeExpenses(ctx, model.SummaryGroupByCategory, cur)
+	if err != nil {
+		return Monthly{}, fmt.Errorf("failed to total expenses by category, %w", err)
+	}
+	prevCats, err := db.SummarizeExpenses(ctx, model.SummaryGroupByCategory, prev)
+	if err != nil {
+		return Monthly{}, fmt.Errorf("failed to total previous month's expenses by category, %w", err)
+	}
+	r.Categories = categoryTotals(cats, prevCats)
+
+	budgets, err := db.ListBudgets(ctx)
+	if err != nil {
+		return Monthly{}, fmt.Errorf("failed to list budgets, %w", err)
+	}
+	actual := make(map[string]float64, len(cats))
+	for _, c := range cats {
+		actual[c.Key] = c.Total
+	}
+	for _, b := range budgets {
+		r.Budgets = append(r.Budgets, BudgetLine{Category: b.Category.Name, Budget: b.Amount, Actual: actual[b.Category.Name]})
+	}
+	sort.Slice(r.Budgets, func(i, j int) bool { return r.Budgets[i].Category < r.Budgets[j].Category })
+
+	if r.Merchants, err = db.SummarizeExpenses(ctx, model.SummaryGroupByPayee, cur); err != nil {
+		return Monthly{}, fmt.Errorf("failed to total expenses by payee, %w", err)
+	}
+	if len(r.Merchants) > TopMerchants {
+		r.Merchants = r.Merchants[:TopMerchants]
+	}
+
+	largest := make([]*model.Expense, len(exps))
+	for i := range exps {
+		largest[i] = &exps[i]
+	}
+	view.Sort(largest, model.ExpenseSortAmountDesc)
+	for i := 0; i < len(largest) && i < LargestExpenses; i++ {
+		r.Largest = append(r.Largest, *largest[i])
+	}
+	return r, nil
+}
+
+// monthFilter matches the expenses of the month starting on start.
+func monthFilter(start time.Time) model.ExpenseFilter {
+	from, to := start.Format(expense.DateLayout), start.AddDate(0, 1, -1).Format(expense.DateLayout)
+	return model.ExpenseFilter{From: &from, To: &to}
+}
+
+// personal finds the expenses matching f that weren't paid back.
+func personal(ctx context.Context, f model.ExpenseFilter, db Database) ([]model.Expense, error) {
+	found, err := db.FindExpenses(ctx, f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find expenses from %v to %v, %w", *f.From, *f.To, err)
+	}
+	var exps []model.Expense
+	for _, e := range found {
+		if e.ReimbursementStatus == nil || *e.ReimbursementStatus != model.ReimbursementStatusReimbursed {
+			exps = append(exps, e)
+		}
+	}
+	return exps, nil
+}
+
+// categoryTotals lines up a month's category totals with the month
+// before's, including categories only spent on in the month before.
+func categoryTotals(cur, prev []model.SummaryRow) []CategoryTotal {
+	byName := make(map[string]*CategoryTotal)
+	var cs []*CategoryTotal
+	get := func(name string) *CategoryTotal {
+		c, ok := byName[name]
+		if !ok {
+			c = &CategoryTotal{Name: name}
+			byName[name] = c
+			cs = append(cs, c)
+		}
+		return c
+	}
+	for _, r := range cur {
+		get(r.Key).Total = r.Total
+	}
+	for _, r := range prev {
+		get(r.Key).Previous = r.Total
+	}
+	sort.SliceStable(cs, func(i, j int) bool {
+		if cs[i].Total != cs[j].Total {
+			return cs[i].Total > cs[j].Total
+		}
+		return cs[i].Previous > cs[j].Previous
+	})
+	out := make([]CategoryTotal, len(cs))
+	for i, c := range cs {
+		out[i] = *c
+	}
+	return out
+}
+
+// cents rounds away the error of adding up amounts as floats.
+func cents(a float64) float64 {
+	return math.Round(a*100) / 100
+}
@@ -0,0 +1,140 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/expense"
+)
+
+type MockDatabase struct {
+	exps    map[string][]model.Expense
+	rows    map[string]map[model.SummaryGroupBy][]model.SummaryRow
+	budgets []model.Budget
+}
+
+func (mdb *MockDatabase) FindExpenses(ctx context.Context, f model.ExpenseFilter) ([]model.Expense, error) {
+	return mdb.exps[*f.From], nil
+}
+
+func (mdb *MockDatabase) SummarizeExpenses(ctx context.Context, groupBy model.SummaryGroupBy, f model.ExpenseFilter) ([]model.SummaryRow, error) {
+	return mdb.rows[*f.From][groupBy], nil
+}
+
+func (mdb *MockDatabase) ListBudgets(ctx context.Context) ([]model.Budget, error) {
+	return mdb.budgets, nil
+}
+
+func newMock() *MockDatabase {
+	reimbursed := model.ReimbursementStatusReimbursed
+	return &MockDatabase{
+		exps: map[string][]model.Expense{
+			"03-01-2022": {
+				{Id: 1, Date: "03-02-2022", Description: "sushi", Amount: 64.1, Categories: []model.Category{{Name: "restaurants"}}},
+				{Id: 2, Date: "03-05-2022", Description: "burger", Amount: 18.2, Categories: []model.Category{{Name: "restaurants"}}},
+				{Id: 3, Date: "03-07-2022", Description: "groceries", Amount: 120},
+				{Id: 4, Date: "03-09-2022", Description: "flight", Amount: 400, Reimbursable: true, ReimbursementStatus: &reimbursed},
+			},
+			"02-01-2022": {
+				{Id: 5, Date: "02-12-2022", Description: "tapas", Amount: 90, Categories: []model.Category{{Name: "restaurants"}}},
+				{Id: 6, Date: "02-20-2022", Description: "cinema", Amount: 24, Categories: []model.Category{{Name: "fun"}}},
+			},
+		},
+		rows: map[string]map[model.SummaryGroupBy][]model.SummaryRow{
+			"03-01-2022": {
+				model.SummaryGroupByCategory: {{Key: "", Total: 120, Count: 1}, {Key: "restaurants", Total: 82.3, Count: 2}},
+				model.SummaryGroupByPayee: {
+					{Key: "grocer", Total: 120, Count: 1}, {Key: "sushi", Total: 64.1, Count: 1}, {Key: "burger", Total: 18.2, Count: 1},
+					{Key: "a", Total: 3, Count: 1}, {Key: "b", Total: 2, Count: 1}, {Key: "c", Total: 1, Count: 1},
+				},
+			},
+			"02-01-2022": {
+				model.SummaryGroupByCategory: {{Key: "restaurants", Total: 90, Count: 1}, {Key: "fun", Total: 24, Count: 1}},
+			},
+		},
+		budgets: []model.Budget{
+			{Category: model.Category{Name: "travel"}, Amount: 500},
+			{Category: model.Category{Name: "restaurants"}, Amount: 80},
+		},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	r, err := Build(context.Background(), time.Date(2022, time.March, 17, 0, 0, 0, 0, time.UTC), newMock())
+	if err != nil {
+		t.Fatalf("error running Build func, %v", err)
+	}
+	assert.Equal(t, time.Date(2022, time.March, 1, 0, 0, 0, 0, time.UTC), r.Month)
+	assert.Equal(t, time.Date(2022, time.February, 1, 0, 0, 0, 0, time.UTC), r.Previous)
+	assert.Equal(t, 202.3, r.Total, "reimbursed expenses are left out")
+	assert.Equal(t, 114.0, r.PreviousTotal)
+	assert.Equal(t, 3, r.Count)
+	assert.Equal(t, []CategoryTotal{
+		{Name: "", Total: 120},
+		{Name: "restaurants", Total: 82.3, Previous: 90},
+		{Name: "fun", Previous: 24},
+	}, r.Categories)
+	assert.Equal(t, []BudgetLine{
+		{Category: "restaurants", Budget: 80, Actual: 82.3},
+		{Category: "travel", Budget: 500},
+	}, r.Budgets)
+	assert.Equal(t, -2.3, r.Budgets[0].Remaining())
+	assert.Len(t, r.Merchants, TopMerchants)
+	var ids []int
+	for _, e := range r.Largest {
+		ids = append(ids, e.Id)
+	}
+	assert.Equal(t, []int{3, 1, 2}, ids)
+}
+
+func TestParseMonth(t *testing.T) {
+	m, err := ParseMonth("03-2022")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2022, time.March, 1, 0, 0, 0, 0, time.UTC), m)
+
+	_, err = ParseMonth("2022-03")
+	var ves expense.ValidationErrors
+	assert.ErrorAs(t, err, &ves)
+}
+
+func TestWrite(t *testing.T) {
+	r, err := Build(context.Background(), time.Date(2022, time.March, 1, 0, 0, 0, 0, time.UTC), newMock())
+	if err != nil {
+		t.Fatalf("error running Build func, %v", err)
+	}
+	r.Largest[0].Description = "<script>"
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, r); err != nil {
+		t.Fatalf("error running WriteHTML func, %v", err)
+	}
+	page := buf.String()
+	assert.Contains(t, page, "Monthly report, March 2022")
+	assert.Contains(t, page, "<td>Uncategorized</td>")
+	assert.Contains(t, page, `<td class="amount over">-$2.30</td>`)
+	assert.Contains(t, page, "&lt;script&gt;")
+	assert.NotContains(t, page, "<script>")
+
+	buf.Reset()
+	if err := WritePDF(&buf, r); err != nil {
+		t.Fatalf("error running WritePDF func, %v", err)
+	}
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")))
+	buf.Reset()
+	if err := WritePDF(&buf, Monthly{Month: r.Month, Previous: r.Previous}); err != nil {
+		t.Fatalf("error running WritePDF func on an empty month, %v", err)
+	}
+}
+
+func Test_money(t *testing.T) {
+	cases := map[float64]string{0: "$0.00", 5: "$5.00", 1234.5: "$1,234.50", 1234567.891: "$1,234,567.89", -2.3: "-$2.30", -0.001: "$0.00"}
+	for a, want := range cases {
+		assert.Equal(t, want, money(a))
+	}
+	assert.Equal(t, "+25%", change(125, 100))
+	assert.Equal(t, "-10%", change(90, 100))
+	assert.Equal(t, "new", change(5, 0))
+}
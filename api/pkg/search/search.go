@@ -0,0 +1,53 @@
+package search
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/expense"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+type Database interface {
+	SearchExpenses(context.Context, string, model.ExpenseFilter, int) ([]model.SearchResult, error)
+}
+
+// Expenses returns the expenses best matching a search query and the
+// optional filter, resolved as for listing expenses, at most limit of them. The query is written as for a web
+// search engine, with quoted phrases, "or" and -excluded words.
+func Expenses(ctx context.Context, query string, filter *model.ExpenseFilter, limit *int, db Database) ([]*model.SearchResult, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, &expense.ValidationError{Field: "query", Message: "must not be empty"}
+	}
+	var f model.ExpenseFilter
+	if filter != nil {
+		var err error
+		if f, err = expense.ResolveFilter(*filter, time.Now().UTC()); err != nil {
+			return nil, err
+		}
+	}
+	n := DefaultLimit
+	if limit != nil {
+		if *limit <= 0 || *limit > MaxLimit {
+			return nil, &expense.ValidationError{Field: "limit", Message: fmt.Sprintf("must be between 1 and %d", MaxLimit)}
+		}
+		n = *limit
+	}
+	rs, err := db.SearchExpenses(ctx, query, f, n)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search expenses, %w", err)
+	}
+	out := make([]*model.SearchResult, len(rs))
+	for i := range rs {
+		out[i] = &rs[i]
+	}
+	return out, nil
+}
@@ -0,0 +1,71 @@
+package search
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/expense"
+)
+
+type MockDatabase struct {
+	query  string
+	filter model.ExpenseFilter
+	limit  int
+}
+
+func (mdb *MockDatabase) SearchExpenses(ctx context.Context, query string, f model.ExpenseFilter, limit int) ([]model.SearchResult, error) {
+	mdb.query, mdb.filter, mdb.limit = query, f, limit
+	return []model.SearchResult{{Expense: model.Expense{Id: 1, Description: "plumber"}, Rank: 0.5, Snippet: "<b>plumber</b>"}}, nil
+}
+
+func TestExpenses(t *testing.T) {
+	ten, zero, many := 10, 0, MaxLimit+1
+	month := model.PeriodThisMonth
+	start, end := expense.PeriodDates(month, time.Now().UTC())
+	from, to := start.Format(expense.DateLayout), end.Format(expense.DateLayout)
+	cases := []struct {
+		name   string
+		query  string
+		filter *model.ExpenseFilter
+		limit  *int
+		field  string
+		want   MockDatabase
+	}{
+		{name: "defaults", query: " plumber march ", want: MockDatabase{query: "plumber march", limit: DefaultLimit}},
+		{
+			name:   "filter and limit",
+			query:  "plumber",
+			filter: &model.ExpenseFilter{Tags: []string{" House "}, Period: &month},
+			limit:  &ten,
+			want:   MockDatabase{query: "plumber", filter: model.ExpenseFilter{Tags: []string{"house"}, From: &from, To: &to}, limit: 10},
+		},
+		{name: "bad filter", query: "plumber", filter: &model.ExpenseFilter{Period: &month, From: &from}, field: "period"},
+		{name: "empty query", query: "  ", field: "query"},
+		{name: "zero limit", query: "plumber", limit: &zero, field: "limit"},
+		{name: "big limit", query: "plumber", limit: &many, field: "limit"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			mock := &MockDatabase{}
+			rs, err := Expenses(context.Background(), c.query, c.filter, c.limit, mock)
+			if c.field != "" {
+				var ve *expense.ValidationError
+				var ves expense.ValidationErrors
+				if errors.As(err, &ves) {
+					assert.Equal(t, c.field, ves[0].Field)
+				} else if assert.ErrorAs(t, err, &ve) {
+					assert.Equal(t, c.field, ve.Field)
+				}
+				assert.Equal(t, MockDatabase{}, *mock, "the database is not searched")
+				return
+			}
+			assert.Nil(t, err)
+			assert.Equal(t, c.want, *mock)
+			assert.Len(t, rs, 1)
+		})
+	}
+}
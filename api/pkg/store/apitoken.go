@@ -0,0 +1,118 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+func (db *Database) CreateApiToken(ctx context.Context, uid int, t model.ApiToken, tokenHash string, expires *time.Time) (int, error) {
+	ctx = named(ctx, "CreateApiToken")
+	sql := `
+		INSERT INTO financeview.api_token (user_id, name, scope, token_hash, expiresat, createdate)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+	var id int
+	if err := db.Conn.QueryRow(ctx, sql, uid, t.Name, string(t.Scope), tokenHash, expires, time.Now().UTC()).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to insert new api_token into database, %w", err)
+	}
+	return id, nil
+}
+
+func (db *Database) ListApiTokens(ctx context.Context, uid int) ([]model.ApiToken, error) {
+	ctx = named(ctx, "ListApiTokens")
+	sql := `
+		SELECT id, name, scope, expiresat, lastusedat, createdate
+		FROM financeview.api_token
+		WHERE user_id = $1
+		ORDER BY id
+	`
+	var ts []model.ApiToken
+	rows, err := db.Conn.Query(ctx, sql, uid)
+	if err != nil {
+		return ts, fmt.Errorf("failed to select api tokens from database, %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var t ApiToken
+		if err := rows.Scan(&t.Id, &t.Name, &t.Scope, &t.ExpiresAt, &t.LastUsedAt, &t.CreateDate); err != nil {
+			return ts, fmt.Errorf("failed to scan api tokens from database, %w", err)
+		}
+		ts = append(ts, model.ApiToken{
+			Id:         int(t.Id.Int),
+			Name:       t.Name.String,
+			Scope:      model.TokenScope(t.Scope.String),
+			ExpiresAt:  timestampString(t.ExpiresAt),
+			LastUsedAt: timestampString(t.LastUsedAt),
+			CreateDate: t.CreateDate.Time.Format(time.RFC3339),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return ts, fmt.Errorf("failed to read api tokens from database, %w", err)
+	}
+	return ts, nil
+}
+
+func (db *Database) DeleteApiToken(ctx context.Context, uid int, id int) error {
+	ctx = named(ctx, "DeleteApiToken")
+	tag, err := db.Conn.Exec(ctx, `DELETE FROM financeview.api_token WHERE id=$1 AND user_id=$2`, id, uid)
+	if err != nil {
+		return fmt.Errorf("failed to delete api_token id=%v, %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return notFound("api_token id=%v does not exist", id)
+	}
+	return nil
+}
+
+// GetApiTokenUser returns the user and scope of a token that has not expired
+// by now, and records now as when it was last used. The last used time is
+// only written once a minute to keep busy scripts from updating it on
+// every request.
+func (db *Database) GetApiTokenUser(ctx context.Context, tokenHash string, now time.Time) (model.User, model.TokenScope, bool, error) {
+	ctx = named(ctx, "GetApiTokenUser")
+	sql := `
+		SELECT t.id, u.id, u.email, t.scope, t.lastusedat
+		FROM financeview.api_token AS t
+		INNER JOIN financeview.app_user AS u
+		ON t.user_id = u.id
+		WHERE t.token_hash=$1 AND (t.expiresat IS NULL OR t.expiresat > $2)
+	`
+	var t ApiToken
+	var u User
+	if err := db.Conn.QueryRow(ctx, sql, tokenHash, now).Scan(&t.Id, &u.Id, &u.Email, &t.Scope, &t.LastUsedAt); err != nil {
+		if err == pgx.ErrNoRows {
+			return model.User{}, "", false, nil
+		}
+		return model.User{}, "", false, fmt.Errorf("failed to query api_token table, %w", err)
+	}
+	if t.LastUsedAt.Status != pgtype.Present || now.Sub(t.LastUsedAt.Time) >= time.Minute {
+		if _, err := db.Conn.Exec(ctx, `UPDATE financeview.api_token SET lastusedat=$2 WHERE id=$1`, t.Id.Int, now); err != nil {
+			return model.User{}, "", false, fmt.Errorf("failed to record api_token use, %w", err)
+		}
+	}
+	return model.User{Id: int(u.Id.Int), Email: u.Email.String}, model.TokenScope(t.Scope.String), true, nil
+}
+
+// timestampString formats a nullable timestamp, leaving NULL as nil.
+func timestampString(t pgtype.Timestamp) *string {
+	if t.Status != pgtype.Present {
+		return nil
+	}
+	s := t.Time.Format(time.RFC3339)
+	return &s
+}
+
+type ApiToken struct {
+	Id         pgtype.Int4
+	Name       pgtype.Text
+	Scope      pgtype.Text
+	ExpiresAt  pgtype.Timestamp
+	LastUsedAt pgtype.Timestamp
+	CreateDate pgtype.Timestamp
+}
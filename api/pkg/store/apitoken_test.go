@@ -0,0 +1,48 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+func TestGetApiTokenUser(t *testing.T) {
+	ctx := testCtx
+	db := Database{conn}
+	defer func() {
+		if err := cleanUpDb(); err != nil {
+			t.Fatalf("failed to clean up test data, %v", err)
+		}
+	}()
+	uid, err := db.CreateUser(ctx, "bot@example.com", "hash")
+	if err != nil {
+		t.Fatalf("failed to setup test data, %v", err)
+	}
+	now := time.Now().UTC()
+	exp := now.Add(time.Hour)
+	id, err := db.CreateApiToken(ctx, uid, model.ApiToken{Name: "import", Scope: model.TokenScopeWrite}, "tokenhash", &exp)
+	if err != nil {
+		t.Fatalf("error running CreateApiToken func, %v", err)
+	}
+	u, s, ok, err := db.GetApiTokenUser(ctx, "tokenhash", now)
+	if err != nil {
+		t.Fatalf("error running GetApiTokenUser func, %v", err)
+	}
+	assert.True(t, ok)
+	assert.Equal(t, model.User{Id: uid, Email: "bot@example.com"}, u)
+	assert.Equal(t, model.TokenScopeWrite, s)
+	_, _, ok, err = db.GetApiTokenUser(ctx, "tokenhash", now.Add(2*time.Hour))
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	ts, err := db.ListApiTokens(ctx, uid)
+	if err != nil {
+		t.Fatalf("error running ListApiTokens func, %v", err)
+	}
+	assert.Len(t, ts, 1)
+	assert.Equal(t, id, ts[0].Id)
+	assert.NotNil(t, ts[0].LastUsedAt)
+	assert.Nil(t, db.DeleteApiToken(ctx, uid, id))
+	assert.Error(t, db.DeleteApiToken(ctx, uid, id))
+}
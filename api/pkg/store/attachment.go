@@ -0,0 +1,210 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+func (db *Database) ExpenseExists(ctx context.Context, eid int) (bool, error) {
+	ctx = named(ctx, "ExpenseExists")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return false, err
+	}
+	sql := `SELECT EXISTS (SELECT 1 FROM financeview.expense WHERE id=$1 AND ledger_id=$2 AND deletedat IS NULL)`
+	var ok bool
+	if err := db.Conn.QueryRow(ctx, sql, eid, lid).Scan(&ok); err != nil {
+		return false, fmt.Errorf("failed to query expense table, %w", err)
+	}
+	return ok, nil
+}
+
+// CreateAttachment runs put to store the attachment's contents and inserts
+// the attachment, holding the lock on its storage key so the contents can't
+// be released by ReleaseStorageKey in between.
+func (db *Database) CreateAttachment(ctx context.Context, a model.Attachment, put func() error) (int, error) {
+	ctx = named(ctx, "CreateAttachment")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sql := `
+		INSERT INTO financeview.attachment (ledger_id, expense_id, filename, content_type, size, sha256, storage_key, createdate)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+	var id int
+	err = db.inTx(ctx, func(tx pgx.Tx) error {
+		if err := lockStorageKey(ctx, tx, a.Key); err != nil {
+			return err
+		}
+		if err := put(); err != nil {
+			return err
+		}
+		if err := tx.QueryRow(
+			ctx,
+			sql,
+			lid,
+			a.ExpenseId,
+			a.Filename,
+			a.ContentType,
+			a.Size,
+			a.Sha256,
+			a.Key,
+			time.Now().UTC(),
+		).Scan(&id); err != nil {
+			return fmt.Errorf("failed to insert new attachment into database, %w", err)
+		}
+		return nil
+	})
+	return id, err
+}
+
+// ReleaseStorageKey runs del to delete the contents stored under a key if no
+// attachment, of any ledger, uses the key. The check and del run holding the
+// key's lock, so contents are never deleted as an attachment of the same
+// contents is being created.
+func (db *Database) ReleaseStorageKey(ctx context.Context, key string, del func() error) error {
+	ctx = named(ctx, "ReleaseStorageKey")
+	return db.inTx(ctx, func(tx pgx.Tx) error {
+		if err := lockStorageKey(ctx, tx, key); err != nil {
+			return err
+		}
+		var used bool
+		sql := `SELECT EXISTS (SELECT 1 FROM financeview.attachment WHERE storage_key=$1)`
+		if err := tx.QueryRow(ctx, sql, key).Scan(&used); err != nil {
+			return fmt.Errorf("failed to query attachment table, %w", err)
+		}
+		if used {
+			return nil
+		}
+		return del()
+	})
+}
+
+// lockStorageKey takes a lock on a storage key held until tx ends.
+func lockStorageKey(ctx context.Context, tx pgx.Tx, key string) error {
+	if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, key); err != nil {
+		return fmt.Errorf("failed to lock storage key, %w", err)
+	}
+	return nil
+}
+
+func (db *Database) GetAttachment(ctx context.Context, id int) (model.Attachment, bool, error) {
+	ctx = named(ctx, "GetAttachment")
+	as, err := db.queryAttachments(ctx, `WHERE a.id=$1`, id)
+	if err != nil {
+		return model.Attachment{}, false, err
+	}
+	if len(as) == 0 {
+		return model.Attachment{}, false, nil
+	}
+	return as[0], true, nil
+}
+
+func (db *Database) ListAttachments(ctx context.Context, eid int) ([]model.Attachment, error) {
+	ctx = named(ctx, "ListAttachments")
+	return db.queryAttachments(ctx, `WHERE a.expense_id=$1`, eid)
+}
+
+// ListAttachmentsFor returns the attachments of each of the expenses, by
+// expense id.
+func (db *Database) ListAttachmentsFor(ctx context.Context, eids []int) (map[int][]model.Attachment, error) {
+	ctx = named(ctx, "ListAttachmentsFor")
+	as, err := db.queryAttachments(ctx, `WHERE a.expense_id = ANY($1)`, eids)
+	if err != nil {
+		return nil, err
+	}
+	byExpense := make(map[int][]model.Attachment)
+	for _, a := range as {
+		byExpense[a.ExpenseId] = append(byExpense[a.ExpenseId], a)
+	}
+	return byExpense, nil
+}
+
+// queryAttachments selects the ledger's attachments matching where, leaving
+// out those of deleted expenses. Purging and releasing storage keys query
+// the attachment table directly instead.
+func (db *Database) queryAttachments(ctx context.Context, where string, args ...interface{}) ([]model.Attachment, error) {
+	where, args, err := scope(ctx, "a.ledger_id", where, args)
+	if err != nil {
+		return nil, err
+	}
+	sql := `
+		SELECT a.id, a.expense_id, a.filename, a.content_type, a.size, a.sha256, a.storage_key, a.createdate
+		FROM financeview.attachment AS a
+		INNER JOIN financeview.expense AS e
+		ON a.expense_id = e.id
+		` + where + ` AND e.deletedat IS NULL
+		ORDER BY a.id
+	`
+	var as []model.Attachment
+	rows, err := db.Conn.Query(ctx, sql, args...)
+	if err != nil {
+		return as, fmt.Errorf("failed to select attachments from database, %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a Attachment
+		if err := rows.Scan(&a.Id, &a.ExpenseId, &a.Filename, &a.ContentType, &a.Size, &a.Sha256, &a.Key, &a.CreateDate); err != nil {
+			return as, fmt.Errorf("failed to scan attachments from database, %w", err)
+		}
+		as = append(as, model.Attachment{
+			Id:          int(a.Id.Int),
+			ExpenseId:   int(a.ExpenseId.Int),
+			Filename:    a.Filename.String,
+			ContentType: a.ContentType.String,
+			Size:        int(a.Size.Int),
+			Sha256:      a.Sha256.String,
+			Key:         a.Key.String,
+			CreateDate:  a.CreateDate.Time.Format(time.RFC3339),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return as, fmt.Errorf("failed to read attachments from database, %w", err)
+	}
+	return as, nil
+}
+
+// DeleteAttachment removes the attachment row and reports whether any other
+// attachment, of any ledger, still uses the same storage key.
+func (db *Database) DeleteAttachment(ctx context.Context, id int) (string, bool, error) {
+	ctx = named(ctx, "DeleteAttachment")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	var key string
+	var shared bool
+	err = db.inTx(ctx, func(tx pgx.Tx) error {
+		sql := `DELETE FROM financeview.attachment WHERE id=$1 AND ledger_id=$2 RETURNING storage_key`
+		if err := tx.QueryRow(ctx, sql, id, lid).Scan(&key); err != nil {
+			if err == pgx.ErrNoRows {
+				return notFound("attachment id=%v does not exist", id)
+			}
+			return fmt.Errorf("failed to delete attachment id=%v, %w", id, err)
+		}
+		sql = `SELECT EXISTS (SELECT 1 FROM financeview.attachment WHERE storage_key=$1)`
+		if err := tx.QueryRow(ctx, sql, key).Scan(&shared); err != nil {
+			return fmt.Errorf("failed to query attachment table, %w", err)
+		}
+		return nil
+	})
+	return key, shared, err
+}
+
+type Attachment struct {
+	Id          pgtype.Int4
+	ExpenseId   pgtype.Int4
+	Filename    pgtype.Text
+	ContentType pgtype.Text
+	Size        pgtype.Int4
+	Sha256      pgtype.Text
+	Key         pgtype.Text
+	CreateDate  pgtype.Timestamp
+}
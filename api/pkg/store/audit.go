@@ -0,0 +1,270 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/auth"
+)
+
+// querier is a connection or a transaction.
+type querier interface {
+	Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error)
+	QueryRow(context.Context, string, ...interface{}) pgx.Row
+}
+
+// auditTables maps audited entities to their table.
+var auditTables = map[model.AuditEntity]string{
+	model.AuditEntityExpense:         "expense",
+	model.AuditEntityCategory:        "category",
+	model.AuditEntityExpenseCategory: "expense_category",
+	model.AuditEntityExpenseTag:      "expense_tag",
+	model.AuditEntityBudget:          "budget",
+}
+
+type auditChange struct {
+	Entity    model.AuditEntity
+	EntityId  int
+	ExpenseId int
+	Action    model.AuditAction
+	Before    []byte
+	After     []byte
+}
+
+// snapshot returns an audited entity's row as JSON, or nil if there is none.
+func snapshot(ctx context.Context, q querier, e model.AuditEntity, id int) ([]byte, error) {
+	sql := fmt.Sprintf(`SELECT row_to_json(t) FROM financeview.%s AS t WHERE t.id=$1`, auditTables[e])
+	var b []byte
+	if err := q.QueryRow(ctx, sql, id).Scan(&b); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to snapshot %s id=%v, %w", auditTables[e], id, err)
+	}
+	return b, nil
+}
+
+// audit appends a change made by the user in the context to the ledger's
+// audit log.
+func audit(ctx context.Context, q querier, c auditChange) error {
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return err
+	}
+	var actor *int
+	if u, ok := auth.UserFromContext(ctx); ok {
+		actor = &u.Id
+	}
+	var eid *int
+	if c.ExpenseId != 0 {
+		eid = &c.ExpenseId
+	}
+	sql := `
+		INSERT INTO financeview.audit_log (ledger_id, actor_id, entity, entity_id, expense_id, action, before, after, createdate)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	if _, err := q.Exec(ctx, sql, lid, actor, string(c.Entity), c.EntityId, eid, string(c.Action), c.Before, c.After, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to insert audit_log entry, %w", err)
+	}
+	return nil
+}
+
+// createAudited runs an INSERT returning the new row's id in a transaction
+// with its audit entry. The INSERT returning no row is reported as
+// pgx.ErrNoRows.
+func (db *Database) createAudited(ctx context.Context, c auditChange, sql string, args ...interface{}) (int, error) {
+	var id int
+	err := db.inTx(ctx, func(tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, sql, args...).Scan(&id); err != nil {
+			return err
+		}
+		after, err := snapshot(ctx, tx, c.Entity, id)
+		if err != nil {
+			return err
+		}
+		c.EntityId, c.Action, c.After = id, model.AuditActionCreate, after
+		if c.Entity == model.AuditEntityExpense {
+			c.ExpenseId = id
+		}
+		return audit(ctx, tx, c)
+	})
+	return id, err
+}
+
+// updateExpenseAudited runs an UPDATE of one expense in a transaction with
+// its audit entry, reporting whether the expense was changed. Soft deletes
+// are recorded with the DELETE action.
+func (db *Database) updateExpenseAudited(ctx context.Context, action model.AuditAction, id int, sql string, args ...interface{}) (bool, error) {
+	var ok bool
+	err := db.inTx(ctx, func(tx pgx.Tx) (err error) {
+		ok, err = updateExpenseTx(ctx, tx, action, id, sql, args...)
+		return err
+	})
+	return ok, err
+}
+
+// updateExpenseTx is updateExpenseAudited in a transaction already begun.
+func updateExpenseTx(ctx context.Context, tx pgx.Tx, action model.AuditAction, id int, sql string, args ...interface{}) (bool, error) {
+	before, err := snapshot(ctx, tx, model.AuditEntityExpense, id)
+	if err != nil {
+		return false, err
+	}
+	tag, err := tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return false, err
+	}
+	if tag.RowsAffected() == 0 {
+		return false, nil
+	}
+	after, err := snapshot(ctx, tx, model.AuditEntityExpense, id)
+	if err != nil {
+		return false, err
+	}
+	return true, audit(ctx, tx, auditChange{
+		Entity:    model.AuditEntityExpense,
+		EntityId:  id,
+		ExpenseId: id,
+		Action:    action,
+		Before:    before,
+		After:     after,
+	})
+}
+
+// deleteAudited runs a DELETE returning the id and row_to_json of every
+// deleted row in a transaction with an audit entry for each.
+func (db *Database) deleteAudited(ctx context.Context, e model.AuditEntity, eid int, sql string, args ...interface{}) error {
+	return db.inTx(ctx, func(tx pgx.Tx) error {
+		return deleteTx(ctx, tx, e, eid, sql, args...)
+	})
+}
+
+// deleteTx is deleteAudited in a transaction already begun.
+func deleteTx(ctx context.Context, tx pgx.Tx, e model.AuditEntity, eid int, sql string, args ...interface{}) error {
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	var cs []auditChange
+	for rows.Next() {
+		c := auditChange{Entity: e, ExpenseId: eid, Action: model.AuditActionDelete}
+		if err := rows.Scan(&c.EntityId, &c.Before); err != nil {
+			rows.Close()
+			return err
+		}
+		cs = append(cs, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, c := range cs {
+		if err := audit(ctx, tx, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListAuditEntries returns the ledger's newest audit entries matching the
+// filter.
+func (db *Database) ListAuditEntries(ctx context.Context, f model.AuditFilter, limit int) ([]model.AuditEntry, error) {
+	ctx = named(ctx, "ListAuditEntries")
+	var conds []string
+	var args []interface{}
+	if f.Entity != nil {
+		args = append(args, string(*f.Entity))
+		conds = append(conds, fmt.Sprintf("a.entity = $%d", len(args)))
+	}
+	if f.EntityID != nil {
+		args = append(args, *f.EntityID)
+		conds = append(conds, fmt.Sprintf("a.entity_id = $%d", len(args)))
+	}
+	if f.ActorID != nil {
+		args = append(args, *f.ActorID)
+		conds = append(conds, fmt.Sprintf("a.actor_id = $%d", len(args)))
+	}
+	var where string
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+	args = append(args, limit)
+	order := fmt.Sprintf("ORDER BY a.id DESC LIMIT $%d", len(args))
+	return db.queryAuditEntries(ctx, where, order, args...)
+}
+
+// ExpenseHistory returns every audit entry about an expense and its links,
+// oldest first.
+func (db *Database) ExpenseHistory(ctx context.Context, eid int) ([]model.AuditEntry, error) {
+	ctx = named(ctx, "ExpenseHistory")
+	return db.queryAuditEntries(ctx, `WHERE a.expense_id = $1`, `ORDER BY a.id`, eid)
+}
+
+func (db *Database) queryAuditEntries(ctx context.Context, where string, order string, args ...interface{}) ([]model.AuditEntry, error) {
+	where, args, err := scope(ctx, "a.ledger_id", where, args)
+	if err != nil {
+		return nil, err
+	}
+	sql := `
+		SELECT a.id, a.entity, a.entity_id, a.expense_id, a.action, a.before::text, a.after::text, a.createdate, u.id, u.email
+		FROM financeview.audit_log AS a
+		LEFT JOIN financeview.app_user AS u
+		ON a.actor_id = u.id
+		` + where + `
+		` + order
+	var es []model.AuditEntry
+	rows, err := db.Conn.Query(ctx, sql, args...)
+	if err != nil {
+		return es, fmt.Errorf("failed to select audit entries from database, %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a AuditEntry
+		var u User
+		if err := rows.Scan(&a.Id, &a.Entity, &a.EntityId, &a.ExpenseId, &a.Action, &a.Before, &a.After, &a.CreateDate, &u.Id, &u.Email); err != nil {
+			return es, fmt.Errorf("failed to scan audit entries from database, %w", err)
+		}
+		e := model.AuditEntry{
+			Id:         int(a.Id.Int),
+			Entity:     model.AuditEntity(a.Entity.String),
+			EntityId:   int(a.EntityId.Int),
+			ExpenseId:  int(a.ExpenseId.Int),
+			Action:     model.AuditAction(a.Action.String),
+			Before:     textString(a.Before),
+			After:      textString(a.After),
+			CreateDate: a.CreateDate.Time.Format(time.RFC3339),
+		}
+		if u.Id.Status == pgtype.Present {
+			e.Actor = &model.User{Id: int(u.Id.Int), Email: u.Email.String}
+		}
+		es = append(es, e)
+	}
+	if err := rows.Err(); err != nil {
+		return es, fmt.Errorf("failed to read audit entries from database, %w", err)
+	}
+	return es, nil
+}
+
+// textString returns a nullable text value, leaving NULL as nil.
+func textString(t pgtype.Text) *string {
+	if t.Status != pgtype.Present {
+		return nil
+	}
+	return &t.String
+}
+
+type AuditEntry struct {
+	Id         pgtype.Int4
+	Entity     pgtype.Text
+	EntityId   pgtype.Int4
+	ExpenseId  pgtype.Int4
+	Action     pgtype.Text
+	Before     pgtype.Text
+	After      pgtype.Text
+	CreateDate pgtype.Timestamp
+}
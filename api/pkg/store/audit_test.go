@@ -0,0 +1,71 @@
+package store
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+func TestExpenseHistory(t *testing.T) {
+	ctx := testCtx
+	db := Database{conn}
+	defer func() {
+		if err := cleanUpDb(); err != nil {
+			t.Fatalf("failed to clean up test data, %v", err)
+		}
+	}()
+	if _, err := conn.Exec(ctx, "INSERT INTO financeview.app_user (id, email, password_hash) VALUES (1, 'test@example.com', 'hash')"); err != nil {
+		t.Fatalf("failed to setup test data, %v", err)
+	}
+	dt := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	eid, err := db.CreateExpense(ctx, dt, 1, 12.5, "lunch")
+	if err != nil {
+		t.Fatalf("error running CreateExpense func, %v", err)
+	}
+	cid, err := db.CreateCategory(ctx, "food")
+	if err != nil {
+		t.Fatalf("error running CreateCategory func, %v", err)
+	}
+	if _, err := db.LinkExpenseCategory(ctx, eid, cid); err != nil {
+		t.Fatalf("error running LinkExpenseCategory func, %v", err)
+	}
+	if err := db.UpdateExpense(ctx, eid, dt, 1, 14, "lunch"); err != nil {
+		t.Fatalf("error running UpdateExpense func, %v", err)
+	}
+	if err := db.UnlinkExpenseCategories(ctx, eid); err != nil {
+		t.Fatalf("error running UnlinkExpenseCategories func, %v", err)
+	}
+	actual, err := db.ExpenseHistory(ctx, eid)
+	if err != nil {
+		t.Fatalf("error running ExpenseHistory func, %v", err)
+	}
+	var got []string
+	for _, e := range actual {
+		got = append(got, string(e.Entity)+" "+string(e.Action))
+		assert.Equal(t, &model.User{Id: 1, Email: "test@example.com"}, e.Actor)
+	}
+	assert.Equal(t, []string{"EXPENSE CREATE", "EXPENSE_CATEGORY CREATE", "EXPENSE UPDATE", "EXPENSE_CATEGORY DELETE"}, got)
+	var before, after map[string]interface{}
+	if err := json.Unmarshal([]byte(*actual[2].Before), &before); err != nil {
+		t.Fatalf("failed to decode audit before, %v", err)
+	}
+	if err := json.Unmarshal([]byte(*actual[2].After), &after); err != nil {
+		t.Fatalf("failed to decode audit after, %v", err)
+	}
+	assert.Equal(t, "$12.50", before["amount"])
+	assert.Equal(t, "$14.00", after["amount"])
+	assert.Nil(t, actual[3].After)
+
+	ent := model.AuditEntityCategory
+	cs, err := db.ListAuditEntries(ctx, model.AuditFilter{Entity: &ent}, 10)
+	if err != nil {
+		t.Fatalf("error running ListAuditEntries func, %v", err)
+	}
+	assert.Len(t, cs, 1)
+	assert.Equal(t, cid, cs[0].EntityId)
+	_, err = conn.Exec(ctx, "DELETE FROM financeview.audit_log")
+	assert.Error(t, err)
+}
@@ -0,0 +1,339 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/auth"
+)
+
+// CreateExpenses saves new expenses in one transaction, returning them with
+// their ids and the ids of their categories and tags. Descriptions,
+// categories and tags are looked up once for the whole batch, and missing
+// ones created, before the expenses and their links are inserted in batches
+// of statements. Every insert is audited as CreateExpense and the link
+// methods would.
+func (db *Database) CreateExpenses(ctx context.Context, es []model.Expense) ([]model.Expense, error) {
+	ctx = named(ctx, "CreateExpenses")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var actor *int
+	if u, ok := auth.UserFromContext(ctx); ok {
+		actor = &u.Id
+	}
+	dates := make([]time.Time, len(es))
+	var descs, cats, tags []string
+	for i, e := range es {
+		if dates[i], err = time.Parse("01-02-2006", e.Date); err != nil {
+			return nil, fmt.Errorf("failed to parse date of expense %d, %w", i, err)
+		}
+		descs = append(descs, e.Description)
+		for _, c := range e.Categories {
+			cats = append(cats, c.Name)
+		}
+		for _, t := range e.Tags {
+			tags = append(tags, t.Name)
+		}
+	}
+	now := time.Now().UTC()
+	saved := make([]model.Expense, len(es))
+	err = db.inTx(ctx, func(tx pgx.Tx) error {
+		dids, err := descriptionIds(ctx, tx, lid, now, descs)
+		if err != nil {
+			return err
+		}
+		cids, err := categoryIds(ctx, tx, lid, actor, now, cats)
+		if err != nil {
+			return err
+		}
+		tids, err := tagIds(ctx, tx, lid, now, tags)
+		if err != nil {
+			return err
+		}
+
+		b := &pgx.Batch{}
+		for i, e := range es {
+			var r *bool
+			var rs *string
+			if e.Reimbursable {
+				pending := model.ReimbursementStatusPending.String()
+				r, rs = &e.Reimbursable, &pending
+			}
+			sql := `
+				WITH created AS (
+					INSERT INTO financeview.expense AS e (ledger_id, date, description_id, amount, comment, reimbursable, reimbursement_status, createdate)
+					VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+					RETURNING e.id, row_to_json(e) AS row
+				), audited AS (
+					INSERT INTO financeview.audit_log (ledger_id, actor_id, entity, entity_id, expense_id, action, after, createdate)
+					SELECT $1, $9::int, $10::text, id, id, $11::text, row, $8 FROM created
+				)
+				SELECT id FROM created
+			`
+			b.Queue(sql, lid, dates[i], dids[e.Description], e.Amount, e.Comment, r, rs, now, actor, string(model.AuditEntityExpense), string(model.AuditActionCreate))
+		}
+		err = sendBatch(ctx, tx, b, func(i int, row pgx.Row) error {
+			saved[i] = es[i]
+			return row.Scan(&saved[i].Id)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to insert new expenses, %w", err)
+		}
+
+		var ceids, ccids, teids, ttids []int
+		for i := range saved {
+			e := &saved[i]
+			e.Categories = append([]model.Category(nil), e.Categories...)
+			for j := range e.Categories {
+				e.Categories[j].Id = cids[e.Categories[j].Name]
+				ceids, ccids = append(ceids, e.Id), append(ccids, e.Categories[j].Id)
+			}
+			e.Tags = append([]model.Tag(nil), e.Tags...)
+			for j := range e.Tags {
+				e.Tags[j].Id = tids[e.Tags[j].Name]
+				teids, ttids = append(teids, e.Id), append(ttids, e.Tags[j].Id)
+			}
+			if e.Reimbursable {
+				rs := model.ReimbursementStatusPending
+				e.ReimbursementStatus = &rs
+			}
+		}
+		if err := insertLinks(ctx, tx, model.AuditEntityExpenseCategory, "category_id", ceids, ccids, lid, actor, now); err != nil {
+			return err
+		}
+		return insertLinks(ctx, tx, model.AuditEntityExpenseTag, "tag_id", teids, ttids, lid, actor, now)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create expenses, %w", err)
+	}
+	return saved, nil
+}
+
+// ReplaceExpense saves every field of an existing expense and relinks it to
+// the categories and tags named in e, in one transaction, so a failure
+// leaves the expense as it was. Missing descriptions, categories and tags
+// are created, and every change is audited as UpdateExpense, the unlink
+// methods and CreateExpenses would. Whether the expense is reimbursable is
+// only changed when r isn't nil.
+func (db *Database) ReplaceExpense(ctx context.Context, e model.Expense, r *bool) error {
+	ctx = named(ctx, "ReplaceExpense")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return err
+	}
+	var actor *int
+	if u, ok := auth.UserFromContext(ctx); ok {
+		actor = &u.Id
+	}
+	dt, err := time.Parse("01-02-2006", e.Date)
+	if err != nil {
+		return fmt.Errorf("failed to parse date of expense id=%v, %w", e.Id, err)
+	}
+	var cats, tags []string
+	for _, c := range e.Categories {
+		cats = append(cats, c.Name)
+	}
+	for _, t := range e.Tags {
+		tags = append(tags, t.Name)
+	}
+	now := time.Now().UTC()
+	err = db.inTx(ctx, func(tx pgx.Tx) error {
+		dids, err := descriptionIds(ctx, tx, lid, now, []string{e.Description})
+		if err != nil {
+			return err
+		}
+		cids, err := categoryIds(ctx, tx, lid, actor, now, cats)
+		if err != nil {
+			return err
+		}
+		tids, err := tagIds(ctx, tx, lid, now, tags)
+		if err != nil {
+			return err
+		}
+		ok, err := updateExpenseTx(ctx, tx, model.AuditActionUpdate, e.Id, expenseUpdate, e.Id, dt, dids[e.Description], e.Amount, e.Comment, now, lid)
+		if err != nil {
+			return fmt.Errorf("failed to update expense, %w", err)
+		}
+		if !ok {
+			return notFound("expense id=%v does not exist", e.Id)
+		}
+		if err := deleteTx(ctx, tx, model.AuditEntityExpenseCategory, e.Id, expenseCategoryDelete, e.Id, lid); err != nil {
+			return fmt.Errorf("failed to delete expense_category rows, %w", err)
+		}
+		if err := deleteTx(ctx, tx, model.AuditEntityExpenseTag, e.Id, expenseTagDelete, e.Id, lid); err != nil {
+			return fmt.Errorf("failed to delete expense_tag rows, %w", err)
+		}
+		var ceids, ccids, teids, ttids []int
+		for _, c := range cats {
+			ceids, ccids = append(ceids, e.Id), append(ccids, cids[c])
+		}
+		for _, t := range tags {
+			teids, ttids = append(teids, e.Id), append(ttids, tids[t])
+		}
+		if err := insertLinks(ctx, tx, model.AuditEntityExpenseCategory, "category_id", ceids, ccids, lid, actor, now); err != nil {
+			return err
+		}
+		if err := insertLinks(ctx, tx, model.AuditEntityExpenseTag, "tag_id", teids, ttids, lid, actor, now); err != nil {
+			return err
+		}
+		if r == nil {
+			return nil
+		}
+		if _, err := updateExpenseTx(ctx, tx, model.AuditActionUpdate, e.Id, reimbursableUpdate, e.Id, *r, model.ReimbursementStatusPending.String(), now, lid); err != nil {
+			return fmt.Errorf("failed to set reimbursable, %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to replace expense id=%v, %w", e.Id, err)
+	}
+	return nil
+}
+
+// descriptionIds returns the ids of the ledger's descriptions by text,
+// creating missing ones as CreateDescription would.
+func descriptionIds(ctx context.Context, tx pgx.Tx, lid int, now time.Time, descs []string) (map[string]int, error) {
+	ids, err := lookupIds(ctx, tx, `SELECT id, description FROM financeview.description WHERE ledger_id=$1 AND description = ANY($2)`, lid, descs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query description table, %w", err)
+	}
+	err = createMissing(ctx, tx, ids, descs, func(b *pgx.Batch, d string) {
+		b.Queue(descriptionInsert, d, now, lid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert new descriptions, %w", err)
+	}
+	return ids, nil
+}
+
+// categoryIds returns the ids of the ledger's categories by name, creating
+// and auditing missing ones as CreateCategory would.
+func categoryIds(ctx context.Context, tx pgx.Tx, lid int, actor *int, now time.Time, cats []string) (map[string]int, error) {
+	ids, err := lookupIds(ctx, tx, `SELECT id, name FROM financeview.category WHERE ledger_id=$1 AND name = ANY($2)`, lid, cats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query database for categories, %w", err)
+	}
+	err = createMissing(ctx, tx, ids, cats, func(b *pgx.Batch, c string) {
+		sql := `
+			WITH created AS (
+				INSERT INTO financeview.category AS c (ledger_id, name, createdate)
+				VALUES ($1, $2, $3)
+				RETURNING c.id, row_to_json(c) AS row
+			), audited AS (
+				INSERT INTO financeview.audit_log (ledger_id, actor_id, entity, entity_id, action, after, createdate)
+				SELECT $1, $4::int, $5::text, id, $6::text, row, $3 FROM created
+			)
+			SELECT id FROM created
+		`
+		b.Queue(sql, lid, c, now, actor, string(model.AuditEntityCategory), string(model.AuditActionCreate))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert new categories, %w", err)
+	}
+	return ids, nil
+}
+
+// tagIds returns the ids of the ledger's tags by name, creating missing ones
+// as CreateTag would.
+func tagIds(ctx context.Context, tx pgx.Tx, lid int, now time.Time, tags []string) (map[string]int, error) {
+	ids, err := lookupIds(ctx, tx, `SELECT id, name FROM financeview.tag WHERE ledger_id=$1 AND name = ANY($2)`, lid, tags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query database for tags, %w", err)
+	}
+	err = createMissing(ctx, tx, ids, tags, func(b *pgx.Batch, t string) {
+		b.Queue(`INSERT INTO financeview.tag (ledger_id, name, createdate) VALUES ($1, $2, $3) RETURNING id`, lid, t, now)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert new tags, %w", err)
+	}
+	return ids, nil
+}
+
+// insertLinks links each expense in eids to the category or tag at the same
+// index of ids, auditing each link as the link methods would. col is the
+// link table's column of the category or tag id.
+func insertLinks(ctx context.Context, tx pgx.Tx, entity model.AuditEntity, col string, eids []int, ids []int, lid int, actor *int, now time.Time) error {
+	if len(eids) == 0 {
+		return nil
+	}
+	sql := fmt.Sprintf(`
+		WITH linked AS (
+			INSERT INTO financeview.%s AS l (expense_id, %s, createdate)
+			SELECT u.expense_id, u.id, $3 FROM unnest($1::int[], $2::int[]) AS u(expense_id, id)
+			RETURNING l.id, l.expense_id, row_to_json(l) AS row
+		)
+		INSERT INTO financeview.audit_log (ledger_id, actor_id, entity, entity_id, expense_id, action, after, createdate)
+		SELECT $4, $5::int, $6::text, id, expense_id, $7::text, row, $3 FROM linked
+	`, auditTables[entity], col)
+	if _, err := tx.Exec(ctx, sql, eids, ids, now, lid, actor, string(entity), string(model.AuditActionCreate)); err != nil {
+		return fmt.Errorf("failed to insert new %s rows, %w", auditTables[entity], err)
+	}
+	return nil
+}
+
+// lookupIds runs a query of the id and name of the rows in a ledger with
+// one of the names, returning the ids by name.
+func lookupIds(ctx context.Context, tx pgx.Tx, sql string, lid int, names []string) (map[string]int, error) {
+	ids := make(map[string]int)
+	if len(names) == 0 {
+		return ids, nil
+	}
+	rows, err := tx.Query(ctx, sql, lid, names)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var n string
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, err
+		}
+		ids[n] = id
+	}
+	return ids, rows.Err()
+}
+
+// createMissing queues an insert returning the new id for every name not in
+// ids, adding the ids of the created rows.
+func createMissing(ctx context.Context, tx pgx.Tx, ids map[string]int, names []string, insert func(*pgx.Batch, string)) error {
+	b := &pgx.Batch{}
+	var missing []string
+	for _, n := range names {
+		if _, ok := ids[n]; ok {
+			continue
+		}
+		ids[n] = 0
+		missing = append(missing, n)
+		insert(b, n)
+	}
+	return sendBatch(ctx, tx, b, func(i int, row pgx.Row) error {
+		var id int
+		if err := row.Scan(&id); err != nil {
+			return err
+		}
+		ids[missing[i]] = id
+		return nil
+	})
+}
+
+// sendBatch sends a batch of statements returning a row each, calling scan
+// with every statement's index and row.
+func sendBatch(ctx context.Context, tx pgx.Tx, b *pgx.Batch, scan func(int, pgx.Row) error) error {
+	if b.Len() == 0 {
+		return nil
+	}
+	br := tx.SendBatch(ctx, b)
+	for i := 0; i < b.Len(); i++ {
+		if err := scan(i, br.QueryRow()); err != nil {
+			br.Close()
+			return err
+		}
+	}
+	return br.Close()
+}
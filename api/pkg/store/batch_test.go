@@ -0,0 +1,148 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+func TestCreateExpenses(t *testing.T) {
+	ctx := testCtx
+	db := Database{conn}
+	defer func() {
+		if err := cleanUpDb(); err != nil {
+			t.Fatalf("failed to clean up test data, %v", err)
+		}
+	}()
+	food, err := db.CreateCategory(ctx, "food")
+	if err != nil {
+		t.Fatalf("failed to setup test data, %v", err)
+	}
+	lunch, err := db.CreateDescription(ctx, "lunch")
+	if err != nil {
+		t.Fatalf("failed to setup test data, %v", err)
+	}
+	input := []model.Expense{
+		{
+			Date:        "03-01-2022",
+			Description: "lunch",
+			Amount:      12.5,
+			Categories:  []model.Category{{Name: "food"}, {Name: "travel"}},
+			Tags:        []model.Tag{{Name: "trip"}},
+		},
+		{
+			Date:         "03-02-2022",
+			Description:  "train",
+			Amount:       40,
+			Categories:   []model.Category{{Name: "travel"}},
+			Comment:      "return ticket",
+			Reimbursable: true,
+		},
+		{
+			Date:        "03-02-2022",
+			Description: "lunch",
+			Amount:      9,
+			Categories:  []model.Category{{Name: "food"}},
+			Tags:        []model.Tag{{Name: "trip"}},
+		},
+	}
+	saved, err := db.CreateExpenses(ctx, input)
+	if err != nil {
+		t.Fatalf("error running CreateExpenses func, %v", err)
+	}
+	assert.Len(t, saved, 3)
+	travel, ok, err := db.GetCategoryId(ctx, "travel")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	trip, ok, err := db.GetTagId(ctx, "trip")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, []model.Category{{Id: food, Name: "food"}, {Id: travel, Name: "travel"}}, saved[0].Categories)
+	assert.Equal(t, []model.Tag{{Id: trip, Name: "trip"}}, saved[2].Tags)
+	assert.Equal(t, 0, input[0].Categories[0].Id, "the input is left alone")
+	for i, e := range saved {
+		got, ok, err := db.GetExpense(ctx, e.Id)
+		if err != nil || !ok {
+			t.Fatalf("failed to get expense %d, %v", i, err)
+		}
+		assert.Equal(t, e.Description, got.Description)
+		assert.Equal(t, e.Amount, got.Amount)
+		assert.Equal(t, e.Date, got.Date)
+		assert.Equal(t, e.Comment, got.Comment)
+		assert.Equal(t, e.Reimbursable, got.Reimbursable)
+	}
+	did, ok, err := db.GetDescriptionId(ctx, "lunch")
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, lunch, did, "existing descriptions are reused")
+
+	history, err := db.ExpenseHistory(ctx, saved[0].Id)
+	if err != nil {
+		t.Fatalf("error running ExpenseHistory func, %v", err)
+	}
+	var entities []model.AuditEntity
+	for _, h := range history {
+		entities = append(entities, h.Entity)
+	}
+	assert.Equal(t, []model.AuditEntity{model.AuditEntityExpense, model.AuditEntityExpenseCategory, model.AuditEntityExpenseCategory, model.AuditEntityExpenseTag}, entities)
+
+	_, err = db.CreateExpenses(ctx, []model.Expense{{Date: "03-03-2022", Description: "dinner", Amount: 20}, {Date: "2022-03-03", Description: "dinner", Amount: 20}})
+	assert.NotNil(t, err)
+	_, ok, err = db.GetDescriptionId(ctx, "dinner")
+	assert.Nil(t, err)
+	assert.False(t, ok, "a batch with a bad date saves nothing")
+}
+
+func TestReplaceExpense(t *testing.T) {
+	ctx := testCtx
+	db := Database{conn}
+	defer func() {
+		if err := cleanUpDb(); err != nil {
+			t.Fatalf("failed to clean up test data, %v", err)
+		}
+	}()
+	saved, err := db.CreateExpenses(ctx, []model.Expense{{
+		Date:        "03-01-2022",
+		Description: "lunch",
+		Amount:      12.5,
+		Categories:  []model.Category{{Name: "food"}},
+		Tags:        []model.Tag{{Name: "trip"}},
+	}})
+	if err != nil {
+		t.Fatalf("failed to setup test data, %v", err)
+	}
+	e := saved[0]
+	e.Description, e.Amount = "dinner", 30
+	e.Categories = []model.Category{{Name: "restaurants"}}
+	e.Tags = nil
+	reimbursable := true
+	if err := db.ReplaceExpense(ctx, e, &reimbursable); err != nil {
+		t.Fatalf("error running ReplaceExpense func, %v", err)
+	}
+	actual, ok, err := db.GetExpense(ctx, e.Id)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "dinner", actual.Description)
+	assert.Equal(t, 30.0, actual.Amount)
+	if assert.Len(t, actual.Categories, 1) {
+		assert.Equal(t, "restaurants", actual.Categories[0].Name)
+	}
+	assert.Empty(t, actual.Tags)
+	assert.True(t, actual.Reimbursable)
+	hist, err := db.ExpenseHistory(ctx, e.Id)
+	assert.Nil(t, err)
+	var got []string
+	for _, h := range hist[3:] {
+		got = append(got, string(h.Entity)+" "+string(h.Action))
+	}
+	assert.Equal(t, []string{"EXPENSE UPDATE", "EXPENSE_CATEGORY DELETE", "EXPENSE_TAG DELETE", "EXPENSE_CATEGORY CREATE", "EXPENSE UPDATE"}, got)
+
+	e.Id = 0
+	e.Categories = []model.Category{{Name: "never saved"}}
+	var nf *NotFoundError
+	assert.ErrorAs(t, db.ReplaceExpense(ctx, e, nil), &nf)
+	_, ok, err = db.GetCategoryId(ctx, "never saved")
+	assert.Nil(t, err)
+	assert.False(t, ok, "a failed replace is rolled back")
+}
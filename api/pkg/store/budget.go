@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+// SetBudget sets the monthly budget of the named category, replacing any it
+// had, in a transaction with its audit entry.
+func (db *Database) SetBudget(ctx context.Context, category string, amt float64) (model.Budget, error) {
+	ctx = named(ctx, "SetBudget")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return model.Budget{}, err
+	}
+	sql := `
+		INSERT INTO financeview.budget AS b (ledger_id, category_id, amount, createdate)
+		SELECT c.ledger_id, c.id, $2, $3
+		FROM financeview.category AS c
+		WHERE c.name=$1 AND c.ledger_id=$4
+		ON CONFLICT (category_id) DO UPDATE SET amount=EXCLUDED.amount, updatedate=EXCLUDED.createdate
+		RETURNING b.id
+	`
+	var id int
+	err = db.inTx(ctx, func(tx pgx.Tx) error {
+		c := auditChange{Entity: model.AuditEntityBudget, Action: model.AuditActionCreate}
+		existing := `
+			SELECT b.id FROM financeview.budget AS b
+			INNER JOIN financeview.category AS c ON b.category_id = c.id
+			WHERE c.name=$1 AND b.ledger_id=$2
+		`
+		var bid int
+		if err := tx.QueryRow(ctx, existing, category, lid).Scan(&bid); err != nil && err != pgx.ErrNoRows {
+			return fmt.Errorf("failed to get budget of category %q, %w", category, err)
+		}
+		if bid != 0 {
+			before, err := snapshot(ctx, tx, model.AuditEntityBudget, bid)
+			if err != nil {
+				return err
+			}
+			c.Action, c.Before = model.AuditActionUpdate, before
+		}
+		if err := tx.QueryRow(ctx, sql, category, amt, time.Now().UTC(), lid).Scan(&id); err != nil {
+			if err == pgx.ErrNoRows {
+				return notFound("category %q does not exist", category)
+			}
+			return fmt.Errorf("failed to set budget of category %q, %w", category, err)
+		}
+		after, err := snapshot(ctx, tx, model.AuditEntityBudget, id)
+		if err != nil {
+			return err
+		}
+		c.EntityId, c.After = id, after
+		return audit(ctx, tx, c)
+	})
+	if err != nil {
+		return model.Budget{}, err
+	}
+	bs, err := db.queryBudgets(ctx, `WHERE b.id = $1`, id)
+	if err != nil {
+		return model.Budget{}, err
+	}
+	if len(bs) == 0 {
+		return model.Budget{}, notFound("budget id=%v does not exist", id)
+	}
+	return bs[0], nil
+}
+
+func (db *Database) DeleteBudget(ctx context.Context, category string) error {
+	ctx = named(ctx, "DeleteBudget")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return err
+	}
+	sql := `
+		DELETE FROM financeview.budget AS b
+		USING financeview.category AS c
+		WHERE b.category_id = c.id AND c.name=$1 AND b.ledger_id=$2
+		RETURNING b.id, row_to_json(b)
+	`
+	return db.inTx(ctx, func(tx pgx.Tx) error {
+		c := auditChange{Entity: model.AuditEntityBudget, Action: model.AuditActionDelete}
+		if err := tx.QueryRow(ctx, sql, category, lid).Scan(&c.EntityId, &c.Before); err != nil {
+			if err == pgx.ErrNoRows {
+				return notFound("category %q has no budget", category)
+			}
+			return fmt.Errorf("failed to delete budget of category %q, %w", category, err)
+		}
+		return audit(ctx, tx, c)
+	})
+}
+
+func (db *Database) ListBudgets(ctx context.Context) ([]model.Budget, error) {
+	ctx = named(ctx, "ListBudgets")
+	return db.queryBudgets(ctx, ``)
+}
+
+// queryBudgets selects the ledger's budgets by category name, filtered by
+// the optional where clause.
+func (db *Database) queryBudgets(ctx context.Context, where string, args ...interface{}) ([]model.Budget, error) {
+	where, args, err := scope(ctx, "b.ledger_id", where, args)
+	if err != nil {
+		return nil, err
+	}
+	sql := `
+		SELECT b.id, b.amount::numeric::float8, c.id, c.name, c.tax_category
+		FROM financeview.budget AS b
+		INNER JOIN financeview.category AS c
+		ON b.category_id = c.id
+		` + where + `
+		ORDER BY c.name, b.id
+	`
+	var bs []model.Budget
+	rows, err := db.Conn.Query(ctx, sql, args...)
+	if err != nil {
+		return bs, fmt.Errorf("failed to select budgets from database, %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var b Budget
+		var c Category
+		if err := rows.Scan(&b.Id, &b.Amount, &c.Id, &c.Name, &c.TaxCategory); err != nil {
+			return bs, fmt.Errorf("failed to scan budgets from database, %w", err)
+		}
+		bs = append(bs, model.Budget{
+			Id:     int(b.Id.Int),
+			Amount: b.Amount.Float,
+			Category: model.Category{
+				Id:          int(c.Id.Int),
+				Name:        c.Name.String,
+				TaxCategory: taxCategory(c.TaxCategory),
+			},
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return bs, fmt.Errorf("failed to read budgets from database, %w", err)
+	}
+	return bs, nil
+}
+
+type Budget struct {
+	Id     pgtype.Int4
+	Amount pgtype.Float8
+}
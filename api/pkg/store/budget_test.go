@@ -0,0 +1,68 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+func TestBudgets(t *testing.T) {
+	ctx := testCtx
+	db := Database{conn}
+	defer func() {
+		if err := cleanUpDb(); err != nil {
+			t.Fatalf("failed to clean up test data, %v", err)
+		}
+	}()
+	cid, err := db.CreateCategory(ctx, "restaurants")
+	if err != nil {
+		t.Fatalf("failed to setup test data, %v", err)
+	}
+	b, err := db.SetBudget(ctx, "restaurants", 200)
+	if err != nil {
+		t.Fatalf("error running SetBudget func, %v", err)
+	}
+	want := model.Budget{Id: b.Id, Category: model.Category{Id: cid, Name: "restaurants"}, Amount: 200}
+	assert.Equal(t, want, b)
+
+	b, err = db.SetBudget(ctx, "restaurants", 150.5)
+	assert.Nil(t, err)
+	want.Amount = 150.5
+	assert.Equal(t, want, b, "setting a budget again replaces it")
+	bs, err := db.ListBudgets(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []model.Budget{want}, bs)
+
+	var nf *NotFoundError
+	_, err = db.SetBudget(ctx, "travel", 100)
+	assert.True(t, errors.As(err, &nf))
+	if err := db.DeleteBudget(ctx, "restaurants"); err != nil {
+		t.Fatalf("error running DeleteBudget func, %v", err)
+	}
+	assert.True(t, errors.As(db.DeleteBudget(ctx, "restaurants"), &nf))
+
+	ent := model.AuditEntityBudget
+	es, err := db.ListAuditEntries(ctx, model.AuditFilter{Entity: &ent}, 10)
+	if err != nil {
+		t.Fatalf("error running ListAuditEntries func, %v", err)
+	}
+	if assert.Len(t, es, 3) {
+		assert.Equal(t, []model.AuditAction{model.AuditActionDelete, model.AuditActionUpdate, model.AuditActionCreate},
+			[]model.AuditAction{es[0].Action, es[1].Action, es[2].Action})
+		assert.Equal(t, b.Id, es[1].EntityId)
+		var before, after map[string]interface{}
+		if err := json.Unmarshal([]byte(*es[1].Before), &before); err != nil {
+			t.Fatalf("failed to decode audit before, %v", err)
+		}
+		if err := json.Unmarshal([]byte(*es[1].After), &after); err != nil {
+			t.Fatalf("failed to decode audit after, %v", err)
+		}
+		assert.Equal(t, "$200.00", before["amount"])
+		assert.Equal(t, "$150.50", after["amount"])
+		assert.Nil(t, es[0].After)
+		assert.Nil(t, es[2].Before)
+	}
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/vapor05/financeview/pkg/ledger"
+)
+
+// ErrInternal is matched by errors from the database or its driver, such
+// as a lost connection or a failed statement. Their details are for logs,
+// not clients.
+var ErrInternal = errors.New("internal database error")
+
+// NotFoundError is returned for a record that isn't in the ledger.
+type NotFoundError struct {
+	Msg string
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Msg
+}
+
+func notFound(format string, args ...interface{}) error {
+	return &NotFoundError{Msg: fmt.Sprintf(format, args...)}
+}
+
+// ConflictError is returned for a change breaking a unique or foreign key
+// constraint, such as adding a record that already exists.
+type ConflictError struct {
+	Constraint string
+	Err        error
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("conflicts with existing data, %v", e.Err)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return e.Err
+}
+
+type internalError struct {
+	err error
+}
+
+func (e *internalError) Error() string {
+	return e.err.Error()
+}
+
+func (e *internalError) Unwrap() error {
+	return e.err
+}
+
+func (e *internalError) Is(target error) bool {
+	return target == ErrInternal
+}
+
+// dbError types an error the database returned. Constraint violations
+// become a ConflictError and anything else matches ErrInternal. No rows, no
+// selected ledger and errors that are already typed are left as they are.
+func dbError(err error) error {
+	if err == nil || err == pgx.ErrNoRows {
+		return err
+	}
+	var nf *NotFoundError
+	var ce *ConflictError
+	if errors.As(err, &nf) || errors.As(err, &ce) || errors.Is(err, ErrInternal) || errors.Is(err, ledger.ErrNoLedger) {
+		return err
+	}
+	var pe *pgconn.PgError
+	if errors.As(err, &pe) && (pe.Code == "23505" || pe.Code == "23503") {
+		return &ConflictError{Constraint: pe.ConstraintName, Err: err}
+	}
+	return &internalError{err}
+}
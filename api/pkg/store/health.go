@@ -0,0 +1,28 @@
+package store
+
+import (
+	"context"
+	"fmt"
+)
+
+// SchemaVersion is the version of sql/ddl/create_schema.sql this code
+// expects. Older databases are brought up to it by running the scripts in
+// sql/migrations in order, starting with 001 for databases from before
+// schema versions.
+const SchemaVersion = 5
+
+// Ready checks that the database is reachable and its schema is current.
+func (db *Database) Ready(ctx context.Context) error {
+	ctx = named(ctx, "Ready")
+	if err := db.Conn.Ping(ctx); err != nil {
+		return fmt.Errorf("failed to reach database, %w", err)
+	}
+	var v int
+	if err := db.Conn.QueryRow(ctx, `SELECT max(version) FROM financeview.schema_version`).Scan(&v); err != nil {
+		return fmt.Errorf("failed to get schema version, %w", err)
+	}
+	if v != SchemaVersion {
+		return fmt.Errorf("database schema is version %d, expected %d", v, SchemaVersion)
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/ledger"
+)
+
+// ledgerId is the id of the selected ledger every query on ledger data is
+// scoped to.
+func ledgerId(ctx context.Context) (int, error) {
+	lid, err := ledger.Id(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to scope query to a ledger, %w", err)
+	}
+	return lid, nil
+}
+
+// scope adds a condition on the ledger id column to an optional WHERE
+// clause, passing the ledger id as the next argument.
+func scope(ctx context.Context, col string, where string, args []interface{}) (string, []interface{}, error) {
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+	args = append(args, lid)
+	cond := fmt.Sprintf("%s = $%d", col, len(args))
+	if where == "" {
+		return "WHERE " + cond, args, nil
+	}
+	return where + " AND " + cond, args, nil
+}
+
+// CreateLedger inserts a new ledger owned by the user.
+func (db *Database) CreateLedger(ctx context.Context, name string, uid int) (int, error) {
+	ctx = named(ctx, "CreateLedger")
+	var id int
+	err := db.inTx(ctx, func(tx pgx.Tx) (err error) {
+		id, err = createLedger(ctx, tx, name, uid)
+		return err
+	})
+	return id, err
+}
+
+func createLedger(ctx context.Context, tx pgx.Tx, name string, uid int) (int, error) {
+	now := time.Now().UTC()
+	sql := `INSERT INTO financeview.ledger (name, createdate) VALUES ($1, $2) RETURNING id`
+	var id int
+	if err := tx.QueryRow(ctx, sql, name, now).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to insert new ledger into database, %w", err)
+	}
+	sql = `INSERT INTO financeview.ledger_member (ledger_id, user_id, role, createdate) VALUES ($1, $2, $3, $4)`
+	if _, err := tx.Exec(ctx, sql, id, uid, string(model.RoleOwner), now); err != nil {
+		return 0, fmt.Errorf("failed to insert ledger owner into database, %w", err)
+	}
+	return id, nil
+}
+
+// ListLedgers returns every ledger the user is a member of, oldest first.
+func (db *Database) ListLedgers(ctx context.Context, uid int) ([]model.Ledger, error) {
+	ctx = named(ctx, "ListLedgers")
+	sql := `
+		SELECT l.id, l.name, m.role
+		FROM financeview.ledger AS l
+		INNER JOIN financeview.ledger_member AS m
+		ON l.id = m.ledger_id
+		WHERE m.user_id = $1
+		ORDER BY l.id
+	`
+	var ls []model.Ledger
+	rows, err := db.Conn.Query(ctx, sql, uid)
+	if err != nil {
+		return ls, fmt.Errorf("failed to select ledgers from database, %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var l Ledger
+		if err := rows.Scan(&l.Id, &l.Name, &l.Role); err != nil {
+			return ls, fmt.Errorf("failed to scan ledgers from database, %w", err)
+		}
+		ls = append(ls, model.Ledger{Id: int(l.Id.Int), Name: l.Name.String, Role: model.Role(l.Role.String)})
+	}
+	if err := rows.Err(); err != nil {
+		return ls, fmt.Errorf("failed to read ledgers from database, %w", err)
+	}
+	return ls, nil
+}
+
+// GetLedgerRole returns the user's role in a ledger, if they are a member.
+func (db *Database) GetLedgerRole(ctx context.Context, lid int, uid int) (model.Role, bool, error) {
+	ctx = named(ctx, "GetLedgerRole")
+	sql := `SELECT role FROM financeview.ledger_member WHERE ledger_id=$1 AND user_id=$2`
+	var role string
+	if err := db.Conn.QueryRow(ctx, sql, lid, uid).Scan(&role); err != nil {
+		if err == pgx.ErrNoRows {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("failed to query ledger_member table, %w", err)
+	}
+	return model.Role(role), true, nil
+}
+
+func (db *Database) ListMembers(ctx context.Context, lid int) ([]model.LedgerMember, error) {
+	ctx = named(ctx, "ListMembers")
+	sql := `
+		SELECT u.id, u.email, m.role
+		FROM financeview.ledger_member AS m
+		INNER JOIN financeview.app_user AS u
+		ON m.user_id = u.id
+		WHERE m.ledger_id = $1
+		ORDER BY m.id
+	`
+	var ms []model.LedgerMember
+	rows, err := db.Conn.Query(ctx, sql, lid)
+	if err != nil {
+		return ms, fmt.Errorf("failed to select ledger members from database, %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var u User
+		var role pgtype.Text
+		if err := rows.Scan(&u.Id, &u.Email, &role); err != nil {
+			return ms, fmt.Errorf("failed to scan ledger members from database, %w", err)
+		}
+		ms = append(ms, model.LedgerMember{
+			User: model.User{Id: int(u.Id.Int), Email: u.Email.String},
+			Role: model.Role(role.String),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return ms, fmt.Errorf("failed to read ledger members from database, %w", err)
+	}
+	return ms, nil
+}
+
+func (db *Database) SetMemberRole(ctx context.Context, lid int, uid int, role model.Role) error {
+	ctx = named(ctx, "SetMemberRole")
+	sql := `UPDATE financeview.ledger_member SET role=$3, updatedate=$4 WHERE ledger_id=$1 AND user_id=$2`
+	tag, err := db.Conn.Exec(ctx, sql, lid, uid, string(role), time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to set role of user id=%v, %w", uid, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return notFound("user id=%v is not a member of ledger id=%v", uid, lid)
+	}
+	return nil
+}
+
+func (db *Database) DeleteMember(ctx context.Context, lid int, uid int) error {
+	ctx = named(ctx, "DeleteMember")
+	sql := `DELETE FROM financeview.ledger_member WHERE ledger_id=$1 AND user_id=$2`
+	tag, err := db.Conn.Exec(ctx, sql, lid, uid)
+	if err != nil {
+		return fmt.Errorf("failed to remove user id=%v from ledger, %w", uid, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return notFound("user id=%v is not a member of ledger id=%v", uid, lid)
+	}
+	return nil
+}
+
+func (db *Database) CreateInvitation(ctx context.Context, inv model.Invitation, tokenHash string, invitedBy int, expires time.Time) (int, error) {
+	ctx = named(ctx, "CreateInvitation")
+	sql := `
+		INSERT INTO financeview.ledger_invitation (ledger_id, email, role, token_hash, invited_by, expiresat, createdate)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+	var id int
+	if err := db.Conn.QueryRow(ctx, sql, inv.LedgerId, inv.Email, string(inv.Role), tokenHash, invitedBy, expires, time.Now().UTC()).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to insert new ledger_invitation into database, %w", err)
+	}
+	return id, nil
+}
+
+// GetInvitation returns the invitation a token was issued for, if it has
+// neither been accepted nor expired by now.
+func (db *Database) GetInvitation(ctx context.Context, tokenHash string, now time.Time) (model.Invitation, bool, error) {
+	ctx = named(ctx, "GetInvitation")
+	is, err := db.queryInvitations(ctx, `WHERE token_hash = $1 AND expiresat > $2`, tokenHash, now)
+	if err != nil {
+		return model.Invitation{}, false, err
+	}
+	if len(is) == 0 {
+		return model.Invitation{}, false, nil
+	}
+	return is[0], true, nil
+}
+
+// ListInvitations returns a ledger's invitations still open at now.
+func (db *Database) ListInvitations(ctx context.Context, lid int, now time.Time) ([]model.Invitation, error) {
+	ctx = named(ctx, "ListInvitations")
+	return db.queryInvitations(ctx, `WHERE ledger_id = $1 AND expiresat > $2`, lid, now)
+}
+
+// queryInvitations selects unaccepted invitations matching the where clause.
+func (db *Database) queryInvitations(ctx context.Context, where string, args ...interface{}) ([]model.Invitation, error) {
+	sql := `
+		SELECT id, ledger_id, email, role, expiresat
+		FROM financeview.ledger_invitation
+		` + where + ` AND acceptedat IS NULL
+		ORDER BY id
+	`
+	var is []model.Invitation
+	rows, err := db.Conn.Query(ctx, sql, args...)
+	if err != nil {
+		return is, fmt.Errorf("failed to select invitations from database, %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var i Invitation
+		if err := rows.Scan(&i.Id, &i.LedgerId, &i.Email, &i.Role, &i.ExpiresAt); err != nil {
+			return is, fmt.Errorf("failed to scan invitations from database, %w", err)
+		}
+		is = append(is, model.Invitation{
+			Id:        int(i.Id.Int),
+			LedgerId:  int(i.LedgerId.Int),
+			Email:     i.Email.String,
+			Role:      model.Role(i.Role.String),
+			ExpiresAt: i.ExpiresAt.Time.Format(time.RFC3339),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return is, fmt.Errorf("failed to read invitations from database, %w", err)
+	}
+	return is, nil
+}
+
+// AcceptInvitation marks an invitation accepted and adds the user to its
+// ledger with the invited role, unless they are already a member.
+func (db *Database) AcceptInvitation(ctx context.Context, inv model.Invitation, uid int) error {
+	ctx = named(ctx, "AcceptInvitation")
+	return db.inTx(ctx, func(tx pgx.Tx) error {
+		now := time.Now().UTC()
+		sql := `UPDATE financeview.ledger_invitation SET acceptedat=$2 WHERE id=$1 AND acceptedat IS NULL`
+		tag, err := tx.Exec(ctx, sql, inv.Id, now)
+		if err != nil {
+			return fmt.Errorf("failed to accept invitation id=%v, %w", inv.Id, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return &ConflictError{Err: fmt.Errorf("invitation id=%v was already accepted", inv.Id)}
+		}
+		sql = `
+			INSERT INTO financeview.ledger_member (ledger_id, user_id, role, createdate)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (ledger_id, user_id) DO NOTHING
+		`
+		if _, err := tx.Exec(ctx, sql, inv.LedgerId, uid, string(inv.Role), now); err != nil {
+			return fmt.Errorf("failed to insert ledger member into database, %w", err)
+		}
+		return nil
+	})
+}
+
+func (db *Database) DeleteInvitation(ctx context.Context, lid int, id int) error {
+	ctx = named(ctx, "DeleteInvitation")
+	sql := `DELETE FROM financeview.ledger_invitation WHERE id=$1 AND ledger_id=$2 AND acceptedat IS NULL`
+	tag, err := db.Conn.Exec(ctx, sql, id, lid)
+	if err != nil {
+		return fmt.Errorf("failed to delete invitation id=%v, %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return notFound("invitation id=%v does not exist", id)
+	}
+	return nil
+}
+
+type Ledger struct {
+	Id   pgtype.Int4
+	Name pgtype.Text
+	Role pgtype.Text
+}
+
+type Invitation struct {
+	Id        pgtype.Int4
+	LedgerId  pgtype.Int4
+	Email     pgtype.Text
+	Role      pgtype.Text
+	ExpiresAt pgtype.Timestamp
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/ledger"
+)
+
+func TestLedgerInvitation(t *testing.T) {
+	ctx := testCtx
+	db := Database{conn}
+	defer func() {
+		if err := cleanUpDb(); err != nil {
+			t.Fatalf("failed to clean up test data, %v", err)
+		}
+	}()
+	owner, err := db.CreateUser(ctx, "owner@example.com", "hash")
+	if err != nil {
+		t.Fatalf("failed to setup test data, %v", err)
+	}
+	guest, err := db.CreateUser(ctx, "guest@example.com", "hash")
+	if err != nil {
+		t.Fatalf("failed to setup test data, %v", err)
+	}
+	lid, err := db.CreateLedger(ctx, "Household", owner)
+	if err != nil {
+		t.Fatalf("error running CreateLedger func, %v", err)
+	}
+	role, ok, err := db.GetLedgerRole(ctx, lid, owner)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, model.RoleOwner, role)
+	_, ok, err = db.GetLedgerRole(ctx, lid, guest)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	now := time.Now().UTC()
+	inv := model.Invitation{LedgerId: lid, Email: "guest@example.com", Role: model.RoleEditor}
+	if _, err := db.CreateInvitation(ctx, inv, "tokenhash", owner, now.Add(time.Hour)); err != nil {
+		t.Fatalf("error running CreateInvitation func, %v", err)
+	}
+	_, ok, err = db.GetInvitation(ctx, "tokenhash", now.Add(2*time.Hour))
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	actual, ok, err := db.GetInvitation(ctx, "tokenhash", now)
+	if err != nil {
+		t.Fatalf("error running GetInvitation func, %v", err)
+	}
+	assert.True(t, ok)
+	if err := db.AcceptInvitation(ctx, actual, guest); err != nil {
+		t.Fatalf("error running AcceptInvitation func, %v", err)
+	}
+	assert.Error(t, db.AcceptInvitation(ctx, actual, guest))
+	_, ok, err = db.GetInvitation(ctx, "tokenhash", now)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	ms, err := db.ListMembers(ctx, lid)
+	if err != nil {
+		t.Fatalf("error running ListMembers func, %v", err)
+	}
+	want := []model.LedgerMember{
+		{User: model.User{Id: owner, Email: "owner@example.com"}, Role: model.RoleOwner},
+		{User: model.User{Id: guest, Email: "guest@example.com"}, Role: model.RoleEditor},
+	}
+	assert.Equal(t, want, ms)
+	ls, err := db.ListLedgers(ctx, guest)
+	if err != nil {
+		t.Fatalf("error running ListLedgers func, %v", err)
+	}
+	if assert.Len(t, ls, 2) {
+		assert.Equal(t, model.Ledger{Id: ls[0].Id, Name: ledger.DefaultName, Role: model.RoleOwner}, ls[0], "made when the guest registered")
+		assert.Equal(t, model.Ledger{Id: lid, Name: "Household", Role: model.RoleEditor}, ls[1])
+	}
+}
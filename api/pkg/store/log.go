@@ -0,0 +1,44 @@
+package store
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/vapor05/financeview/pkg/logging"
+)
+
+// pgxLogger sends pgx's log records to the context's logger, so a failed
+// query is logged with the id of the request that ran it. Query arguments
+// hold financial data and are only logged at debug level.
+type pgxLogger struct{}
+
+func (pgxLogger) Log(ctx context.Context, level pgx.LogLevel, msg string, data map[string]interface{}) {
+	l := logging.FromContext(ctx)
+	var sl slog.Level
+	switch level {
+	case pgx.LogLevelTrace, pgx.LogLevelDebug:
+		sl = slog.LevelDebug
+	case pgx.LogLevelInfo:
+		sl = slog.LevelInfo
+	case pgx.LogLevelWarn:
+		sl = slog.LevelWarn
+	default:
+		sl = slog.LevelError
+	}
+	if !l.Enabled(ctx, sl) {
+		return
+	}
+	debug := l.Enabled(ctx, slog.LevelDebug)
+	attrs := make([]slog.Attr, 0, len(data))
+	for k, v := range data {
+		if k == "args" && !debug {
+			continue
+		}
+		if err, ok := v.(error); ok {
+			v = err.Error()
+		}
+		attrs = append(attrs, slog.Any(k, v))
+	}
+	l.LogAttrs(ctx, sl, "pgx: "+msg, attrs...)
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Notify sends a payload to every connection listening on a channel.
+func (db *Database) Notify(ctx context.Context, channel string, payload string) error {
+	ctx = named(ctx, "Notify")
+	if _, err := db.Conn.Exec(ctx, `SELECT pg_notify($1, $2)`, channel, payload); err != nil {
+		return fmt.Errorf("failed to notify %s, %w", channel, err)
+	}
+	return nil
+}
+
+// Listen holds one of the pool's connections listening on a channel,
+// passing each payload to handle, until ctx is done or the connection
+// fails. The connection is closed afterwards rather than going back to the
+// pool still listening.
+func (db *Database) Listen(ctx context.Context, channel string, handle func(string)) error {
+	ctx = named(ctx, "Listen")
+	p := db.pool()
+	if p == nil {
+		return errors.New("failed to listen, not connected through a pool")
+	}
+	c, err := p.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire listening connection, %w", err)
+	}
+	defer func() {
+		c.Conn().Close(context.Background())
+		c.Release()
+	}()
+	if _, err := c.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return fmt.Errorf("failed to listen on %s, %w", channel, err)
+	}
+	for {
+		n, err := c.Conn().WaitForNotification(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to wait for notification on %s, %w", channel, err)
+		}
+		handle(n.Payload)
+	}
+}
@@ -0,0 +1,253 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+func (db *Database) CreatePayee(ctx context.Context, name string) (int, error) {
+	ctx = named(ctx, "CreatePayee")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sql := `INSERT INTO financeview.payee (ledger_id, name, createdate) VALUES ($1, $2, $3) RETURNING id`
+	var id int
+	if err := db.Conn.QueryRow(ctx, sql, lid, name, time.Now().UTC()).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to insert new payee into database, %w", err)
+	}
+	return id, nil
+}
+
+func (db *Database) GetPayeeId(ctx context.Context, name string) (int, bool, error) {
+	ctx = named(ctx, "GetPayeeId")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	sql := `SELECT id FROM financeview.payee WHERE name=$1 AND ledger_id=$2`
+	var id int
+	if err := db.Conn.QueryRow(ctx, sql, name, lid).Scan(&id); err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to query payee table, %w", err)
+	}
+	return id, true, nil
+}
+
+func (db *Database) GetPayee(ctx context.Context, id int) (model.Payee, bool, error) {
+	ctx = named(ctx, "GetPayee")
+	ps, err := db.queryPayees(ctx, `WHERE p.id = $1`, id)
+	if err != nil {
+		return model.Payee{}, false, err
+	}
+	if len(ps) == 0 {
+		return model.Payee{}, false, nil
+	}
+	return ps[0], true, nil
+}
+
+func (db *Database) ListPayees(ctx context.Context) ([]model.Payee, error) {
+	ctx = named(ctx, "ListPayees")
+	return db.queryPayees(ctx, ``)
+}
+
+// queryPayees selects the ledger's payees with their aliases in one query,
+// filtered by the optional where clause.
+func (db *Database) queryPayees(ctx context.Context, where string, args ...interface{}) ([]model.Payee, error) {
+	where, args, err := scope(ctx, "p.ledger_id", where, args)
+	if err != nil {
+		return nil, err
+	}
+	sql := `
+		SELECT p.id, p.name, a.id, a.pattern
+		FROM financeview.payee AS p
+		LEFT JOIN financeview.payee_alias AS a
+		ON p.id = a.payee_id
+		` + where + `
+		ORDER BY p.name, p.id, a.id
+	`
+	var ps []model.Payee
+	rows, err := db.Conn.Query(ctx, sql, args...)
+	if err != nil {
+		return ps, fmt.Errorf("failed to select payees from database, %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p Payee
+		var a PayeeAlias
+		if err := rows.Scan(&p.Id, &p.Name, &a.Id, &a.Pattern); err != nil {
+			return ps, fmt.Errorf("failed to scan payees from database, %w", err)
+		}
+		if len(ps) == 0 || ps[len(ps)-1].Id != int(p.Id.Int) {
+			ps = append(ps, model.Payee{Id: int(p.Id.Int), Name: p.Name.String})
+		}
+		if a.Id.Status == pgtype.Present {
+			last := &ps[len(ps)-1]
+			last.Aliases = append(last.Aliases, model.PayeeAlias{Id: int(a.Id.Int), Pattern: a.Pattern.String})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return ps, fmt.Errorf("failed to read payees from database, %w", err)
+	}
+	return ps, nil
+}
+
+func (db *Database) RenamePayee(ctx context.Context, id int, name string) error {
+	ctx = named(ctx, "RenamePayee")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return err
+	}
+	sql := `UPDATE financeview.payee SET name=$2, updatedate=$3 WHERE id=$1 AND ledger_id=$4`
+	tag, err := db.Conn.Exec(ctx, sql, id, name, time.Now().UTC(), lid)
+	if err != nil {
+		return fmt.Errorf("failed to rename payee id=%v, %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return notFound("payee id=%v does not exist", id)
+	}
+	return nil
+}
+
+// DeletePayee removes a payee and its aliases, leaving its descriptions
+// without a payee.
+func (db *Database) DeletePayee(ctx context.Context, id int) error {
+	ctx = named(ctx, "DeletePayee")
+	if err := db.ownsPayees(ctx, id); err != nil {
+		return err
+	}
+	return db.inTx(ctx, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `UPDATE financeview.description SET payee_id=NULL, updatedate=$2 WHERE payee_id=$1`, id, time.Now().UTC()); err != nil {
+			return fmt.Errorf("failed to unlink descriptions from payee id=%v, %w", id, err)
+		}
+		if _, err := tx.Exec(ctx, `DELETE FROM financeview.payee_alias WHERE payee_id=$1`, id); err != nil {
+			return fmt.Errorf("failed to delete aliases of payee id=%v, %w", id, err)
+		}
+		tag, err := tx.Exec(ctx, `DELETE FROM financeview.payee WHERE id=$1`, id)
+		if err != nil {
+			return fmt.Errorf("failed to delete payee id=%v, %w", id, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return notFound("payee id=%v does not exist", id)
+		}
+		return nil
+	})
+}
+
+func (db *Database) CreatePayeeAlias(ctx context.Context, pid int, pattern string) (int, error) {
+	ctx = named(ctx, "CreatePayeeAlias")
+	if err := db.ownsPayees(ctx, pid); err != nil {
+		return 0, err
+	}
+	sql := `INSERT INTO financeview.payee_alias (payee_id, pattern, createdate) VALUES ($1, $2, $3) RETURNING id`
+	var id int
+	if err := db.Conn.QueryRow(ctx, sql, pid, pattern, time.Now().UTC()).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to insert new payee_alias into database, %w", err)
+	}
+	return id, nil
+}
+
+func (db *Database) DeletePayeeAlias(ctx context.Context, id int) error {
+	ctx = named(ctx, "DeletePayeeAlias")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return err
+	}
+	sql := `
+		DELETE FROM financeview.payee_alias
+		WHERE id=$1 AND payee_id IN (SELECT id FROM financeview.payee WHERE ledger_id=$2)
+	`
+	tag, err := db.Conn.Exec(ctx, sql, id, lid)
+	if err != nil {
+		return fmt.Errorf("failed to delete payee_alias id=%v, %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return notFound("payee_alias id=%v does not exist", id)
+	}
+	return nil
+}
+
+// AssignDescriptionPayee links every one of the ledger's descriptions without
+// a payee that matches the alias pattern to the payee, returning how many
+// were linked.
+func (db *Database) AssignDescriptionPayee(ctx context.Context, pid int, pattern string) (int, error) {
+	ctx = named(ctx, "AssignDescriptionPayee")
+	if err := db.ownsPayees(ctx, pid); err != nil {
+		return 0, err
+	}
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sql := `
+		UPDATE financeview.description SET payee_id=$1, updatedate=$3
+		WHERE payee_id IS NULL AND description ILIKE $2 AND ledger_id=$4
+	`
+	tag, err := db.Conn.Exec(ctx, sql, pid, pattern, time.Now().UTC(), lid)
+	if err != nil {
+		return 0, fmt.Errorf("failed to assign descriptions to payee id=%v, %w", pid, err)
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+// MergePayees moves the source payee's aliases and descriptions to the
+// target payee and deletes the source.
+func (db *Database) MergePayees(ctx context.Context, src int, dst int) error {
+	ctx = named(ctx, "MergePayees")
+	if err := db.ownsPayees(ctx, src, dst); err != nil {
+		return err
+	}
+	return db.inTx(ctx, func(tx pgx.Tx) error {
+		now := time.Now().UTC()
+		if _, err := tx.Exec(ctx, `UPDATE financeview.description SET payee_id=$2, updatedate=$3 WHERE payee_id=$1`, src, dst, now); err != nil {
+			return fmt.Errorf("failed to move descriptions to payee id=%v, %w", dst, err)
+		}
+		if _, err := tx.Exec(ctx, `UPDATE financeview.payee_alias SET payee_id=$2 WHERE payee_id=$1`, src, dst); err != nil {
+			return fmt.Errorf("failed to move aliases to payee id=%v, %w", dst, err)
+		}
+		tag, err := tx.Exec(ctx, `DELETE FROM financeview.payee WHERE id=$1`, src)
+		if err != nil {
+			return fmt.Errorf("failed to delete merged payee id=%v, %w", src, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return notFound("payee id=%v does not exist", src)
+		}
+		return nil
+	})
+}
+
+// ownsPayees checks every payee id exists and belongs to the ledger.
+func (db *Database) ownsPayees(ctx context.Context, ids ...int) error {
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		sql := `SELECT EXISTS (SELECT 1 FROM financeview.payee WHERE id=$1 AND ledger_id=$2)`
+		var ok bool
+		if err := db.Conn.QueryRow(ctx, sql, id, lid).Scan(&ok); err != nil {
+			return fmt.Errorf("failed to query payee table, %w", err)
+		}
+		if !ok {
+			return notFound("payee id=%v does not exist", id)
+		}
+	}
+	return nil
+}
+
+type Payee struct {
+	Id   pgtype.Int4
+	Name pgtype.Text
+}
+
+type PayeeAlias struct {
+	Id      pgtype.Int4
+	Pattern pgtype.Text
+}
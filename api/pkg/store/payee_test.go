@@ -0,0 +1,120 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+func TestCreateDescriptionPayee(t *testing.T) {
+	ctx := testCtx
+	db := Database{conn}
+	defer func() {
+		if err := cleanUpDb(); err != nil {
+			t.Fatalf("failed to clean up test data, %v", err)
+		}
+	}()
+	pid, err := db.CreatePayee(ctx, "Amazon")
+	if err != nil {
+		t.Fatalf("error running CreatePayee func, %v", err)
+	}
+	if _, err := db.CreatePayeeAlias(ctx, pid, "amzn%"); err != nil {
+		t.Fatalf("error running CreatePayeeAlias func, %v", err)
+	}
+	did, err := db.CreateDescription(ctx, "AMZN Mktp US*2K3")
+	if err != nil {
+		t.Fatalf("error running CreateDescription func, %v", err)
+	}
+	var actual int
+	if err := conn.QueryRow(ctx, "select payee_id from financeview.description where id=$1", did).Scan(&actual); err != nil {
+		t.Fatalf("failed to get description payee from db, %v", err)
+	}
+	assert.Equal(t, pid, actual)
+}
+
+func TestMergePayees(t *testing.T) {
+	ctx := testCtx
+	db := Database{conn}
+	defer func() {
+		if err := cleanUpDb(); err != nil {
+			t.Fatalf("failed to clean up test data, %v", err)
+		}
+	}()
+	src, err := db.CreatePayee(ctx, "Amazon Marketplace")
+	if err != nil {
+		t.Fatalf("failed to setup test data, %v", err)
+	}
+	dst, err := db.CreatePayee(ctx, "Amazon")
+	if err != nil {
+		t.Fatalf("failed to setup test data, %v", err)
+	}
+	aid, err := db.CreatePayeeAlias(ctx, src, "amzn mktp%")
+	if err != nil {
+		t.Fatalf("failed to setup test data, %v", err)
+	}
+	if err := db.MergePayees(ctx, src, dst); err != nil {
+		t.Fatalf("error running MergePayees func, %v", err)
+	}
+	actual, err := db.ListPayees(ctx)
+	if err != nil {
+		t.Fatalf("error running ListPayees func, %v", err)
+	}
+	want := []model.Payee{
+		{Id: dst, Name: "Amazon", Aliases: []model.PayeeAlias{{Id: aid, Pattern: "amzn mktp%"}}},
+	}
+	assert.Equal(t, want, actual)
+	assert.Error(t, db.MergePayees(ctx, src, dst))
+}
+
+func TestSummarizeExpenses(t *testing.T) {
+	ctx := testCtx
+	db := Database{conn}
+	defer func() {
+		if err := cleanUpDb(); err != nil {
+			t.Fatalf("failed to clean up test data, %v", err)
+		}
+	}()
+	pid, err := db.CreatePayee(ctx, "Amazon")
+	if err != nil {
+		t.Fatalf("failed to setup test data, %v", err)
+	}
+	if _, err := db.CreatePayeeAlias(ctx, pid, "amzn%"); err != nil {
+		t.Fatalf("failed to setup test data, %v", err)
+	}
+	cid, err := db.CreateCategory(ctx, "shopping")
+	if err != nil {
+		t.Fatalf("failed to setup test data, %v", err)
+	}
+	dt := time.Date(2022, time.March, 1, 0, 0, 0, 0, time.UTC)
+	for _, e := range []struct {
+		desc string
+		amt  float64
+	}{{"AMZN Mktp US*2K3", 10.5}, {"amzn digital", 4.5}, {"Target", 20}} {
+		did, err := db.CreateDescription(ctx, e.desc)
+		if err != nil {
+			t.Fatalf("failed to setup test data, %v", err)
+		}
+		eid, err := db.CreateExpense(ctx, dt, did, e.amt, "")
+		if err != nil {
+			t.Fatalf("failed to setup test data, %v", err)
+		}
+		if _, err := db.LinkExpenseCategory(ctx, eid, cid); err != nil {
+			t.Fatalf("failed to setup test data, %v", err)
+		}
+	}
+	actual, err := db.SummarizeExpenses(ctx, model.SummaryGroupByPayee, model.ExpenseFilter{})
+	if err != nil {
+		t.Fatalf("error running SummarizeExpenses func, %v", err)
+	}
+	assert.Equal(t, []model.SummaryRow{
+		{Key: "Target", Total: 20, Count: 1},
+		{Key: "Amazon", Total: 15, Count: 2},
+	}, actual)
+	actual, err = db.SummarizeExpenses(ctx, model.SummaryGroupByCategory, model.ExpenseFilter{})
+	if err != nil {
+		t.Fatalf("error running SummarizeExpenses func, %v", err)
+	}
+	assert.Equal(t, []model.SummaryRow{{Key: "shopping", Total: 35, Count: 3}}, actual)
+}
@@ -0,0 +1,167 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+// reimbursableUpdate sets whether expense $1 of ledger $5 is reimbursable to
+// $2 at time $4. Newly reimbursable expenses get status $3, and expenses no
+// longer reimbursable lose their status and income.
+const reimbursableUpdate = `
+	UPDATE financeview.expense
+	SET reimbursable=$2,
+		reimbursement_status = CASE WHEN $2 THEN COALESCE(reimbursement_status, $3) ELSE NULL END,
+		reimbursement_income_id = CASE WHEN $2 THEN reimbursement_income_id ELSE NULL END,
+		updatedate=$4
+	WHERE id=$1 AND ledger_id=$5 AND deletedat IS NULL
+`
+
+// SetReimbursable marks whether an expense is owed back. Newly reimbursable
+// expenses start out pending, and clearing the flag drops any status.
+func (db *Database) SetReimbursable(ctx context.Context, eid int, r bool) error {
+	ctx = named(ctx, "SetReimbursable")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return err
+	}
+	ok, err := db.updateExpenseAudited(ctx, model.AuditActionUpdate, eid, reimbursableUpdate, eid, r, model.ReimbursementStatusPending.String(), time.Now().UTC(), lid)
+	if err != nil {
+		return fmt.Errorf("failed to set reimbursable on expense id=%v, %w", eid, err)
+	}
+	if !ok {
+		return notFound("expense id=%v does not exist", eid)
+	}
+	return nil
+}
+
+// SetReimbursementStatus moves a reimbursable expense to a status that has
+// no reimbursement income, unlinking any income it had.
+func (db *Database) SetReimbursementStatus(ctx context.Context, eid int, rs model.ReimbursementStatus) error {
+	ctx = named(ctx, "SetReimbursementStatus")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return err
+	}
+	sql := `
+		UPDATE financeview.expense
+		SET reimbursement_status=$2, reimbursement_income_id=NULL, updatedate=$3
+		WHERE id=$1 AND ledger_id=$4 AND deletedat IS NULL AND reimbursable
+	`
+	ok, err := db.updateExpenseAudited(ctx, model.AuditActionUpdate, eid, sql, eid, rs.String(), time.Now().UTC(), lid)
+	if err != nil {
+		return fmt.Errorf("failed to set reimbursement status on expense id=%v, %w", eid, err)
+	}
+	if !ok {
+		return notFound("expense id=%v does not exist or is not reimbursable", eid)
+	}
+	return nil
+}
+
+// LinkReimbursement links an income and a reimbursable expense that both
+// belong to the ledger.
+func (db *Database) LinkReimbursement(ctx context.Context, eid int, iid int) error {
+	ctx = named(ctx, "LinkReimbursement")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return err
+	}
+	sql := `
+		UPDATE financeview.expense
+		SET reimbursement_status=$3, reimbursement_income_id=$2, updatedate=$4
+		WHERE id=$1 AND ledger_id=$5 AND deletedat IS NULL AND reimbursable
+		AND EXISTS (SELECT 1 FROM financeview.income WHERE id=$2 AND ledger_id=$5)
+	`
+	ok, err := db.updateExpenseAudited(ctx, model.AuditActionUpdate, eid, sql, eid, iid, model.ReimbursementStatusReimbursed.String(), time.Now().UTC(), lid)
+	if err != nil {
+		return fmt.Errorf("failed to link reimbursement income id=%v to expense id=%v, %w", iid, eid, err)
+	}
+	if !ok {
+		return notFound("expense id=%v is not reimbursable or income id=%v does not exist", eid, iid)
+	}
+	return nil
+}
+
+func (db *Database) CreateIncome(ctx context.Context, dt time.Time, desc string, amt float64, cmt string) (int, error) {
+	ctx = named(ctx, "CreateIncome")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sql := `INSERT INTO financeview.income (ledger_id, date, description, amount, comment, createdate) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	var id int
+	if err := db.Conn.QueryRow(ctx, sql, lid, dt, desc, amt, cmt, time.Now().UTC()).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to insert new income into database, %w", err)
+	}
+	return id, nil
+}
+
+func (db *Database) GetIncome(ctx context.Context, id int) (model.Income, bool, error) {
+	ctx = named(ctx, "GetIncome")
+	is, err := db.queryIncomes(ctx, `WHERE id=$1`, id)
+	if err != nil {
+		return model.Income{}, false, err
+	}
+	if len(is) == 0 {
+		return model.Income{}, false, nil
+	}
+	return is[0], true, nil
+}
+
+func (db *Database) ListIncomes(ctx context.Context) ([]model.Income, error) {
+	ctx = named(ctx, "ListIncomes")
+	return db.queryIncomes(ctx, ``)
+}
+
+func (db *Database) queryIncomes(ctx context.Context, where string, args ...interface{}) ([]model.Income, error) {
+	where, args, err := scope(ctx, "ledger_id", where, args)
+	if err != nil {
+		return nil, err
+	}
+	sql := `
+		SELECT id, date, description, amount, comment
+		FROM financeview.income
+		` + where + `
+		ORDER BY date, id
+	`
+	var is []model.Income
+	rows, err := db.Conn.Query(ctx, sql, args...)
+	if err != nil {
+		return is, fmt.Errorf("failed to select income from database, %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var i Income
+		if err := rows.Scan(&i.Id, &i.Date, &i.Description, &i.Amount, &i.Comment); err != nil {
+			if err == pgx.ErrNoRows {
+				return is, nil
+			}
+			return is, fmt.Errorf("failed to scan income from database, %w", err)
+		}
+		amt, err := moneyToFloat(i.Amount.String)
+		if err != nil {
+			return is, fmt.Errorf("failed to covert amount, %w", err)
+		}
+		is = append(is, model.Income{
+			Id:          int(i.Id.Int),
+			Date:        i.Date.Time.Format("01-02-2006"),
+			Description: i.Description.String,
+			Amount:      amt,
+			Comment:     i.Comment.String,
+		})
+	}
+	return is, nil
+}
+
+type Income struct {
+	Id          pgtype.Int4
+	Date        pgtype.Date
+	Description pgtype.Text
+	Amount      pgtype.Text
+	Comment     pgtype.Text
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/vapor05/financeview/graph/model"
+)
+
+// Snippets are headlined between these bytes so that the text can be
+// escaped before they become <b> tags.
+const (
+	startSel = "\x02"
+	stopSel  = "\x03"
+)
+
+// SearchExpenses returns the ledger's expenses matching a web search style
+// query and every condition set in the filter, best match first and at most
+// limit of them. Snippets are HTML with the matched words in <b> tags.
+func (db *Database) SearchExpenses(ctx context.Context, query string, f model.ExpenseFilter, limit int) ([]model.SearchResult, error) {
+	ctx = named(ctx, "SearchExpenses")
+	where, args := expenseWhere(f)
+	args = append(args, query)
+	q := len(args)
+	where += " AND s.document @@ q.query"
+	where, args, err := scope(ctx, "e.ledger_id", where, args)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, limit, fmt.Sprintf("StartSel=%s, StopSel=%s, MaxFragments=2, MinWords=5, MaxWords=20", startSel, stopSel))
+	sql := fmt.Sprintf(`
+		SELECT m.id, m.rank, ts_headline('english', concat_ws(' ', t.description, t.labels, t.comment), m.query, $%d)
+		FROM (
+			SELECT e.id, e.date, q.query, ts_rank_cd(s.document, q.query) AS rank
+			FROM financeview.expense AS e
+			INNER JOIN financeview.description AS d
+			ON e.description_id = d.id
+			INNER JOIN financeview.expense_search AS s
+			ON s.expense_id = e.id
+			CROSS JOIN websearch_to_tsquery('english', $%d) AS q(query)
+			%s
+			ORDER BY rank DESC, e.date DESC, e.id DESC
+			LIMIT $%d
+		) AS m
+		CROSS JOIN LATERAL financeview.expense_search_text(m.id) AS t
+		ORDER BY m.rank DESC, m.date DESC, m.id DESC
+	`, len(args), q, where, len(args)-1)
+	rows, err := db.Conn.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search expenses, %w", err)
+	}
+	var rs []model.SearchResult
+	var ids []int
+	for rows.Next() {
+		var r model.SearchResult
+		if err := rows.Scan(&r.Expense.Id, &r.Rank, &r.Snippet); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan search results, %w", err)
+		}
+		r.Snippet = strings.NewReplacer(startSel, "<b>", stopSel, "</b>").Replace(html.EscapeString(r.Snippet))
+		rs = append(rs, r)
+		ids = append(ids, r.Expense.Id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read search results, %w", err)
+	}
+	if len(ids) == 0 {
+		return rs, nil
+	}
+	exps, err := db.queryExpenses(ctx, `WHERE e.id = ANY($1)`, ids)
+	if err != nil {
+		return nil, err
+	}
+	byId := make(map[int]model.Expense, len(exps))
+	for _, e := range exps {
+		byId[e.Id] = e
+	}
+	found := rs[:0]
+	for _, r := range rs {
+		if e, ok := byId[r.Expense.Id]; ok {
+			r.Expense = e
+			found = append(found, r)
+		}
+	}
+	return found, nil
+}
@@ -0,0 +1,69 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+func TestSearchExpenses(t *testing.T) {
+	ctx := testCtx
+	db := Database{conn}
+	defer func() {
+		if err := cleanUpDb(); err != nil {
+			t.Fatalf("failed to clean up test data, %v", err)
+		}
+	}()
+	saved, err := db.CreateExpenses(ctx, []model.Expense{
+		{Date: "03-14-2022", Description: "Dan the Plumber", Amount: 180, Categories: []model.Category{{Name: "house"}}, Comment: "kitchen & sink"},
+		{Date: "04-02-2022", Description: "plumber call out", Amount: 60, Categories: []model.Category{{Name: "house"}}, Reimbursable: true},
+		{Date: "03-20-2022", Description: "groceries", Amount: 45, Categories: []model.Category{{Name: "food"}}, Tags: []model.Tag{{Name: "plumber-party"}}},
+		{Date: "03-21-2022", Description: "lunch", Amount: 12, Categories: []model.Category{{Name: "food"}}},
+	})
+	if err != nil {
+		t.Fatalf("failed to setup test data, %v", err)
+	}
+	ids := func(rs []model.SearchResult) []int {
+		var out []int
+		for _, r := range rs {
+			out = append(out, r.Expense.Id)
+		}
+		return out
+	}
+
+	rs, err := db.SearchExpenses(ctx, "plumber", model.ExpenseFilter{}, 10)
+	if err != nil {
+		t.Fatalf("error running SearchExpenses func, %v", err)
+	}
+	assert.ElementsMatch(t, []int{saved[0].Id, saved[1].Id, saved[2].Id}, ids(rs))
+	assert.Equal(t, saved[2].Id, rs[2].Expense.Id, "descriptions rank above tags")
+
+	rs, err = db.SearchExpenses(ctx, "plumber sink", model.ExpenseFilter{}, 10)
+	assert.Nil(t, err)
+	if assert.Len(t, rs, 1) {
+		assert.Equal(t, "Dan the Plumber", rs[0].Expense.Description)
+		assert.Contains(t, rs[0].Snippet, "<b>Plumber</b>")
+		assert.Contains(t, rs[0].Snippet, "kitchen &amp; <b>sink</b>", "snippet text is escaped")
+	}
+
+	rs, err = db.SearchExpenses(ctx, "plumber march", model.ExpenseFilter{}, 10)
+	assert.Nil(t, err)
+	assert.ElementsMatch(t, []int{saved[0].Id, saved[2].Id}, ids(rs))
+
+	pending := []model.ReimbursementStatus{model.ReimbursementStatusPending}
+	rs, err = db.SearchExpenses(ctx, "plumber", model.ExpenseFilter{ReimbursementStatus: pending}, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, []int{saved[1].Id}, ids(rs), "searches are filtered")
+
+	rs, err = db.SearchExpenses(ctx, "plumber", model.ExpenseFilter{}, 1)
+	assert.Nil(t, err)
+	assert.Len(t, rs, 1)
+
+	if err := db.SoftDeleteExpense(ctx, saved[0].Id); err != nil {
+		t.Fatalf("failed to delete expense, %v", err)
+	}
+	rs, err = db.SearchExpenses(ctx, "sink", model.ExpenseFilter{}, 10)
+	assert.Nil(t, err)
+	assert.Empty(t, rs, "deleted expenses are not found")
+}
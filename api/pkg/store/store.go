@@ -7,27 +7,82 @@ import (
 	"strings"
 	"time"
 
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgtype"
 	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/config"
 )
 
+// Conn is a single connection or a pool of them.
+type Conn interface {
+	Begin(context.Context) (pgx.Tx, error)
+	Exec(context.Context, string, ...interface{}) (pgconn.CommandTag, error)
+	Query(context.Context, string, ...interface{}) (pgx.Rows, error)
+	QueryRow(context.Context, string, ...interface{}) pgx.Row
+	Ping(context.Context) error
+}
+
 type Database struct {
-	Conn *pgx.Conn
+	Conn Conn
 }
 
-func NewDatabase(ctx context.Context, dbUrl string) (*Database, error) {
-	conn, err := pgx.Connect(ctx, dbUrl)
+// NewDatabase connects a pool sized by the config to the app database.
+func NewDatabase(ctx context.Context, c config.Database) (*Database, error) {
+	pc, err := pgxpool.ParseConfig(c.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database url, %w", err)
+	}
+	pc.MaxConns = c.MaxConns
+	pc.MinConns = c.MinConns
+	pc.MaxConnLifetime = time.Duration(c.MaxConnLifetime)
+	pc.MaxConnIdleTime = time.Duration(c.MaxConnIdleTime)
+	pc.ConnConfig.Logger = pgxLogger{}
+	pc.ConnConfig.LogLevel = pgx.LogLevelWarn
+	pool, err := pgxpool.ConnectConfig(ctx, pc)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to app database, %w", err)
 	}
-	return &Database{conn}, nil
+	return &Database{timedConn{pool}}, nil
+}
+
+// Close closes the connection pool made by NewDatabase.
+func (db *Database) Close() {
+	if p := db.pool(); p != nil {
+		p.Close()
+	}
+}
+
+// inTx runs f inside a transaction, rolling back if f returns an error.
+// Statements in a transaction skip timedConn, so their errors are typed
+// here.
+func (db *Database) inTx(ctx context.Context, f func(pgx.Tx) error) (err error) {
+	ctx, end := start(ctx, "BEGIN")
+	defer func() { end(err) }()
+	tx, err := db.Conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction, %w", dbError(err))
+	}
+	if err := f(tx); err != nil {
+		tx.Rollback(ctx)
+		return dbError(err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction, %w", dbError(err))
+	}
+	return nil
 }
 
 func (db *Database) GetDescriptionId(ctx context.Context, d string) (int, bool, error) {
-	sql := `SELECT id FROM financeview.description WHERE description=$1`
+	ctx = named(ctx, "GetDescriptionId")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	sql := `SELECT id FROM financeview.description WHERE description=$1 AND ledger_id=$2`
 	var id int
-	if err := db.Conn.QueryRow(ctx, sql, d).Scan(&id); err != nil {
+	if err := db.Conn.QueryRow(ctx, sql, d, lid).Scan(&id); err != nil {
 		if err == pgx.ErrNoRows {
 			return 0, false, nil
 		}
@@ -36,36 +91,70 @@ func (db *Database) GetDescriptionId(ctx context.Context, d string) (int, bool,
 	return id, true, nil
 }
 
+// descriptionInsert inserts description $1 created at $2 into ledger $3,
+// linking it to the payee with the longest alias pattern that matches it.
+const descriptionInsert = `
+	INSERT INTO financeview.description (ledger_id, description, payee_id, createdate)
+	VALUES ($3, $1, (
+		SELECT a.payee_id
+		FROM financeview.payee_alias AS a
+		INNER JOIN financeview.payee AS p
+		ON a.payee_id = p.id AND p.ledger_id = $3
+		WHERE $1 ILIKE a.pattern
+		ORDER BY length(a.pattern) DESC, a.id
+		LIMIT 1
+	), $2)
+	RETURNING id
+`
+
+// CreateDescription inserts a new description, linking it to the ledger's
+// payee with the longest alias pattern that matches it.
 func (db *Database) CreateDescription(ctx context.Context, d string) (int, error) {
-	sql := `INSERT INTO financeview.description (description, createdate) VALUES ($1, $2) RETURNING id`
+	ctx = named(ctx, "CreateDescription")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return 0, err
+	}
 	var id int
-	if err := db.Conn.QueryRow(ctx, sql, d, time.Now().UTC()).Scan(&id); err != nil {
+	if err := db.Conn.QueryRow(ctx, descriptionInsert, d, time.Now().UTC(), lid).Scan(&id); err != nil {
 		return 0, fmt.Errorf("failed to insert new description into database, %w", err)
 	}
 	return id, nil
 }
 
 func (db *Database) CreateExpense(ctx context.Context, dt time.Time, did int, amt float64, cmt string) (int, error) {
-	sql := `INSERT INTO financeview.expense (date, description_id, amount, comment, createdate) VALUES ($1, $2, $3, $4, $5) RETURNING id`
-	var id int
-	if err := db.Conn.QueryRow(
+	ctx = named(ctx, "CreateExpense")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sql := `INSERT INTO financeview.expense (ledger_id, date, description_id, amount, comment, createdate) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+	id, err := db.createAudited(
 		ctx,
+		auditChange{Entity: model.AuditEntityExpense},
 		sql,
+		lid,
 		dt,
 		did,
 		amt,
 		cmt,
 		time.Now().UTC(),
-	).Scan(&id); err != nil {
+	)
+	if err != nil {
 		return 0, fmt.Errorf("failed to insert new expense into database, %w", err)
 	}
 	return id, nil
 }
 
 func (db *Database) GetCategoryId(ctx context.Context, c string) (int, bool, error) {
-	sql := `SELECT id FROM financeview.category WHERE name=$1`
+	ctx = named(ctx, "GetCategoryId")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	sql := `SELECT id FROM financeview.category WHERE name=$1 AND ledger_id=$2`
 	var id int
-	if err := db.Conn.QueryRow(ctx, sql, c).Scan(&id); err != nil {
+	if err := db.Conn.QueryRow(ctx, sql, c, lid).Scan(&id); err != nil {
 		if err == pgx.ErrNoRows {
 			return 0, false, nil
 		}
@@ -75,23 +164,83 @@ func (db *Database) GetCategoryId(ctx context.Context, c string) (int, bool, err
 }
 
 func (db *Database) CreateCategory(ctx context.Context, c string) (int, error) {
-	sql := `INSERT INTO financeview.category (name, createdate) VALUES ($1, $2) RETURNING id`
-	var id int
-	if err := db.Conn.QueryRow(ctx, sql, c, time.Now().UTC()).Scan(&id); err != nil {
+	ctx = named(ctx, "CreateCategory")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sql := `INSERT INTO financeview.category (ledger_id, name, createdate) VALUES ($1, $2, $3) RETURNING id`
+	id, err := db.createAudited(ctx, auditChange{Entity: model.AuditEntityCategory}, sql, lid, c, time.Now().UTC())
+	if err != nil {
 		return 0, fmt.Errorf("failed to insert new category into database, %w", err)
 	}
 	return id, nil
 }
 
+// LinkExpenseCategory links an expense and category that both belong to the
+// ledger.
 func (db *Database) LinkExpenseCategory(ctx context.Context, eid int, cid int) (int, error) {
-	sql := `INSERT INTO financeview.expense_category (expense_id, category_id, createdate) VALUES ($1, $2, $3) RETURNING id`
-	var id int
-	if err := db.Conn.QueryRow(ctx, sql, eid, cid, time.Now().UTC()).Scan(&id); err != nil {
+	ctx = named(ctx, "LinkExpenseCategory")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sql := `
+		INSERT INTO financeview.expense_category (expense_id, category_id, createdate)
+		SELECT e.id, c.id, $3
+		FROM financeview.expense AS e, financeview.category AS c
+		WHERE e.id=$1 AND e.ledger_id=$4 AND c.id=$2 AND c.ledger_id=$4
+		RETURNING id
+	`
+	id, err := db.createAudited(ctx, auditChange{Entity: model.AuditEntityExpenseCategory, ExpenseId: eid}, sql, eid, cid, time.Now().UTC(), lid)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, notFound("expense id=%v or category id=%v does not exist", eid, cid)
+		}
 		return 0, fmt.Errorf("failed to insert new expense_category into database, %w", err)
 	}
 	return id, nil
 }
 
+// expenseUpdate sets the date, description id, amount, comment and update
+// time of expense $1 of ledger $7 to $2 through $6.
+const expenseUpdate = `UPDATE financeview.expense SET date=$2, description_id=$3, amount=$4, comment=$5, updatedate=$6 WHERE id=$1 AND ledger_id=$7 AND deletedat IS NULL`
+
+func (db *Database) UpdateExpense(ctx context.Context, id int, dt time.Time, did int, amt float64, cmt string) error {
+	ctx = named(ctx, "UpdateExpense")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return err
+	}
+	ok, err := db.updateExpenseAudited(ctx, model.AuditActionUpdate, id, expenseUpdate, id, dt, did, amt, cmt, time.Now().UTC(), lid)
+	if err != nil {
+		return fmt.Errorf("failed to update expense id=%v, %w", id, err)
+	}
+	if !ok {
+		return notFound("expense id=%v does not exist", id)
+	}
+	return nil
+}
+
+// expenseCategoryDelete unlinks expense $1 of ledger $2 from its categories.
+const expenseCategoryDelete = `
+	DELETE FROM financeview.expense_category AS ec
+	WHERE ec.expense_id=$1 AND ec.expense_id IN (SELECT id FROM financeview.expense WHERE ledger_id=$2)
+	RETURNING ec.id, row_to_json(ec)
+`
+
+func (db *Database) UnlinkExpenseCategories(ctx context.Context, eid int) error {
+	ctx = named(ctx, "UnlinkExpenseCategories")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return err
+	}
+	if err := db.deleteAudited(ctx, model.AuditEntityExpenseCategory, eid, expenseCategoryDelete, eid, lid); err != nil {
+		return fmt.Errorf("failed to delete expense_category rows for expense_id=%v, %w", eid, err)
+	}
+	return nil
+}
+
 func moneyToFloat(m string) (float64, error) {
 	amt, err := strconv.ParseFloat(strings.ReplaceAll(m, "$", ""), 64)
 	if err != nil {
@@ -101,20 +250,69 @@ func moneyToFloat(m string) (float64, error) {
 }
 
 func (db *Database) ListAllExpenses(ctx context.Context) ([]model.Expense, error) {
+	ctx = named(ctx, "ListAllExpenses")
+	return db.FindExpenses(ctx, model.ExpenseFilter{})
+}
+
+// FindExpenses lists the expenses matching every condition set in the
+// filter, leaving out deleted expenses.
+func (db *Database) FindExpenses(ctx context.Context, f model.ExpenseFilter) ([]model.Expense, error) {
+	ctx = named(ctx, "FindExpenses")
+	where, args := expenseWhere(f)
+	return db.queryExpenses(ctx, where, args...)
+}
+
+func (db *Database) GetExpense(ctx context.Context, id int) (model.Expense, bool, error) {
+	ctx = named(ctx, "GetExpense")
+	exps, err := db.queryExpenses(ctx, `WHERE e.id = $1 AND e.deletedat IS NULL`, id)
+	if err != nil {
+		return model.Expense{}, false, err
+	}
+	if len(exps) == 0 {
+		return model.Expense{}, false, nil
+	}
+	return exps[0], true, nil
+}
+
+// queryExpenses selects the ledger's expenses, filtered by the optional where
+// clause.
+func (db *Database) queryExpenses(ctx context.Context, where string, args ...interface{}) ([]model.Expense, error) {
+	where, args, err := scope(ctx, "e.ledger_id", where, args)
+	if err != nil {
+		return nil, err
+	}
 	expSql := `
-		SELECT e.id, e.date, d.description, e.amount, e.comment
+		SELECT e.id, e.date, d.description, e.amount, e.comment, p.id, p.name,
+			e.reimbursable, e.reimbursement_status, e.reimbursement_income_id, e.tax_category, e.deletedat
 		FROM financeview.expense AS e
 		INNER JOIN financeview.description AS d
 		ON e.description_id = d.id
-	`
+		LEFT JOIN financeview.payee AS p
+		ON d.payee_id = p.id
+	` + where
 	var exps []model.Expense
-	rows, err := db.Conn.Query(ctx, expSql)
+	rows, err := db.Conn.Query(ctx, expSql, args...)
 	if err != nil {
 		return exps, fmt.Errorf("failed to select expenses from database, %w", err)
 	}
+	defer rows.Close()
 	for rows.Next() {
 		var e Expense
-		if err := rows.Scan(&e.Id, &e.Date, &e.Description, &e.Amount, &e.Comment); err != nil {
+		var p Payee
+		if err := rows.Scan(
+			&e.Id,
+			&e.Date,
+			&e.Description,
+			&e.Amount,
+			&e.Comment,
+			&p.Id,
+			&p.Name,
+			&e.Reimbursable,
+			&e.ReimbursementStatus,
+			&e.ReimbursementIncomeId,
+			&e.TaxCategory,
+			&e.DeletedAt,
+		); err != nil {
 			if err == pgx.ErrNoRows {
 				return exps, nil
 			}
@@ -124,61 +322,160 @@ func (db *Database) ListAllExpenses(ctx context.Context) ([]model.Expense, error
 		if err != nil {
 			return exps, fmt.Errorf("failed to covert amount, %w", err)
 		}
-		exps = append(exps, model.Expense{
-			Id:          int(e.Id.Int),
-			Date:        e.Date.Time.Format("01-02-2006"),
-			Description: e.Description.String,
-			Amount:      amt,
-			Comment:     e.Comment.String,
-		})
+		exp := model.Expense{
+			Id:                    int(e.Id.Int),
+			Date:                  e.Date.Time.Format("01-02-2006"),
+			Description:           e.Description.String,
+			Amount:                amt,
+			Comment:               e.Comment.String,
+			Reimbursable:          e.Reimbursable.Bool,
+			ReimbursementIncomeId: int(e.ReimbursementIncomeId.Int),
+			TaxCategory:           taxCategory(e.TaxCategory),
+			DeletedAt:             timestampString(e.DeletedAt),
+		}
+		if p.Id.Status == pgtype.Present {
+			exp.Payee = &model.Payee{Id: int(p.Id.Int), Name: p.Name.String}
+		}
+		if e.ReimbursementStatus.Status == pgtype.Present {
+			rs := model.ReimbursementStatus(e.ReimbursementStatus.String)
+			exp.ReimbursementStatus = &rs
+		}
+		exps = append(exps, exp)
+	}
+	if err := rows.Err(); err != nil {
+		return exps, fmt.Errorf("failed to read expenses from database, %w", err)
 	}
+	// Release the connection before querying the expenses' categories and
+	// tags.
+	rows.Close()
+	if len(exps) == 0 {
+		return exps, nil
+	}
+	eids := make([]int, len(exps))
 	for i := range exps {
-		cats, err := GetCategories(ctx, exps[i].Id, db)
-		if err != nil {
-			return exps, fmt.Errorf("failed to get expense's categories from database, %w", err)
-		}
-		exps[i].Categories = cats
+		eids[i] = exps[i].Id
+	}
+	cats, err := GetCategories(ctx, eids, db)
+	if err != nil {
+		return exps, fmt.Errorf("failed to get expenses' categories from database, %w", err)
+	}
+	tags, err := GetTags(ctx, eids, db)
+	if err != nil {
+		return exps, fmt.Errorf("failed to get expenses' tags from database, %w", err)
+	}
+	for i := range exps {
+		exps[i].Categories = cats[exps[i].Id]
+		exps[i].Tags = tags[exps[i].Id]
 	}
 	return exps, nil
 }
 
-func GetCategories(ctx context.Context, eid int, db *Database) ([]model.Category, error) {
+// expenseWhere builds the WHERE clause and its arguments for an expense
+// filter over the e (expense) and d (description) tables. Deleted expenses
+// never match. Periods must already be resolved to dates.
+func expenseWhere(f model.ExpenseFilter) (string, []interface{}) {
+	conds := []string{"e.deletedat IS NULL"}
+	var args []interface{}
+	if len(f.Tags) > 0 {
+		args = append(args, f.Tags, len(f.Tags))
+		conds = append(conds, fmt.Sprintf(`e.id IN (
+			SELECT et.expense_id
+			FROM financeview.expense_tag AS et
+			INNER JOIN financeview.tag AS t
+			ON et.tag_id = t.id
+			WHERE t.name = ANY($%d)
+			GROUP BY et.expense_id
+			HAVING COUNT(DISTINCT t.name) = $%d
+		)`, len(args)-1, len(args)))
+	}
+	if len(f.ReimbursementStatus) > 0 {
+		var rs []string
+		for _, r := range f.ReimbursementStatus {
+			rs = append(rs, r.String())
+		}
+		args = append(args, rs)
+		conds = append(conds, fmt.Sprintf(`e.reimbursable AND e.reimbursement_status = ANY($%d)`, len(args)))
+	}
+	if len(f.Categories) > 0 {
+		args = append(args, f.Categories)
+		conds = append(conds, fmt.Sprintf(`e.id IN (
+			SELECT ec.expense_id
+			FROM financeview.expense_category AS ec
+			INNER JOIN financeview.category AS c
+			ON ec.category_id = c.id
+			WHERE c.name = ANY($%d)
+		)`, len(args)))
+	}
+	if f.From != nil {
+		args = append(args, *f.From)
+		conds = append(conds, fmt.Sprintf(`e.date >= to_date($%d, 'MM-DD-YYYY')`, len(args)))
+	}
+	if f.To != nil {
+		args = append(args, *f.To)
+		conds = append(conds, fmt.Sprintf(`e.date <= to_date($%d, 'MM-DD-YYYY')`, len(args)))
+	}
+	if f.MinAmount != nil {
+		args = append(args, *f.MinAmount)
+		conds = append(conds, fmt.Sprintf(`e.amount::numeric::float8 >= $%d`, len(args)))
+	}
+	if f.MaxAmount != nil {
+		args = append(args, *f.MaxAmount)
+		conds = append(conds, fmt.Sprintf(`e.amount::numeric::float8 <= $%d`, len(args)))
+	}
+	return "WHERE " + strings.Join(conds, " AND "), args
+}
+
+// GetCategories returns the categories of each of the expenses, by expense
+// id.
+func GetCategories(ctx context.Context, eids []int, db *Database) (map[int][]model.Category, error) {
+	ctx = named(ctx, "GetCategories")
 	catSql := `
-		SELECT c.id, c.name
+		SELECT ec.expense_id, c.id, c.name, c.tax_category
 		FROM financeview.category AS c
 		INNER JOIN financeview.expense_category AS ec
-		ON c.id = ec.category_id AND ec.expense_id = $1
+		ON c.id = ec.category_id
+		WHERE ec.expense_id = ANY($1)
+		ORDER BY ec.id
 	`
-	var cats []model.Category
-	rows, err := db.Conn.Query(ctx, catSql, eid)
+	cats := make(map[int][]model.Category)
+	rows, err := db.Conn.Query(ctx, catSql, eids)
 	if err != nil {
-		return cats, fmt.Errorf("failed to select categories for expense_id=%v from database, %w", eid, err)
+		return cats, fmt.Errorf("failed to select categories of expenses from database, %w", err)
 	}
+	defer rows.Close()
 	for rows.Next() {
+		var eid int
 		var c Category
-		if err := rows.Scan(&c.Id, &c.Name); err != nil {
-			if err == pgx.ErrNoRows {
-				return cats, nil
-			}
-			return cats, fmt.Errorf("failed to scan categories for expense_id=%v from database, %w", eid, err)
+		if err := rows.Scan(&eid, &c.Id, &c.Name, &c.TaxCategory); err != nil {
+			return cats, fmt.Errorf("failed to scan categories of expenses from database, %w", err)
 		}
-		cats = append(cats, model.Category{
-			Id:   int(c.Id.Int),
-			Name: c.Name.String,
+		cats[eid] = append(cats[eid], model.Category{
+			Id:          int(c.Id.Int),
+			Name:        c.Name.String,
+			TaxCategory: taxCategory(c.TaxCategory),
 		})
 	}
+	if err := rows.Err(); err != nil {
+		return cats, fmt.Errorf("failed to read categories of expenses from database, %w", err)
+	}
 	return cats, nil
 }
 
 type Expense struct {
-	Id          pgtype.Int4
-	Date        pgtype.Date
-	Description pgtype.Text
-	Amount      pgtype.Text
-	Comment     pgtype.Text
+	Id                    pgtype.Int4
+	Date                  pgtype.Date
+	Description           pgtype.Text
+	Amount                pgtype.Text
+	Comment               pgtype.Text
+	Reimbursable          pgtype.Bool
+	ReimbursementStatus   pgtype.Text
+	ReimbursementIncomeId pgtype.Int4
+	TaxCategory           pgtype.Text
+	DeletedAt             pgtype.Timestamp
 }
 
 type Category struct {
-	Id   pgtype.Int4
-	Name pgtype.Text
+	Id          pgtype.Int4
+	Name        pgtype.Text
+	TaxCategory pgtype.Text
 }
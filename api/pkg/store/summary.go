@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgtype"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+// summaryGroups maps each summary grouping to the joins it needs and the
+// expression whose value becomes the row key.
+var summaryGroups = map[model.SummaryGroupBy]struct {
+	join string
+	key  string
+}{
+	model.SummaryGroupByCategory: {
+		join: `
+		LEFT JOIN financeview.expense_category AS ec
+		ON e.id = ec.expense_id
+		LEFT JOIN financeview.category AS c
+		ON ec.category_id = c.id`,
+		key: `COALESCE(c.name, '')`,
+	},
+	model.SummaryGroupByPayee: {
+		join: `
+		LEFT JOIN financeview.payee AS p
+		ON d.payee_id = p.id`,
+		key: `COALESCE(p.name, d.description)`,
+	},
+	model.SummaryGroupByTag: {
+		join: `
+		INNER JOIN financeview.expense_tag AS et
+		ON e.id = et.expense_id
+		INNER JOIN financeview.tag AS t
+		ON et.tag_id = t.id`,
+		key: `t.name`,
+	},
+}
+
+// SummarizeExpenses totals personal spending matching the filter by the
+// grouping, largest total first, leaving out reimbursed expenses. Expenses
+// without a payee are grouped by their raw description, and untagged
+// expenses are left out of tag totals.
+func (db *Database) SummarizeExpenses(ctx context.Context, groupBy model.SummaryGroupBy, f model.ExpenseFilter) ([]model.SummaryRow, error) {
+	ctx = named(ctx, "SummarizeExpenses")
+	g, ok := summaryGroups[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("unsupported summary grouping %v", groupBy)
+	}
+	where, args := expenseWhere(f)
+	where, args, err := scope(ctx, "e.ledger_id", where+` AND e.reimbursement_status IS DISTINCT FROM 'REIMBURSED'`, args)
+	if err != nil {
+		return nil, err
+	}
+	sql := `
+		SELECT ` + g.key + ` AS key, SUM(e.amount)::numeric::float8, COUNT(*)
+		FROM financeview.expense AS e
+		INNER JOIN financeview.description AS d
+		ON e.description_id = d.id` + g.join + `
+		` + where + `
+		GROUP BY key
+		ORDER BY 2 DESC, key
+	`
+	var sum []model.SummaryRow
+	rows, err := db.Conn.Query(ctx, sql, args...)
+	if err != nil {
+		return sum, fmt.Errorf("failed to summarize expenses by %v, %w", groupBy, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r SummaryRow
+		if err := rows.Scan(&r.Key, &r.Total, &r.Count); err != nil {
+			return sum, fmt.Errorf("failed to scan expense summary, %w", err)
+		}
+		sum = append(sum, model.SummaryRow{
+			Key:   r.Key.String,
+			Total: r.Total.Float,
+			Count: int(r.Count.Int),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return sum, fmt.Errorf("failed to read expense summary, %w", err)
+	}
+	return sum, nil
+}
+
+type SummaryRow struct {
+	Key   pgtype.Text
+	Total pgtype.Float8
+	Count pgtype.Int8
+}
@@ -0,0 +1,124 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+func (db *Database) GetTagId(ctx context.Context, t string) (int, bool, error) {
+	ctx = named(ctx, "GetTagId")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	sql := `SELECT id FROM financeview.tag WHERE name=$1 AND ledger_id=$2`
+	var id int
+	if err := db.Conn.QueryRow(ctx, sql, t, lid).Scan(&id); err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to query database for tag, %w", err)
+	}
+	return id, true, nil
+}
+
+func (db *Database) CreateTag(ctx context.Context, t string) (int, error) {
+	ctx = named(ctx, "CreateTag")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sql := `INSERT INTO financeview.tag (ledger_id, name, createdate) VALUES ($1, $2, $3) RETURNING id`
+	var id int
+	if err := db.Conn.QueryRow(ctx, sql, lid, t, time.Now().UTC()).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to insert new tag into database, %w", err)
+	}
+	return id, nil
+}
+
+// LinkExpenseTag links an expense and tag that both belong to the ledger.
+func (db *Database) LinkExpenseTag(ctx context.Context, eid int, tid int) (int, error) {
+	ctx = named(ctx, "LinkExpenseTag")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return 0, err
+	}
+	sql := `
+		INSERT INTO financeview.expense_tag (expense_id, tag_id, createdate)
+		SELECT e.id, t.id, $3
+		FROM financeview.expense AS e, financeview.tag AS t
+		WHERE e.id=$1 AND e.ledger_id=$4 AND t.id=$2 AND t.ledger_id=$4
+		RETURNING id
+	`
+	id, err := db.createAudited(ctx, auditChange{Entity: model.AuditEntityExpenseTag, ExpenseId: eid}, sql, eid, tid, time.Now().UTC(), lid)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, notFound("expense id=%v or tag id=%v does not exist", eid, tid)
+		}
+		return 0, fmt.Errorf("failed to insert new expense_tag into database, %w", err)
+	}
+	return id, nil
+}
+
+// expenseTagDelete unlinks expense $1 of ledger $2 from its tags.
+const expenseTagDelete = `
+	DELETE FROM financeview.expense_tag AS et
+	WHERE et.expense_id=$1 AND et.expense_id IN (SELECT id FROM financeview.expense WHERE ledger_id=$2)
+	RETURNING et.id, row_to_json(et)
+`
+
+func (db *Database) UnlinkExpenseTags(ctx context.Context, eid int) error {
+	ctx = named(ctx, "UnlinkExpenseTags")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return err
+	}
+	if err := db.deleteAudited(ctx, model.AuditEntityExpenseTag, eid, expenseTagDelete, eid, lid); err != nil {
+		return fmt.Errorf("failed to delete expense_tag rows for expense_id=%v, %w", eid, err)
+	}
+	return nil
+}
+
+// GetTags returns the tags of each of the expenses by name, by expense id.
+func GetTags(ctx context.Context, eids []int, db *Database) (map[int][]model.Tag, error) {
+	ctx = named(ctx, "GetTags")
+	tagSql := `
+		SELECT et.expense_id, t.id, t.name
+		FROM financeview.tag AS t
+		INNER JOIN financeview.expense_tag AS et
+		ON t.id = et.tag_id
+		WHERE et.expense_id = ANY($1)
+		ORDER BY t.name
+	`
+	tags := make(map[int][]model.Tag)
+	rows, err := db.Conn.Query(ctx, tagSql, eids)
+	if err != nil {
+		return tags, fmt.Errorf("failed to select tags of expenses from database, %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var eid int
+		var t Tag
+		if err := rows.Scan(&eid, &t.Id, &t.Name); err != nil {
+			return tags, fmt.Errorf("failed to scan tags of expenses from database, %w", err)
+		}
+		tags[eid] = append(tags[eid], model.Tag{
+			Id:   int(t.Id.Int),
+			Name: t.Name.String,
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return tags, fmt.Errorf("failed to read tags of expenses from database, %w", err)
+	}
+	return tags, nil
+}
+
+type Tag struct {
+	Id   pgtype.Int4
+	Name pgtype.Text
+}
@@ -0,0 +1,60 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+func TestFindExpensesByTag(t *testing.T) {
+	ctx := testCtx
+	db := Database{conn}
+	defer func() {
+		if err := cleanUpDb(); err != nil {
+			t.Fatalf("failed to clean up test data, %v", err)
+		}
+	}()
+	did, err := db.CreateDescription(ctx, "hotel")
+	if err != nil {
+		t.Fatalf("failed to setup test data, %v", err)
+	}
+	dt := time.Date(2022, time.March, 2, 0, 0, 0, 0, time.UTC)
+	trip, err := db.CreateTag(ctx, "trip-2026-lisbon")
+	if err != nil {
+		t.Fatalf("error running CreateTag func, %v", err)
+	}
+	reimb, err := db.CreateTag(ctx, "reimbursable")
+	if err != nil {
+		t.Fatalf("error running CreateTag func, %v", err)
+	}
+	var eids []int
+	for _, tids := range [][]int{{trip}, {trip, reimb}, {}} {
+		eid, err := db.CreateExpense(ctx, dt, did, 100, "")
+		if err != nil {
+			t.Fatalf("failed to setup test data, %v", err)
+		}
+		for _, tid := range tids {
+			if _, err := db.LinkExpenseTag(ctx, eid, tid); err != nil {
+				t.Fatalf("error running LinkExpenseTag func, %v", err)
+			}
+		}
+		eids = append(eids, eid)
+	}
+	actual, err := db.FindExpenses(ctx, model.ExpenseFilter{Tags: []string{"trip-2026-lisbon", "reimbursable"}})
+	if err != nil {
+		t.Fatalf("error running FindExpenses func, %v", err)
+	}
+	assert.Len(t, actual, 1)
+	assert.Equal(t, eids[1], actual[0].Id)
+	assert.Equal(t, []model.Tag{{Id: reimb, Name: "reimbursable"}, {Id: trip, Name: "trip-2026-lisbon"}}, actual[0].Tags)
+	sum, err := db.SummarizeExpenses(ctx, model.SummaryGroupByTag, model.ExpenseFilter{})
+	if err != nil {
+		t.Fatalf("error running SummarizeExpenses func, %v", err)
+	}
+	assert.Equal(t, []model.SummaryRow{
+		{Key: "trip-2026-lisbon", Total: 200, Count: 2},
+		{Key: "reimbursable", Total: 100, Count: 1},
+	}, sum)
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+// SetCategoryTaxCategory marks the named category's expenses deductible
+// under a tax category, or not deductible when tc is nil.
+func (db *Database) SetCategoryTaxCategory(ctx context.Context, name string, tc *model.TaxCategory) (model.Category, error) {
+	ctx = named(ctx, "SetCategoryTaxCategory")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return model.Category{}, err
+	}
+	c := model.Category{Name: name, TaxCategory: tc}
+	err = db.inTx(ctx, func(tx pgx.Tx) error {
+		sql := `SELECT id FROM financeview.category WHERE name=$1 AND ledger_id=$2 FOR UPDATE`
+		if err := tx.QueryRow(ctx, sql, name, lid).Scan(&c.Id); err != nil {
+			if err == pgx.ErrNoRows {
+				return notFound("category %q does not exist", name)
+			}
+			return err
+		}
+		before, err := snapshot(ctx, tx, model.AuditEntityCategory, c.Id)
+		if err != nil {
+			return err
+		}
+		sql = `UPDATE financeview.category SET tax_category=$2, updatedate=$3 WHERE id=$1`
+		if _, err := tx.Exec(ctx, sql, c.Id, taxCategoryText(tc), time.Now().UTC()); err != nil {
+			return err
+		}
+		after, err := snapshot(ctx, tx, model.AuditEntityCategory, c.Id)
+		if err != nil {
+			return err
+		}
+		return audit(ctx, tx, auditChange{
+			Entity:   model.AuditEntityCategory,
+			EntityId: c.Id,
+			Action:   model.AuditActionUpdate,
+			Before:   before,
+			After:    after,
+		})
+	})
+	if err != nil {
+		return model.Category{}, fmt.Errorf("failed to set tax category of category %q, %w", name, err)
+	}
+	return c, nil
+}
+
+// SetExpenseTaxCategory marks an expense deductible under a tax category
+// regardless of its categories, or clears the mark when tc is nil.
+func (db *Database) SetExpenseTaxCategory(ctx context.Context, eid int, tc *model.TaxCategory) error {
+	ctx = named(ctx, "SetExpenseTaxCategory")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return err
+	}
+	sql := `UPDATE financeview.expense SET tax_category=$2, updatedate=$3 WHERE id=$1 AND ledger_id=$4 AND deletedat IS NULL`
+	ok, err := db.updateExpenseAudited(ctx, model.AuditActionUpdate, eid, sql, eid, taxCategoryText(tc), time.Now().UTC(), lid)
+	if err != nil {
+		return fmt.Errorf("failed to set tax category of expense id=%v, %w", eid, err)
+	}
+	if !ok {
+		return notFound("expense id=%v does not exist", eid)
+	}
+	return nil
+}
+
+// taxCategory returns a nullable tax_category column, leaving NULL as nil.
+func taxCategory(t pgtype.Text) *model.TaxCategory {
+	if t.Status != pgtype.Present {
+		return nil
+	}
+	tc := model.TaxCategory(t.String)
+	return &tc
+}
+
+func taxCategoryText(tc *model.TaxCategory) *string {
+	if tc == nil {
+		return nil
+	}
+	s := tc.String()
+	return &s
+}
@@ -0,0 +1,62 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+func TestTaxCategories(t *testing.T) {
+	ctx := testCtx
+	db := Database{conn}
+	defer func() {
+		if err := cleanUpDb(); err != nil {
+			t.Fatalf("failed to clean up test data, %v", err)
+		}
+	}()
+	saved, err := db.CreateExpenses(ctx, []model.Expense{
+		{Date: "03-01-2022", Description: "dentist", Amount: 120, Categories: []model.Category{{Name: "health"}}},
+		{Date: "03-05-2022", Description: "red cross", Amount: 50, Categories: []model.Category{{Name: "gifts"}}},
+	})
+	if err != nil {
+		t.Fatalf("failed to setup test data, %v", err)
+	}
+	medical, charitable := model.TaxCategoryMedical, model.TaxCategoryCharitable
+	c, err := db.SetCategoryTaxCategory(ctx, "health", &medical)
+	if err != nil {
+		t.Fatalf("error running SetCategoryTaxCategory func, %v", err)
+	}
+	assert.Equal(t, "health", c.Name)
+	assert.Equal(t, &medical, c.TaxCategory)
+	if err := db.SetExpenseTaxCategory(ctx, saved[1].Id, &charitable); err != nil {
+		t.Fatalf("error running SetExpenseTaxCategory func, %v", err)
+	}
+	e, _, err := db.GetExpense(ctx, saved[0].Id)
+	assert.Nil(t, err)
+	assert.Nil(t, e.TaxCategory)
+	assert.Equal(t, []model.Category{{Id: c.Id, Name: "health", TaxCategory: &medical}}, e.Categories)
+	e, _, err = db.GetExpense(ctx, saved[1].Id)
+	assert.Nil(t, err)
+	assert.Equal(t, &charitable, e.TaxCategory)
+	assert.Nil(t, e.Categories[0].TaxCategory)
+
+	if err := db.SetExpenseTaxCategory(ctx, saved[1].Id, nil); err != nil {
+		t.Fatalf("error running SetExpenseTaxCategory func, %v", err)
+	}
+	e, _, err = db.GetExpense(ctx, saved[1].Id)
+	assert.Nil(t, err)
+	assert.Nil(t, e.TaxCategory)
+
+	ent := model.AuditEntityCategory
+	es, err := db.ListAuditEntries(ctx, model.AuditFilter{Entity: &ent}, 10)
+	if assert.Nil(t, err) && assert.NotEmpty(t, es) {
+		assert.Equal(t, model.AuditActionUpdate, es[0].Action, "changes to categories are audited")
+	}
+
+	var nf *NotFoundError
+	_, err = db.SetCategoryTaxCategory(ctx, "travel", &medical)
+	assert.True(t, errors.As(err, &nf))
+	assert.True(t, errors.As(db.SetExpenseTaxCategory(ctx, 0, nil), &nf))
+}
@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/vapor05/financeview/pkg/metrics"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName names the store's spans.
+const tracerName = "github.com/vapor05/financeview/pkg/store"
+
+// timedConn times and traces every statement run through it, naming it
+// after the exported store method or function that ran it, and types the
+// errors they return. Each of those names itself with named.
+type timedConn struct {
+	Conn
+}
+
+// start begins a span for a statement, returning the statement's name and
+// a function ending the span and recording its timing.
+func start(ctx context.Context, sql string) (context.Context, func(error)) {
+	name := statement(ctx)
+	t := time.Now()
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "store."+name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.operation", name),
+		attribute.String("db.statement", strings.Join(strings.Fields(sql), " ")),
+	))
+	return ctx, func(err error) {
+		metrics.ObserveQuery(name, time.Since(t), err)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+	}
+}
+
+func (c timedConn) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	ctx, end := start(ctx, sql)
+	tag, err := c.Conn.Exec(ctx, sql, args...)
+	end(err)
+	return tag, dbError(err)
+}
+
+func (c timedConn) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	ctx, end := start(ctx, sql)
+	rows, err := c.Conn.Query(ctx, sql, args...)
+	if err != nil {
+		end(err)
+		return rows, dbError(err)
+	}
+	return &timedRows{Rows: rows, end: end}, nil
+}
+
+func (c timedConn) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	ctx, end := start(ctx, sql)
+	return timedRow{c.Conn.QueryRow(ctx, sql, args...), end}
+}
+
+// timedRows finishes timing a query once its rows are closed.
+type timedRows struct {
+	pgx.Rows
+	end  func(error)
+	done bool
+}
+
+func (r *timedRows) Scan(dest ...interface{}) error {
+	return dbError(r.Rows.Scan(dest...))
+}
+
+func (r *timedRows) Err() error {
+	return dbError(r.Rows.Err())
+}
+
+func (r *timedRows) Close() {
+	r.Rows.Close()
+	if !r.done {
+		r.done = true
+		r.end(r.Rows.Err())
+	}
+}
+
+// timedRow finishes timing a QueryRow once it is scanned, when the query
+// has actually run.
+type timedRow struct {
+	row pgx.Row
+	end func(error)
+}
+
+func (r timedRow) Scan(dest ...interface{}) error {
+	err := r.row.Scan(dest...)
+	if err == pgx.ErrNoRows {
+		r.end(nil)
+	} else {
+		r.end(err)
+	}
+	return dbError(err)
+}
+
+// statementKey holds the name of the store method running statements.
+type statementKey struct{}
+
+// named labels the statements run with the context with the name of the
+// exported store method or function running them. Helpers shared by
+// several methods are reported under the method that called them.
+func named(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, statementKey{}, name)
+}
+
+func statement(ctx context.Context) string {
+	if name, ok := ctx.Value(statementKey{}).(string); ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Stat returns the connection pool's statistics, or nil when the database
+// isn't using a pool.
+func (db *Database) Stat() *pgxpool.Stat {
+	if p := db.pool(); p != nil {
+		return p.Stat()
+	}
+	return nil
+}
+
+// pool returns the connection pool made by NewDatabase, if any.
+func (db *Database) pool() *pgxpool.Pool {
+	c := db.Conn
+	if t, ok := c.(timedConn); ok {
+		c = t.Conn
+	}
+	p, _ := c.(*pgxpool.Pool)
+	return p
+}
+
+// CountExpensesCreatedSince counts the expenses of every ledger created at
+// or after a time, for metrics.
+func (db *Database) CountExpensesCreatedSince(ctx context.Context, since time.Time) (int, error) {
+	ctx = named(ctx, "CountExpensesCreatedSince")
+	var n int
+	if err := db.Conn.QueryRow(ctx, `SELECT count(*) FROM financeview.expense WHERE createdate >= $1`, since).Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count created expenses, %w", err)
+	}
+	return n, nil
+}
@@ -0,0 +1,140 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+// SoftDeleteExpense moves an expense to the trash.
+func (db *Database) SoftDeleteExpense(ctx context.Context, id int) error {
+	ctx = named(ctx, "SoftDeleteExpense")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return err
+	}
+	sql := `UPDATE financeview.expense SET deletedat=$2 WHERE id=$1 AND ledger_id=$3 AND deletedat IS NULL`
+	ok, err := db.updateExpenseAudited(ctx, model.AuditActionDelete, id, sql, id, time.Now().UTC(), lid)
+	if err != nil {
+		return fmt.Errorf("failed to delete expense id=%v, %w", id, err)
+	}
+	if !ok {
+		return notFound("expense id=%v does not exist", id)
+	}
+	return nil
+}
+
+// RestoreExpense takes an expense back out of the trash.
+func (db *Database) RestoreExpense(ctx context.Context, id int) error {
+	ctx = named(ctx, "RestoreExpense")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return err
+	}
+	sql := `UPDATE financeview.expense SET deletedat=NULL, updatedate=$2 WHERE id=$1 AND ledger_id=$3 AND deletedat IS NOT NULL`
+	ok, err := db.updateExpenseAudited(ctx, model.AuditActionUpdate, id, sql, id, time.Now().UTC(), lid)
+	if err != nil {
+		return fmt.Errorf("failed to restore expense id=%v, %w", id, err)
+	}
+	if !ok {
+		return notFound("expense id=%v is not in the trash", id)
+	}
+	return nil
+}
+
+// ListTrash lists the ledger's deleted expenses.
+func (db *Database) ListTrash(ctx context.Context) ([]model.Expense, error) {
+	ctx = named(ctx, "ListTrash")
+	return db.queryExpenses(ctx, `WHERE e.deletedat IS NOT NULL`)
+}
+
+// PurgeExpenses permanently deletes every expense of any ledger deleted
+// before a time, with its category and tag links and attachments. It returns
+// how many expenses were purged and the storage keys of attachments no
+// longer used by any other attachment.
+func (db *Database) PurgeExpenses(ctx context.Context, before time.Time) (int, []string, error) {
+	ctx = named(ctx, "PurgeExpenses")
+	var n int
+	var keys []string
+	err := db.inTx(ctx, func(tx pgx.Tx) error {
+		now := time.Now().UTC()
+		for _, e := range []model.AuditEntity{model.AuditEntityExpenseCategory, model.AuditEntityExpenseTag} {
+			sql := fmt.Sprintf(`
+				WITH purged AS (
+					DELETE FROM financeview.%s AS l
+					USING financeview.expense AS e
+					WHERE l.expense_id = e.id AND e.deletedat < $1
+					RETURNING l.id, e.id AS expense_id, e.ledger_id, row_to_json(l) AS row
+				)
+				INSERT INTO financeview.audit_log (ledger_id, entity, entity_id, expense_id, action, before, createdate)
+				SELECT ledger_id, $2::text, id, expense_id, $3::text, row, $4::timestamp FROM purged
+			`, auditTables[e])
+			if _, err := tx.Exec(ctx, sql, before, string(e), string(model.AuditActionDelete), now); err != nil {
+				return fmt.Errorf("failed to purge %s rows, %w", auditTables[e], err)
+			}
+		}
+		sql := `
+			DELETE FROM financeview.attachment AS a
+			USING financeview.expense AS e
+			WHERE a.expense_id = e.id AND e.deletedat < $1
+			RETURNING a.storage_key
+		`
+		rows, err := tx.Query(ctx, sql, before)
+		if err != nil {
+			return fmt.Errorf("failed to purge attachments, %w", err)
+		}
+		var purged []string
+		for rows.Next() {
+			var k string
+			if err := rows.Scan(&k); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan purged attachments, %w", err)
+			}
+			purged = append(purged, k)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to read purged attachments, %w", err)
+		}
+		sql = `
+			SELECT DISTINCT k
+			FROM unnest($1::text[]) AS k
+			WHERE NOT EXISTS (SELECT 1 FROM financeview.attachment WHERE storage_key = k)
+		`
+		rows, err = tx.Query(ctx, sql, purged)
+		if err != nil {
+			return fmt.Errorf("failed to query attachment storage keys, %w", err)
+		}
+		for rows.Next() {
+			var k string
+			if err := rows.Scan(&k); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan attachment storage keys, %w", err)
+			}
+			keys = append(keys, k)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to read attachment storage keys, %w", err)
+		}
+		sql = `
+			WITH purged AS (
+				DELETE FROM financeview.expense AS e
+				WHERE e.deletedat < $1
+				RETURNING e.id, e.ledger_id, row_to_json(e) AS row
+			)
+			INSERT INTO financeview.audit_log (ledger_id, entity, entity_id, expense_id, action, before, createdate)
+			SELECT ledger_id, $2::text, id, id, $3::text, row, $4::timestamp FROM purged
+		`
+		tag, err := tx.Exec(ctx, sql, before, string(model.AuditEntityExpense), string(model.AuditActionDelete), now)
+		if err != nil {
+			return fmt.Errorf("failed to purge expenses, %w", err)
+		}
+		n = int(tag.RowsAffected())
+		return nil
+	})
+	return n, keys, err
+}
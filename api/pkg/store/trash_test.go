@@ -0,0 +1,112 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+func TestTrash(t *testing.T) {
+	ctx := testCtx
+	db := Database{conn}
+	defer func() {
+		if err := cleanUpDb(); err != nil {
+			t.Fatalf("failed to clean up test data, %v", err)
+		}
+	}()
+	dt := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	keep, err := db.CreateExpense(ctx, dt, 1, 12.5, "lunch")
+	if err != nil {
+		t.Fatalf("error running CreateExpense func, %v", err)
+	}
+	eid, err := db.CreateExpense(ctx, dt, 1, 3, "coffee")
+	if err != nil {
+		t.Fatalf("error running CreateExpense func, %v", err)
+	}
+	var aid int
+	for _, a := range []model.Attachment{{ExpenseId: eid, Key: "shared"}, {ExpenseId: eid, Key: "only"}, {ExpenseId: keep, Key: "shared"}} {
+		id, err := db.CreateAttachment(ctx, a, func() error { return nil })
+		if err != nil {
+			t.Fatalf("error running CreateAttachment func, %v", err)
+		}
+		if a.ExpenseId == eid {
+			aid = id
+		}
+	}
+	if err := db.SoftDeleteExpense(ctx, eid); err != nil {
+		t.Fatalf("error running SoftDeleteExpense func, %v", err)
+	}
+	assert.Error(t, db.SoftDeleteExpense(ctx, eid))
+	es, err := db.ListAllExpenses(ctx)
+	if err != nil {
+		t.Fatalf("error running ListAllExpenses func, %v", err)
+	}
+	assert.Len(t, es, 1)
+	assert.Equal(t, keep, es[0].Id)
+	_, ok, err := db.GetExpense(ctx, eid)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+	tr, err := db.ListTrash(ctx)
+	if err != nil {
+		t.Fatalf("error running ListTrash func, %v", err)
+	}
+	assert.Len(t, tr, 1)
+	assert.Equal(t, eid, tr[0].Id)
+	assert.NotNil(t, tr[0].DeletedAt)
+	as, err := db.ListAttachments(ctx, eid)
+	assert.Nil(t, err)
+	assert.Empty(t, as, "attachments of trashed expenses are hidden")
+	_, ok, err = db.GetAttachment(ctx, aid)
+	assert.Nil(t, err)
+	assert.False(t, ok)
+
+	if err := db.RestoreExpense(ctx, eid); err != nil {
+		t.Fatalf("error running RestoreExpense func, %v", err)
+	}
+	assert.Error(t, db.RestoreExpense(ctx, eid))
+	_, ok, err = db.GetExpense(ctx, eid)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	as, err = db.ListAttachments(ctx, eid)
+	assert.Nil(t, err)
+	assert.Len(t, as, 2)
+
+	if err := db.SoftDeleteExpense(ctx, eid); err != nil {
+		t.Fatalf("error running SoftDeleteExpense func, %v", err)
+	}
+	n, keys, err := db.PurgeExpenses(ctx, time.Now().UTC().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("error running PurgeExpenses func, %v", err)
+	}
+	assert.Equal(t, 0, n)
+	assert.Empty(t, keys)
+	n, keys, err = db.PurgeExpenses(ctx, time.Now().UTC().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("error running PurgeExpenses func, %v", err)
+	}
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"only"}, keys)
+	tr, err = db.ListTrash(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, tr)
+	as, err = db.ListAttachments(ctx, keep)
+	assert.Nil(t, err)
+	assert.Len(t, as, 1)
+	byExpense, err := db.ListAttachmentsFor(ctx, []int{keep, eid})
+	assert.Nil(t, err)
+	assert.Equal(t, map[int][]model.Attachment{keep: as}, byExpense)
+
+	var released []string
+	for _, k := range []string{"shared", "only"} {
+		k := k
+		if err := db.ReleaseStorageKey(ctx, k, func() error {
+			released = append(released, k)
+			return nil
+		}); err != nil {
+			t.Fatalf("error running ReleaseStorageKey func, %v", err)
+		}
+	}
+	assert.Equal(t, []string{"only"}, released)
+}
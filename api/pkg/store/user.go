@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/ledger"
+)
+
+// CreateUser inserts a new user along with the ledger they own to start with.
+func (db *Database) CreateUser(ctx context.Context, email string, hash string) (int, error) {
+	ctx = named(ctx, "CreateUser")
+	var id int
+	err := db.inTx(ctx, func(tx pgx.Tx) error {
+		sql := `INSERT INTO financeview.app_user (email, password_hash, createdate) VALUES ($1, $2, $3) RETURNING id`
+		if err := tx.QueryRow(ctx, sql, email, hash, time.Now().UTC()).Scan(&id); err != nil {
+			return fmt.Errorf("failed to insert new user into database, %w", err)
+		}
+		_, err := createLedger(ctx, tx, ledger.DefaultName, id)
+		return err
+	})
+	return id, err
+}
+
+// GetUserByEmail returns the user and their password hash.
+func (db *Database) GetUserByEmail(ctx context.Context, email string) (model.User, string, bool, error) {
+	ctx = named(ctx, "GetUserByEmail")
+	sql := `SELECT id, email, password_hash FROM financeview.app_user WHERE email=$1`
+	var u User
+	if err := db.Conn.QueryRow(ctx, sql, email).Scan(&u.Id, &u.Email, &u.PasswordHash); err != nil {
+		if err == pgx.ErrNoRows {
+			return model.User{}, "", false, nil
+		}
+		return model.User{}, "", false, fmt.Errorf("failed to query user table, %w", err)
+	}
+	return model.User{Id: int(u.Id.Int), Email: u.Email.String}, u.PasswordHash.String, true, nil
+}
+
+func (db *Database) CreateSession(ctx context.Context, uid int, tokenHash string, expires time.Time) error {
+	ctx = named(ctx, "CreateSession")
+	sql := `INSERT INTO financeview.session (user_id, token_hash, expiresat, createdate) VALUES ($1, $2, $3, $4)`
+	if _, err := db.Conn.Exec(ctx, sql, uid, tokenHash, expires, time.Now().UTC()); err != nil {
+		return fmt.Errorf("failed to insert new session into database, %w", err)
+	}
+	return nil
+}
+
+// GetSessionUser returns the user of a session that has not expired by now.
+func (db *Database) GetSessionUser(ctx context.Context, tokenHash string, now time.Time) (model.User, bool, error) {
+	ctx = named(ctx, "GetSessionUser")
+	sql := `
+		SELECT u.id, u.email
+		FROM financeview.session AS s
+		INNER JOIN financeview.app_user AS u
+		ON s.user_id = u.id
+		WHERE s.token_hash=$1 AND s.expiresat > $2
+	`
+	var u User
+	if err := db.Conn.QueryRow(ctx, sql, tokenHash, now).Scan(&u.Id, &u.Email); err != nil {
+		if err == pgx.ErrNoRows {
+			return model.User{}, false, nil
+		}
+		return model.User{}, false, fmt.Errorf("failed to query session table, %w", err)
+	}
+	return model.User{Id: int(u.Id.Int), Email: u.Email.String}, true, nil
+}
+
+func (db *Database) DeleteSession(ctx context.Context, tokenHash string) error {
+	ctx = named(ctx, "DeleteSession")
+	if _, err := db.Conn.Exec(ctx, `DELETE FROM financeview.session WHERE token_hash=$1`, tokenHash); err != nil {
+		return fmt.Errorf("failed to delete session, %w", err)
+	}
+	return nil
+}
+
+type User struct {
+	Id           pgtype.Int4
+	Email        pgtype.Text
+	PasswordHash pgtype.Text
+}
@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgtype"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+// CreateSavedView inserts a saved view, storing its filter as JSON. Names
+// are unique in a ledger.
+func (db *Database) CreateSavedView(ctx context.Context, v model.SavedView) (int, error) {
+	ctx = named(ctx, "CreateSavedView")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return 0, err
+	}
+	f, sort, groupBy, err := viewColumns(v)
+	if err != nil {
+		return 0, err
+	}
+	sql := `
+		INSERT INTO financeview.saved_view (ledger_id, name, filter, sort, group_by, createdate)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+	var id int
+	if err := db.Conn.QueryRow(ctx, sql, lid, v.Name, f, sort, groupBy, time.Now().UTC()).Scan(&id); err != nil {
+		return 0, fmt.Errorf("failed to insert new saved view into database, %w", err)
+	}
+	return id, nil
+}
+
+func (db *Database) UpdateSavedView(ctx context.Context, v model.SavedView) error {
+	ctx = named(ctx, "UpdateSavedView")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return err
+	}
+	f, sort, groupBy, err := viewColumns(v)
+	if err != nil {
+		return err
+	}
+	sql := `
+		UPDATE financeview.saved_view
+		SET name=$2, filter=$3, sort=$4, group_by=$5, updatedate=$6
+		WHERE id=$1 AND ledger_id=$7
+	`
+	tag, err := db.Conn.Exec(ctx, sql, v.Id, v.Name, f, sort, groupBy, time.Now().UTC(), lid)
+	if err != nil {
+		return fmt.Errorf("failed to update saved view id=%v, %w", v.Id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return notFound("saved view id=%v does not exist", v.Id)
+	}
+	return nil
+}
+
+func (db *Database) DeleteSavedView(ctx context.Context, id int) error {
+	ctx = named(ctx, "DeleteSavedView")
+	lid, err := ledgerId(ctx)
+	if err != nil {
+		return err
+	}
+	tag, err := db.Conn.Exec(ctx, `DELETE FROM financeview.saved_view WHERE id=$1 AND ledger_id=$2`, id, lid)
+	if err != nil {
+		return fmt.Errorf("failed to delete saved view id=%v, %w", id, err)
+	}
+	if tag.RowsAffected() == 0 {
+		return notFound("saved view id=%v does not exist", id)
+	}
+	return nil
+}
+
+func (db *Database) GetSavedView(ctx context.Context, id int) (model.SavedView, bool, error) {
+	ctx = named(ctx, "GetSavedView")
+	vs, err := db.querySavedViews(ctx, `WHERE v.id = $1`, id)
+	if err != nil {
+		return model.SavedView{}, false, err
+	}
+	if len(vs) == 0 {
+		return model.SavedView{}, false, nil
+	}
+	return vs[0], true, nil
+}
+
+func (db *Database) ListSavedViews(ctx context.Context) ([]model.SavedView, error) {
+	ctx = named(ctx, "ListSavedViews")
+	return db.querySavedViews(ctx, ``)
+}
+
+// querySavedViews selects the ledger's saved views by name, filtered by the
+// optional where clause.
+func (db *Database) querySavedViews(ctx context.Context, where string, args ...interface{}) ([]model.SavedView, error) {
+	where, args, err := scope(ctx, "v.ledger_id", where, args)
+	if err != nil {
+		return nil, err
+	}
+	sql := `
+		SELECT v.id, v.name, v.filter, v.sort, v.group_by
+		FROM financeview.saved_view AS v
+		` + where + `
+		ORDER BY v.name, v.id
+	`
+	var vs []model.SavedView
+	rows, err := db.Conn.Query(ctx, sql, args...)
+	if err != nil {
+		return vs, fmt.Errorf("failed to select saved views from database, %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v model.SavedView
+		var f []byte
+		var sort, groupBy pgtype.Text
+		if err := rows.Scan(&v.Id, &v.Name, &f, &sort, &groupBy); err != nil {
+			return vs, fmt.Errorf("failed to scan saved views from database, %w", err)
+		}
+		if err := json.Unmarshal(f, &v.Filter); err != nil {
+			return vs, fmt.Errorf("failed to decode filter of saved view id=%v, %w", v.Id, err)
+		}
+		if sort.Status == pgtype.Present {
+			s := model.ExpenseSort(sort.String)
+			v.Sort = &s
+		}
+		if groupBy.Status == pgtype.Present {
+			g := model.SummaryGroupBy(groupBy.String)
+			v.GroupBy = &g
+		}
+		vs = append(vs, v)
+	}
+	if err := rows.Err(); err != nil {
+		return vs, fmt.Errorf("failed to read saved views from database, %w", err)
+	}
+	return vs, nil
+}
+
+// viewColumns returns the filter, sort and group_by columns of a saved view.
+func viewColumns(v model.SavedView) ([]byte, *string, *string, error) {
+	f, err := json.Marshal(v.Filter)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to encode saved view filter, %w", err)
+	}
+	var sort, groupBy *string
+	if v.Sort != nil {
+		s := v.Sort.String()
+		sort = &s
+	}
+	if v.GroupBy != nil {
+		g := v.GroupBy.String()
+		groupBy = &g
+	}
+	return f, sort, groupBy, nil
+}
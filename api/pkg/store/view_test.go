@@ -0,0 +1,110 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+func TestSavedViews(t *testing.T) {
+	ctx := testCtx
+	db := Database{conn}
+	defer func() {
+		if err := cleanUpDb(); err != nil {
+			t.Fatalf("failed to clean up test data, %v", err)
+		}
+	}()
+	quarter := model.PeriodThisQuarter
+	min := 50.0
+	byCategory := model.SummaryGroupByCategory
+	v := model.SavedView{
+		Name:    "restaurants",
+		Filter:  model.ExpenseFilter{Categories: []string{"restaurants"}, Period: &quarter, MinAmount: &min},
+		GroupBy: &byCategory,
+	}
+	id, err := db.CreateSavedView(ctx, v)
+	if err != nil {
+		t.Fatalf("error running CreateSavedView func, %v", err)
+	}
+	v.Id = id
+	actual, ok, err := db.GetSavedView(ctx, id)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, v, actual)
+
+	_, err = db.CreateSavedView(ctx, model.SavedView{Name: "restaurants"})
+	var ce *ConflictError
+	assert.True(t, errors.As(err, &ce), "names are unique")
+
+	desc := model.ExpenseSortAmountDesc
+	v.Name, v.Sort, v.GroupBy = "big restaurants", &desc, nil
+	if err := db.UpdateSavedView(ctx, v); err != nil {
+		t.Fatalf("error running UpdateSavedView func, %v", err)
+	}
+	vs, err := db.ListSavedViews(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []model.SavedView{v}, vs)
+
+	if err := db.DeleteSavedView(ctx, id); err != nil {
+		t.Fatalf("error running DeleteSavedView func, %v", err)
+	}
+	var nf *NotFoundError
+	assert.True(t, errors.As(db.DeleteSavedView(ctx, id), &nf))
+	assert.True(t, errors.As(db.UpdateSavedView(ctx, v), &nf))
+}
+
+func TestFindExpensesByRange(t *testing.T) {
+	ctx := testCtx
+	db := Database{conn}
+	defer func() {
+		if err := cleanUpDb(); err != nil {
+			t.Fatalf("failed to clean up test data, %v", err)
+		}
+	}()
+	saved, err := db.CreateExpenses(ctx, []model.Expense{
+		{Date: "03-01-2022", Description: "sushi", Amount: 64, Categories: []model.Category{{Name: "restaurants"}}},
+		{Date: "03-05-2022", Description: "burger", Amount: 18, Categories: []model.Category{{Name: "restaurants"}}},
+		{Date: "04-02-2022", Description: "tapas", Amount: 90, Categories: []model.Category{{Name: "restaurants"}}},
+		{Date: "03-07-2022", Description: "groceries", Amount: 120, Categories: []model.Category{{Name: "food"}}},
+	})
+	if err != nil {
+		t.Fatalf("failed to setup test data, %v", err)
+	}
+	from, to := "03-01-2022", "03-31-2022"
+	min, max := 50.0, 100.0
+	cases := []struct {
+		name   string
+		filter model.ExpenseFilter
+		want   []int
+	}{
+		{name: "categories", filter: model.ExpenseFilter{Categories: []string{"restaurants"}}, want: []int{saved[0].Id, saved[1].Id, saved[2].Id}},
+		{name: "dates", filter: model.ExpenseFilter{From: &from, To: &to}, want: []int{saved[0].Id, saved[1].Id, saved[3].Id}},
+		{name: "amounts", filter: model.ExpenseFilter{MinAmount: &min, MaxAmount: &max}, want: []int{saved[0].Id, saved[2].Id}},
+		{
+			name:   "everything",
+			filter: model.ExpenseFilter{Categories: []string{"restaurants"}, From: &from, To: &to, MinAmount: &min},
+			want:   []int{saved[0].Id},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			exps, err := db.FindExpenses(ctx, c.filter)
+			if err != nil {
+				t.Fatalf("error running FindExpenses func, %v", err)
+			}
+			var ids []int
+			for _, e := range exps {
+				ids = append(ids, e.Id)
+			}
+			assert.ElementsMatch(t, c.want, ids)
+		})
+	}
+	sum, err := db.SummarizeExpenses(ctx, model.SummaryGroupByCategory, model.ExpenseFilter{From: &from, To: &to})
+	assert.Nil(t, err)
+	assert.Equal(t, []model.SummaryRow{
+		{Key: "food", Total: 120, Count: 1},
+		{Key: "restaurants", Total: 82, Count: 2},
+	}, sum)
+}
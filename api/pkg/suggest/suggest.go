@@ -0,0 +1,271 @@
+package suggest
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/ledger"
+)
+
+const maxSuggestions = 5
+
+type Database interface {
+	ListAllExpenses(context.Context) ([]model.Expense, error)
+}
+
+// Model is a multinomial naive-Bayes classifier over description tokens and
+// an amount bucket. Every category linked to an expense is counted as a
+// separate observation of that expense's features.
+type Model struct {
+	mu         sync.RWMutex
+	categories map[string]model.Category
+	catCount   map[string]int
+	featCount  map[string]map[string]int
+	featTotal  map[string]int
+	vocab      map[string]struct{}
+	total      int
+}
+
+func NewModel() *Model {
+	return &Model{
+		categories: make(map[string]model.Category),
+		catCount:   make(map[string]int),
+		featCount:  make(map[string]map[string]int),
+		featTotal:  make(map[string]int),
+		vocab:      make(map[string]struct{}),
+	}
+}
+
+// LoadModel trains a new model from every expense already in the database.
+func LoadModel(ctx context.Context, db Database) (*Model, error) {
+	exps, err := db.ListAllExpenses(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list expenses for category model, %w", err)
+	}
+	m := NewModel()
+	for _, e := range exps {
+		m.Observe(e)
+	}
+	return m, nil
+}
+
+// Registry keeps a separate model for each ledger, trained from that ledger's
+// expenses the first time it is needed. Ledgers load independently, so one
+// ledger's training doesn't hold up suggestions for the others.
+type Registry struct {
+	db     Database
+	mu     sync.Mutex
+	models map[int]*entry
+}
+
+// entry is a ledger's model, set along with err once ready is closed.
+type entry struct {
+	ready chan struct{}
+	m     *Model
+	err   error
+}
+
+func NewRegistry(db Database) *Registry {
+	return &Registry{db: db, models: make(map[int]*entry)}
+}
+
+// Model returns the model of the ledger in the context, training it if this
+// is the first time it is needed. A failed training is retried on the next
+// call.
+func (r *Registry) Model(ctx context.Context) (*Model, error) {
+	lid, err := ledger.Id(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	e, ok := r.models[lid]
+	if !ok {
+		e = &entry{ready: make(chan struct{})}
+		r.models[lid] = e
+	}
+	r.mu.Unlock()
+	if ok {
+		select {
+		case <-e.ready:
+			return e.m, e.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	e.m, e.err = LoadModel(ctx, r.db)
+	if e.err != nil {
+		r.mu.Lock()
+		delete(r.models, lid)
+		r.mu.Unlock()
+	}
+	close(e.ready)
+	return e.m, e.err
+}
+
+// loaded returns the ledger's model if it has finished training.
+func (r *Registry) loaded(ctx context.Context) (*Model, bool) {
+	lid, err := ledger.Id(ctx)
+	if err != nil {
+		return nil, false
+	}
+	r.mu.Lock()
+	e, ok := r.models[lid]
+	r.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+	select {
+	case <-e.ready:
+		return e.m, e.err == nil
+	default:
+		return nil, false
+	}
+}
+
+// Observe adds a saved expense to its ledger's model. Models that haven't
+// been trained yet will read it from the database instead.
+func (r *Registry) Observe(ctx context.Context, e model.Expense) {
+	if m, ok := r.loaded(ctx); ok {
+		m.Observe(e)
+	}
+}
+
+// Forget takes an expense back out of its ledger's model, as when it is
+// moved to the trash. Trained models leave trashed expenses out, so they
+// have nothing to forget when the trash is purged.
+func (r *Registry) Forget(ctx context.Context, e model.Expense) {
+	if m, ok := r.loaded(ctx); ok {
+		m.Forget(e)
+	}
+}
+
+// Update replaces what the ledger's model learned from an expense before it
+// was changed with the expense as it is now.
+func (r *Registry) Update(ctx context.Context, before model.Expense, after model.Expense) {
+	if m, ok := r.loaded(ctx); ok {
+		m.Forget(before)
+		m.Observe(after)
+	}
+}
+
+// Observe adds a saved expense to the model.
+func (m *Model) Observe(e model.Expense) {
+	feats := features(e.Description, e.Amount)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range e.Categories {
+		m.categories[c.Name] = c
+		m.catCount[c.Name]++
+		m.total++
+		if m.featCount[c.Name] == nil {
+			m.featCount[c.Name] = make(map[string]int)
+		}
+		for _, f := range feats {
+			m.featCount[c.Name][f]++
+			m.featTotal[c.Name]++
+			m.vocab[f] = struct{}{}
+		}
+	}
+}
+
+// Forget takes an expense observed before back out of the model.
+func (m *Model) Forget(e model.Expense) {
+	feats := features(e.Description, e.Amount)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, c := range e.Categories {
+		if m.catCount[c.Name] == 0 {
+			continue
+		}
+		m.total--
+		if m.catCount[c.Name]--; m.catCount[c.Name] == 0 {
+			delete(m.catCount, c.Name)
+			delete(m.categories, c.Name)
+		}
+		for _, f := range feats {
+			if m.featCount[c.Name][f] > 0 {
+				m.featCount[c.Name][f]--
+				m.featTotal[c.Name]--
+			}
+		}
+	}
+}
+
+// Suggest returns the most likely categories for a description and amount,
+// best first, with scores that sum to at most one.
+func (m *Model) Suggest(desc string, amt float64) []*model.CategorySuggestion {
+	feats := features(desc, amt)
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if m.total == 0 || len(feats) == 0 {
+		return []*model.CategorySuggestion{}
+	}
+	v := float64(len(m.vocab))
+	logs := make(map[string]float64, len(m.catCount))
+	best := math.Inf(-1)
+	for c, n := range m.catCount {
+		lp := math.Log(float64(n) / float64(m.total))
+		for _, f := range feats {
+			lp += math.Log((float64(m.featCount[c][f]) + 1) / (float64(m.featTotal[c]) + v))
+		}
+		logs[c] = lp
+		if lp > best {
+			best = lp
+		}
+	}
+	var sum float64
+	for c, lp := range logs {
+		logs[c] = math.Exp(lp - best)
+		sum += logs[c]
+	}
+	sgs := make([]*model.CategorySuggestion, 0, len(logs))
+	for c, p := range logs {
+		sgs = append(sgs, &model.CategorySuggestion{
+			Category: m.categories[c],
+			Score:    p / sum,
+		})
+	}
+	sort.Slice(sgs, func(i, j int) bool {
+		if sgs[i].Score == sgs[j].Score {
+			return sgs[i].Category.Name < sgs[j].Category.Name
+		}
+		return sgs[i].Score > sgs[j].Score
+	})
+	if len(sgs) > maxSuggestions {
+		sgs = sgs[:maxSuggestions]
+	}
+	return sgs
+}
+
+// features splits a description into lower case word tokens, ignoring
+// numbers such as store or transaction ids, and adds a coarse amount bucket.
+func features(desc string, amt float64) []string {
+	var feats []string
+	for _, t := range strings.FieldsFunc(strings.ToLower(desc), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(t)) < 2 || isNumber(t) {
+			continue
+		}
+		feats = append(feats, "w:"+t)
+	}
+	if amt != 0 && !math.IsNaN(amt) && !math.IsInf(amt, 0) {
+		feats = append(feats, fmt.Sprintf("a:%d", int(math.Log2(math.Abs(amt)+1))))
+	}
+	return feats
+}
+
+func isNumber(s string) bool {
+	for _, r := range s {
+		if !unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,151 @@
+package suggest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/ledger"
+)
+
+type MockDatabase struct {
+	exps []model.Expense
+	// wait holds up loading a ledger's expenses until it is closed.
+	wait map[int]chan struct{}
+}
+
+func (mdb *MockDatabase) ListAllExpenses(ctx context.Context) ([]model.Expense, error) {
+	lid, _ := ledger.Id(ctx)
+	if w, ok := mdb.wait[lid]; ok {
+		<-w
+	}
+	return mdb.exps, nil
+}
+
+func TestLoadModel(t *testing.T) {
+	groc := model.Category{Id: 1, Name: "groceries"}
+	dine := model.Category{Id: 2, Name: "restaurants"}
+	mock := MockDatabase{
+		exps: []model.Expense{
+			{Id: 1, Description: "Whole Foods Market #123", Amount: 85.20, Categories: []model.Category{groc}},
+			{Id: 2, Description: "WHOLE FOODS MKT", Amount: 64.10, Categories: []model.Category{groc}},
+			{Id: 3, Description: "Chipotle 0456", Amount: 12.75, Categories: []model.Category{dine}},
+			{Id: 4, Description: "Thai Garden", Amount: 38.00, Categories: []model.Category{dine}},
+		},
+	}
+	m, err := LoadModel(context.Background(), &mock)
+	if err != nil {
+		t.Fatalf("error running LoadModel func, %v", err)
+	}
+	t.Run("similar description", func(t *testing.T) {
+		actual := m.Suggest("whole foods #998", 70)
+		assert.Len(t, actual, 2)
+		assert.Equal(t, groc, actual[0].Category)
+		assert.Greater(t, actual[0].Score, actual[1].Score)
+	})
+	t.Run("same description", func(t *testing.T) {
+		actual := m.Suggest("Chipotle 0456", 12.75)
+		assert.Equal(t, dine, actual[0].Category)
+	})
+	t.Run("nothing to go on", func(t *testing.T) {
+		assert.Empty(t, m.Suggest("", 0))
+	})
+}
+
+func TestObserve(t *testing.T) {
+	m := NewModel()
+	assert.Empty(t, m.Suggest("netflix", 15.49))
+	subs := model.Category{Id: 7, Name: "subscriptions"}
+	m.Observe(model.Expense{Description: "NETFLIX.COM", Amount: 15.49, Categories: []model.Category{subs}})
+	actual := m.Suggest("netflix", 15.49)
+	assert.Equal(t, []*model.CategorySuggestion{{Category: subs, Score: 1}}, actual)
+}
+
+func TestRegistry(t *testing.T) {
+	groc := model.Category{Id: 1, Name: "groceries"}
+	mock := MockDatabase{
+		exps: []model.Expense{
+			{Id: 1, Description: "Whole Foods", Amount: 85.20, Categories: []model.Category{groc}},
+		},
+	}
+	r := NewRegistry(&mock)
+	_, err := r.Model(context.Background())
+	assert.ErrorIs(t, err, ledger.ErrNoLedger)
+	ctx := ledger.WithLedger(context.Background(), 4, model.RoleOwner)
+	m, err := r.Model(ctx)
+	if err != nil {
+		t.Fatalf("error running Model func, %v", err)
+	}
+	assert.Equal(t, groc, m.Suggest("whole foods", 0)[0].Category)
+	again, err := r.Model(ctx)
+	if err != nil {
+		t.Fatalf("error running Model func, %v", err)
+	}
+	assert.Same(t, m, again)
+	other, err := r.Model(ledger.WithLedger(context.Background(), 5, model.RoleViewer))
+	if err != nil {
+		t.Fatalf("error running Model func, %v", err)
+	}
+	assert.NotSame(t, m, other)
+}
+
+func TestRegistryLoadsLedgersSeparately(t *testing.T) {
+	mock := MockDatabase{wait: map[int]chan struct{}{4: make(chan struct{})}}
+	r := NewRegistry(&mock)
+	slow := ledger.WithLedger(context.Background(), 4, model.RoleOwner)
+	loaded := make(chan *Model)
+	go func() {
+		m, _ := r.Model(slow)
+		loaded <- m
+	}()
+	_, err := r.Model(ledger.WithLedger(context.Background(), 5, model.RoleOwner))
+	assert.Nil(t, err, "ledger 5 loads while ledger 4 is still loading")
+	r.Observe(slow, model.Expense{Description: "ignored until loaded"})
+	close(mock.wait[4])
+	m := <-loaded
+	again, err := r.Model(slow)
+	assert.Nil(t, err)
+	assert.Same(t, m, again)
+	assert.Empty(t, m.Suggest("ignored until loaded", 0))
+}
+
+func TestRegistryUpdate(t *testing.T) {
+	groc := model.Category{Id: 1, Name: "groceries"}
+	dine := model.Category{Id: 2, Name: "restaurants"}
+	before := model.Expense{Id: 1, Description: "Corner Deli", Amount: 12, Categories: []model.Category{groc}}
+	mock := MockDatabase{exps: []model.Expense{before}}
+	r := NewRegistry(&mock)
+	ctx := ledger.WithLedger(context.Background(), 4, model.RoleOwner)
+	m, err := r.Model(ctx)
+	if err != nil {
+		t.Fatalf("error running Model func, %v", err)
+	}
+	after := before
+	after.Categories = []model.Category{dine}
+	r.Update(ctx, before, after)
+	assert.Equal(t, []*model.CategorySuggestion{{Category: dine, Score: 1}}, m.Suggest("corner deli", 12))
+
+	r.Forget(ctx, after)
+	assert.Empty(t, m.Suggest("corner deli", 12), "trashed expenses don't shape suggestions")
+	r.Observe(ctx, after)
+	assert.Equal(t, []*model.CategorySuggestion{{Category: dine, Score: 1}}, m.Suggest("corner deli", 12))
+}
+
+func Test_features(t *testing.T) {
+	cases := []struct {
+		name string
+		desc string
+		amt  float64
+		want []string
+	}{
+		{name: "drops numbers", desc: "AMZN Mktp US*2K3 4451", amt: 0, want: []string{"w:amzn", "w:mktp", "w:us", "w:2k3"}},
+		{name: "amount bucket", desc: "gas", amt: 40, want: []string{"w:gas", "a:5"}},
+		{name: "empty", desc: "", amt: 0, want: nil},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, features(c.desc, c.amt))
+		})
+	}
+}
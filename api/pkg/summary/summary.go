@@ -0,0 +1,36 @@
+package summary
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/expense"
+)
+
+type Database interface {
+	SummarizeExpenses(context.Context, model.SummaryGroupBy, model.ExpenseFilter) ([]model.SummaryRow, error)
+}
+
+// Summarize totals the expenses matching the optional filter by the
+// grouping.
+func Summarize(ctx context.Context, groupBy model.SummaryGroupBy, filter *model.ExpenseFilter, db Database) ([]*model.SummaryRow, error) {
+	var f model.ExpenseFilter
+	if filter != nil {
+		f = *filter
+	}
+	f, err := expense.ResolveFilter(f, time.Now().UTC())
+	if err != nil {
+		return []*model.SummaryRow{}, err
+	}
+	rows, err := db.SummarizeExpenses(ctx, groupBy, f)
+	if err != nil {
+		return []*model.SummaryRow{}, fmt.Errorf("failed to summarize expenses, %w", err)
+	}
+	sum := []*model.SummaryRow{}
+	for i := range rows {
+		sum = append(sum, &rows[i])
+	}
+	return sum, nil
+}
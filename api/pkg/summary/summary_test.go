@@ -0,0 +1,57 @@
+package summary
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/expense"
+)
+
+type MockDatabase struct {
+	rows   map[model.SummaryGroupBy][]model.SummaryRow
+	filter model.ExpenseFilter
+}
+
+func (mdb *MockDatabase) SummarizeExpenses(ctx context.Context, groupBy model.SummaryGroupBy, f model.ExpenseFilter) ([]model.SummaryRow, error) {
+	mdb.filter = f
+	return mdb.rows[groupBy], nil
+}
+
+func TestSummarize(t *testing.T) {
+	mock := MockDatabase{
+		rows: map[model.SummaryGroupBy][]model.SummaryRow{
+			model.SummaryGroupByPayee: {
+				{Key: "Amazon", Total: 15, Count: 2},
+				{Key: "Target", Total: 4.5, Count: 1},
+			},
+		},
+	}
+	actual, err := Summarize(context.Background(), model.SummaryGroupByPayee, nil, &mock)
+	if err != nil {
+		t.Fatalf("error running Summarize func, %v", err)
+	}
+	want := []*model.SummaryRow{
+		{Key: "Amazon", Total: 15, Count: 2},
+		{Key: "Target", Total: 4.5, Count: 1},
+	}
+	assert.Equal(t, want, actual)
+	actual, err = Summarize(context.Background(), model.SummaryGroupByCategory, nil, &mock)
+	if err != nil {
+		t.Fatalf("error running Summarize func, %v", err)
+	}
+	assert.Empty(t, actual)
+}
+
+func TestSummarizeFilter(t *testing.T) {
+	mock := MockDatabase{}
+	_, err := Summarize(context.Background(), model.SummaryGroupByCategory, &model.ExpenseFilter{Tags: []string{" Trip "}}, &mock)
+	assert.Nil(t, err)
+	assert.Equal(t, model.ExpenseFilter{Tags: []string{"trip"}}, mock.filter)
+
+	bad := "2022-01-01"
+	_, err = Summarize(context.Background(), model.SummaryGroupByCategory, &model.ExpenseFilter{From: &bad}, &mock)
+	var ves expense.ValidationErrors
+	assert.ErrorAs(t, err, &ves)
+}
@@ -0,0 +1,178 @@
+package tax
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+// Report is a tax summary with the attachments of its expenses, by expense
+// id, for exporting.
+type Report struct {
+	Summary     model.TaxSummary
+	Attachments map[int][]model.Attachment
+}
+
+func Load(ctx context.Context, year int, db Database) (Report, error) {
+	s, err := Summarize(ctx, year, db)
+	if err != nil {
+		return Report{}, err
+	}
+	var eids []int
+	for _, g := range s.Groups {
+		for _, e := range g.Expenses {
+			eids = append(eids, e.Id)
+		}
+	}
+	r := Report{Summary: s, Attachments: make(map[int][]model.Attachment)}
+	if len(eids) == 0 {
+		return r, nil
+	}
+	if r.Attachments, err = db.ListAttachmentsFor(ctx, eids); err != nil {
+		return Report{}, fmt.Errorf("failed to list attachments of the tax summary's expenses, %w", err)
+	}
+	return r, nil
+}
+
+// WriteCSV writes a row for each expense of the report, grouped by tax
+// category, naming its attachments. Text cells are escaped so spreadsheets
+// don't run them as formulas.
+func WriteCSV(w io.Writer, r Report) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"Tax Category", "Date", "Description", "Categories", "Amount", "Attachments"})
+	for _, g := range r.Summary.Groups {
+		for _, e := range g.Expenses {
+			cw.Write([]string{
+				g.TaxCategory.String(),
+				e.Date,
+				cell(e.Description),
+				cell(strings.Join(categoryNames(*e), "; ")),
+				strconv.FormatFloat(e.Amount, 'f', 2, 64),
+				cell(strings.Join(filenames(r.Attachments[e.Id]), "; ")),
+			})
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("failed to write tax summary csv, %w", err)
+	}
+	return nil
+}
+
+// cell prefixes s with a quote when a spreadsheet would read it as a
+// formula.
+func cell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// WritePDF writes the report as a printable A4 document: a table of each
+// tax category's expenses with their attachments, its total, and the
+// year's total.
+//
+// It uses gofpdf, which is archived. v1.16.2 is its final release and only
+// needs the standard library, and the core fonts and tables used here
+// haven't changed in years. github.com/go-pdf/fpdf is its
+// maintained fork with the same API, so moving to it is a change of import
+// path if gofpdf ever needs a fix.
+func WritePDF(w io.Writer, r Report) error {
+	const (
+		dateW, descW, catW, amtW = 25.0, 95.0, 45.0, 25.0
+		rowH                     = 6.0
+	)
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	// Core fonts are encoded in cp1252, not UTF-8.
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetTitle(fmt.Sprintf("Tax summary %d", r.Summary.Year), true)
+	pdf.AddPage()
+	pdf.SetFont("Helvetica", "B", 16)
+	pdf.CellFormat(0, 10, fmt.Sprintf("Tax summary %d", r.Summary.Year), "", 1, "L", false, 0, "")
+	if len(r.Summary.Groups) == 0 {
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.CellFormat(0, rowH, "No deductible expenses.", "", 1, "L", false, 0, "")
+	}
+	for _, g := range r.Summary.Groups {
+		pdf.Ln(4)
+		pdf.SetFont("Helvetica", "B", 12)
+		pdf.CellFormat(0, 8, title(g.TaxCategory), "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.SetFillColor(230, 230, 230)
+		pdf.CellFormat(dateW, rowH, "Date", "B", 0, "L", true, 0, "")
+		pdf.CellFormat(descW, rowH, "Description", "B", 0, "L", true, 0, "")
+		pdf.CellFormat(catW, rowH, "Categories", "B", 0, "L", true, 0, "")
+		pdf.CellFormat(amtW, rowH, "Amount", "B", 1, "R", true, 0, "")
+		for _, e := range g.Expenses {
+			pdf.SetFont("Helvetica", "", 9)
+			pdf.CellFormat(dateW, rowH, e.Date, "", 0, "L", false, 0, "")
+			pdf.CellFormat(descW, rowH, fit(pdf, tr(e.Description), descW), "", 0, "L", false, 0, "")
+			pdf.CellFormat(catW, rowH, fit(pdf, tr(strings.Join(categoryNames(*e), ", ")), catW), "", 0, "L", false, 0, "")
+			pdf.CellFormat(amtW, rowH, money(e.Amount), "", 1, "R", false, 0, "")
+			if names := filenames(r.Attachments[e.Id]); len(names) > 0 {
+				pdf.SetFont("Helvetica", "I", 8)
+				pdf.SetTextColor(100, 100, 100)
+				pdf.CellFormat(dateW, rowH-1, "", "", 0, "L", false, 0, "")
+				pdf.MultiCell(descW+catW, rowH-1, tr("Attachments: "+strings.Join(names, ", ")), "", "L", false)
+				pdf.SetTextColor(0, 0, 0)
+			}
+		}
+		pdf.SetFont("Helvetica", "B", 9)
+		pdf.CellFormat(dateW+descW+catW, rowH, fmt.Sprintf("Total, %d expenses", g.Count), "T", 0, "L", false, 0, "")
+		pdf.CellFormat(amtW, rowH, money(g.Total), "T", 1, "R", false, 0, "")
+	}
+	pdf.Ln(6)
+	pdf.SetFont("Helvetica", "B", 11)
+	pdf.CellFormat(dateW+descW+catW, 8, "Total deductible", "", 0, "L", false, 0, "")
+	pdf.CellFormat(amtW, 8, money(r.Summary.Total), "", 1, "R", false, 0, "")
+	if err := pdf.Output(w); err != nil {
+		return fmt.Errorf("failed to write tax summary pdf, %w", err)
+	}
+	return nil
+}
+
+// fit shortens s with an ellipsis until it fits a width in the current font.
+func fit(pdf *gofpdf.Fpdf, s string, width float64) string {
+	width -= 2 * pdf.GetCellMargin()
+	if pdf.GetStringWidth(s) <= width {
+		return s
+	}
+	for len(s) > 0 && pdf.GetStringWidth(s+"...") > width {
+		s = s[:len(s)-1]
+	}
+	return s + "..."
+}
+
+// title returns a tax category as a heading, e.g. "Charitable".
+func title(tc model.TaxCategory) string {
+	s := strings.ToLower(tc.String())
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func money(a float64) string {
+	return "$" + strconv.FormatFloat(a, 'f', 2, 64)
+}
+
+func categoryNames(e model.Expense) []string {
+	var names []string
+	for _, c := range e.Categories {
+		names = append(names, c.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func filenames(as []model.Attachment) []string {
+	var names []string
+	for _, a := range as {
+		names = append(names, a.Filename)
+	}
+	return names
+}
@@ -0,0 +1,111 @@
+package tax
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/expense"
+	"github.com/vapor05/financeview/pkg/view"
+)
+
+type Database interface {
+	GetExpense(context.Context, int) (model.Expense, bool, error)
+	FindExpenses(context.Context, model.ExpenseFilter) ([]model.Expense, error)
+	ListAttachmentsFor(context.Context, []int) (map[int][]model.Attachment, error)
+	SetCategoryTaxCategory(context.Context, string, *model.TaxCategory) (model.Category, error)
+	SetExpenseTaxCategory(context.Context, int, *model.TaxCategory) error
+}
+
+func SetCategory(ctx context.Context, name string, tc *model.TaxCategory, db Database) (model.Category, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return model.Category{}, expense.ValidationErrors{{Field: "name", Message: "must not be empty"}}
+	}
+	c, err := db.SetCategoryTaxCategory(ctx, name, tc)
+	if err != nil {
+		return model.Category{}, fmt.Errorf("failed to set category tax category, %w", err)
+	}
+	return c, nil
+}
+
+func SetExpense(ctx context.Context, eid int, tc *model.TaxCategory, db Database) (model.Expense, error) {
+	if err := db.SetExpenseTaxCategory(ctx, eid, tc); err != nil {
+		return model.Expense{}, fmt.Errorf("failed to set expense tax category, %w", err)
+	}
+	e, ok, err := db.GetExpense(ctx, eid)
+	if err != nil {
+		return model.Expense{}, fmt.Errorf("failed to get expense id=%v, %w", eid, err)
+	}
+	if !ok {
+		return model.Expense{}, &expense.NotFoundError{Id: eid}
+	}
+	return e, nil
+}
+
+// Effective returns the tax category an expense is deductible under: its
+// own, or else the first of its categories' by category name. It is nil for
+// expenses that aren't deductible.
+func Effective(e model.Expense) *model.TaxCategory {
+	if e.TaxCategory != nil {
+		return e.TaxCategory
+	}
+	var tc *model.TaxCategory
+	var name string
+	for _, c := range e.Categories {
+		if c.TaxCategory != nil && (tc == nil || c.Name < name) {
+			tc, name = c.TaxCategory, c.Name
+		}
+	}
+	return tc
+}
+
+// Summarize groups a year's deductible expenses by the tax category they
+// are deductible under. Reimbursed expenses were paid back, so they aren't
+// deductible and are left out.
+func Summarize(ctx context.Context, year int, db Database) (model.TaxSummary, error) {
+	if year < 1 || year > 9999 {
+		return model.TaxSummary{}, expense.ValidationErrors{{Field: "year", Message: fmt.Sprintf("%d is not a year", year)}}
+	}
+	from, to := fmt.Sprintf("01-01-%04d", year), fmt.Sprintf("12-31-%04d", year)
+	found, err := db.FindExpenses(ctx, model.ExpenseFilter{From: &from, To: &to})
+	if err != nil {
+		return model.TaxSummary{}, fmt.Errorf("failed to find expenses of %d, %w", year, err)
+	}
+	groups := make(map[model.TaxCategory]*model.TaxGroup)
+	for i := range found {
+		e := &found[i]
+		tc := Effective(*e)
+		if tc == nil || (e.ReimbursementStatus != nil && *e.ReimbursementStatus == model.ReimbursementStatusReimbursed) {
+			continue
+		}
+		g, ok := groups[*tc]
+		if !ok {
+			g = &model.TaxGroup{TaxCategory: *tc, Expenses: []*model.Expense{}}
+			groups[*tc] = g
+		}
+		g.Total += e.Amount
+		g.Count++
+		g.Expenses = append(g.Expenses, e)
+	}
+	s := model.TaxSummary{Year: year, Groups: []*model.TaxGroup{}}
+	for _, tc := range model.AllTaxCategory {
+		g, ok := groups[tc]
+		if !ok {
+			continue
+		}
+		g.Total = cents(g.Total)
+		view.Sort(g.Expenses, model.ExpenseSortDateAsc)
+		s.Total += g.Total
+		s.Groups = append(s.Groups, g)
+	}
+	s.Total = cents(s.Total)
+	return s, nil
+}
+
+// cents rounds away the error of adding up amounts as floats.
+func cents(a float64) float64 {
+	return math.Round(a*100) / 100
+}
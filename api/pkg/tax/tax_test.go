@@ -0,0 +1,194 @@
+package tax
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/expense"
+)
+
+type MockDatabase struct {
+	exps   []model.Expense
+	atts   map[int][]model.Attachment
+	filter model.ExpenseFilter
+	set    map[int]*model.TaxCategory
+	listed int
+}
+
+func (mdb *MockDatabase) GetExpense(ctx context.Context, id int) (model.Expense, bool, error) {
+	for _, e := range mdb.exps {
+		if e.Id == id {
+			e.TaxCategory = mdb.set[id]
+			return e, true, nil
+		}
+	}
+	return model.Expense{}, false, nil
+}
+
+func (mdb *MockDatabase) FindExpenses(ctx context.Context, f model.ExpenseFilter) ([]model.Expense, error) {
+	mdb.filter = f
+	return mdb.exps, nil
+}
+
+func (mdb *MockDatabase) ListAttachmentsFor(ctx context.Context, eids []int) (map[int][]model.Attachment, error) {
+	mdb.listed++
+	atts := make(map[int][]model.Attachment)
+	for _, id := range eids {
+		if as, ok := mdb.atts[id]; ok {
+			atts[id] = as
+		}
+	}
+	return atts, nil
+}
+
+func (mdb *MockDatabase) SetCategoryTaxCategory(ctx context.Context, name string, tc *model.TaxCategory) (model.Category, error) {
+	return model.Category{Id: 1, Name: name, TaxCategory: tc}, nil
+}
+
+func (mdb *MockDatabase) SetExpenseTaxCategory(ctx context.Context, eid int, tc *model.TaxCategory) error {
+	mdb.set[eid] = tc
+	return nil
+}
+
+func taxCategory(tc model.TaxCategory) *model.TaxCategory {
+	return &tc
+}
+
+func TestEffective(t *testing.T) {
+	medical, charitable := taxCategory(model.TaxCategoryMedical), taxCategory(model.TaxCategoryCharitable)
+	cases := []struct {
+		name string
+		exp  model.Expense
+		want *model.TaxCategory
+	}{
+		{name: "not deductible", exp: model.Expense{Categories: []model.Category{{Name: "food"}}}, want: nil},
+		{name: "category", exp: model.Expense{Categories: []model.Category{{Name: "food"}, {Name: "pharmacy", TaxCategory: medical}}}, want: medical},
+		{name: "own", exp: model.Expense{TaxCategory: charitable, Categories: []model.Category{{Name: "pharmacy", TaxCategory: medical}}}, want: charitable},
+		{
+			name: "first category by name",
+			exp:  model.Expense{Categories: []model.Category{{Name: "pharmacy", TaxCategory: medical}, {Name: "gifts", TaxCategory: charitable}}},
+			want: charitable,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.want, Effective(c.exp))
+		})
+	}
+}
+
+func newMock() *MockDatabase {
+	medical := taxCategory(model.TaxCategoryMedical)
+	reimbursed := model.ReimbursementStatusReimbursed
+	return &MockDatabase{
+		exps: []model.Expense{
+			{Id: 1, Date: "03-09-2022", Description: "dentist", Amount: 120.1, Categories: []model.Category{{Name: "health", TaxCategory: medical}}},
+			{Id: 2, Date: "01-15-2022", Description: "pharmacy", Amount: 30.2, Categories: []model.Category{{Name: "health", TaxCategory: medical}}},
+			{Id: 3, Date: "02-01-2022", Description: "red cross", Amount: 50, TaxCategory: taxCategory(model.TaxCategoryCharitable)},
+			{Id: 4, Date: "02-02-2022", Description: "groceries", Amount: 80, Categories: []model.Category{{Name: "food"}}},
+			{
+				Id: 5, Date: "04-04-2022", Description: "x-ray", Amount: 300, Categories: []model.Category{{Name: "health", TaxCategory: medical}},
+				Reimbursable: true, ReimbursementStatus: &reimbursed,
+			},
+		},
+		atts: map[int][]model.Attachment{1: {{Id: 7, ExpenseId: 1, Filename: "dentist.pdf"}, {Id: 8, ExpenseId: 1, Filename: "invoice.png"}}},
+		set:  make(map[int]*model.TaxCategory),
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	mock := newMock()
+	s, err := Summarize(context.Background(), 2022, mock)
+	if err != nil {
+		t.Fatalf("error running Summarize func, %v", err)
+	}
+	from, to := "01-01-2022", "12-31-2022"
+	assert.Equal(t, model.ExpenseFilter{From: &from, To: &to}, mock.filter)
+	assert.Equal(t, 2022, s.Year)
+	assert.Equal(t, 200.3, s.Total)
+	if assert.Len(t, s.Groups, 2) {
+		assert.Equal(t, model.TaxCategoryCharitable, s.Groups[0].TaxCategory)
+		assert.Equal(t, 50.0, s.Groups[0].Total)
+		assert.Equal(t, model.TaxCategoryMedical, s.Groups[1].TaxCategory)
+		assert.Equal(t, 150.3, s.Groups[1].Total)
+		assert.Equal(t, 2, s.Groups[1].Count)
+		assert.Equal(t, 2, s.Groups[1].Expenses[0].Id, "oldest first")
+		assert.Equal(t, 1, s.Groups[1].Expenses[1].Id)
+	}
+
+	_, err = Summarize(context.Background(), 0, mock)
+	var ves expense.ValidationErrors
+	if assert.ErrorAs(t, err, &ves) {
+		assert.Equal(t, "year", ves[0].Field)
+	}
+}
+
+func TestSetExpense(t *testing.T) {
+	mock := newMock()
+	e, err := SetExpense(context.Background(), 4, taxCategory(model.TaxCategoryBusiness), mock)
+	if err != nil {
+		t.Fatalf("error running SetExpense func, %v", err)
+	}
+	assert.Equal(t, taxCategory(model.TaxCategoryBusiness), e.TaxCategory)
+
+	_, err = SetCategory(context.Background(), "  ", nil, mock)
+	var ves expense.ValidationErrors
+	assert.ErrorAs(t, err, &ves)
+}
+
+func TestWriteCSV(t *testing.T) {
+	mock := newMock()
+	r, err := Load(context.Background(), 2022, mock)
+	if err != nil {
+		t.Fatalf("error running Load func, %v", err)
+	}
+	assert.Equal(t, 1, mock.listed, "attachments are listed in one query")
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, r); err != nil {
+		t.Fatalf("error running WriteCSV func, %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, [][]string{
+		{"Tax Category", "Date", "Description", "Categories", "Amount", "Attachments"},
+		{"CHARITABLE", "02-01-2022", "red cross", "", "50.00", ""},
+		{"MEDICAL", "01-15-2022", "pharmacy", "health", "30.20", ""},
+		{"MEDICAL", "03-09-2022", "dentist", "health", "120.10", "dentist.pdf; invoice.png"},
+	}, rows)
+
+	r.Summary.Groups[0].Expenses[0].Description = "=HYPERLINK(\"http://evil\")"
+	r.Summary.Groups[1].Expenses[0].Description = "-5 refund"
+	r.Attachments[1] = []model.Attachment{{Filename: "@receipt.pdf"}}
+	buf.Reset()
+	if err := WriteCSV(&buf, r); err != nil {
+		t.Fatalf("error running WriteCSV func, %v", err)
+	}
+	rows, err = csv.NewReader(&buf).ReadAll()
+	assert.Nil(t, err)
+	assert.Equal(t, `'=HYPERLINK("http://evil")`, rows[1][2])
+	assert.Equal(t, "'-5 refund", rows[2][2])
+	assert.Equal(t, "'@receipt.pdf", rows[3][5])
+	assert.Equal(t, "120.10", rows[3][4], "amounts are left alone")
+}
+
+func TestWritePDF(t *testing.T) {
+	r, err := Load(context.Background(), 2022, newMock())
+	if err != nil {
+		t.Fatalf("error running Load func, %v", err)
+	}
+	r.Summary.Groups[0].Expenses[0].Description = "Café donation with a description far too long to fit in its column of the table"
+	var buf bytes.Buffer
+	if err := WritePDF(&buf, r); err != nil {
+		t.Fatalf("error running WritePDF func, %v", err)
+	}
+	assert.True(t, bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")))
+
+	buf.Reset()
+	if err := WritePDF(&buf, Report{Summary: model.TaxSummary{Year: 2021}}); err != nil {
+		t.Fatalf("error running WritePDF func on an empty summary, %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vapor05/financeview/pkg/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ServiceName names the API in exported spans.
+const ServiceName = "financeview-api"
+
+// tracerName names the spans this package starts. Tracers are looked up
+// per span so a provider set up later is always used.
+const tracerName = "github.com/vapor05/financeview/pkg/tracing"
+
+// Setup installs the global tracer provider and W3C trace context
+// propagation. Stdout spans are written to w. The returned function flushes
+// and stops the exporter.
+func Setup(ctx context.Context, c config.Tracing, w io.Writer) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	var exp sdktrace.SpanExporter
+	var err error
+	switch c.Exporter {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exp, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	case "otlp":
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(c.Endpoint)}
+		if c.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exp, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", c.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s trace exporter, %w", c.Exporter, err)
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exp),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(ServiceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// GraphQL is a gqlgen extension tracing each operation and every field
+// that has a resolver.
+type GraphQL struct{}
+
+var (
+	_ graphql.HandlerExtension    = GraphQL{}
+	_ graphql.ResponseInterceptor = GraphQL{}
+	_ graphql.FieldInterceptor    = GraphQL{}
+)
+
+func (GraphQL) ExtensionName() string {
+	return "OpenTelemetry"
+}
+
+func (GraphQL) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+func (GraphQL) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	if !graphql.HasOperationContext(ctx) {
+		return next(ctx)
+	}
+	oc := graphql.GetOperationContext(ctx)
+	name := oc.OperationName
+	if name == "" {
+		name = "anonymous"
+	}
+	opts := []trace.SpanStartOption{trace.WithTimestamp(oc.Stats.OperationStart)}
+	if oc.Operation != nil {
+		opts = append(opts, trace.WithAttributes(attribute.String("graphql.operation.type", string(oc.Operation.Operation))))
+	}
+	opts = append(opts, trace.WithAttributes(attribute.String("graphql.operation.name", oc.OperationName)))
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "graphql "+name, opts...)
+	defer span.End()
+	resp := next(ctx)
+	if resp != nil && len(resp.Errors) > 0 {
+		span.SetStatus(codes.Error, resp.Errors.Error())
+	}
+	return resp
+}
+
+func (GraphQL) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || !fc.IsResolver {
+		return next(ctx)
+	}
+	ctx, span := otel.Tracer(tracerName).Start(ctx, fc.Object+"."+fc.Field.Name, trace.WithAttributes(
+		attribute.String("graphql.field.path", fc.Path().String()),
+	))
+	defer span.End()
+	res, err := next(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return res, err
+}
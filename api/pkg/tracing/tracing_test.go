@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/pkg/config"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func record(t *testing.T) *tracetest.SpanRecorder {
+	sr := tracetest.NewSpanRecorder()
+	prev := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr)))
+	t.Cleanup(func() { otel.SetTracerProvider(prev) })
+	return sr
+}
+
+func TestSetup(t *testing.T) {
+	prev := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(prev)
+	ctx := context.Background()
+	shutdown, err := Setup(ctx, config.Tracing{Exporter: "none"}, nil)
+	if err != nil {
+		t.Fatalf("error running Setup func, %v", err)
+	}
+	assert.Nil(t, shutdown(ctx))
+
+	var buf bytes.Buffer
+	shutdown, err = Setup(ctx, config.Tracing{Exporter: "stdout", SampleRatio: 1}, &buf)
+	if err != nil {
+		t.Fatalf("error running Setup func, %v", err)
+	}
+	_, span := otel.Tracer("test").Start(ctx, "hello")
+	span.End()
+	assert.Nil(t, shutdown(ctx))
+	assert.Contains(t, buf.String(), `"Name":"hello"`)
+	assert.Contains(t, buf.String(), ServiceName)
+
+	_, err = Setup(ctx, config.Tracing{Exporter: "carrier-pigeon"}, nil)
+	assert.Error(t, err)
+}
+
+func TestInterceptResponse(t *testing.T) {
+	sr := record(t)
+	ctx := graphql.WithOperationContext(context.Background(), &graphql.OperationContext{
+		OperationName: "GetExpenses",
+		Operation:     &ast.OperationDefinition{Operation: ast.Query},
+	})
+	GraphQL{}.InterceptResponse(ctx, func(ctx context.Context) *graphql.Response {
+		return &graphql.Response{Errors: gqlerror.List{gqlerror.Errorf("boom")}}
+	})
+	spans := sr.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "graphql GetExpenses", spans[0].Name())
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+	}
+}
+
+func TestInterceptField(t *testing.T) {
+	sr := record(t)
+	field := func(resolver bool) context.Context {
+		return graphql.WithFieldContext(context.Background(), &graphql.FieldContext{
+			Object:     "Query",
+			Field:      graphql.CollectedField{Field: &ast.Field{Name: "expenses", Alias: "expenses"}},
+			IsResolver: resolver,
+		})
+	}
+	_, err := GraphQL{}.InterceptField(field(true), func(ctx context.Context) (interface{}, error) {
+		return nil, errors.New("boom")
+	})
+	assert.Error(t, err)
+	res, err := GraphQL{}.InterceptField(field(false), func(ctx context.Context) (interface{}, error) {
+		return 1, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, res)
+	spans := sr.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "Query.expenses", spans[0].Name())
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+	}
+}
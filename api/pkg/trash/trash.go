@@ -0,0 +1,101 @@
+package trash
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/blob"
+	"github.com/vapor05/financeview/pkg/expense"
+	"github.com/vapor05/financeview/pkg/logging"
+)
+
+// DefaultRetention is how long deleted expenses stay in the trash before
+// they are purged.
+const DefaultRetention = 30 * 24 * time.Hour
+
+type Database interface {
+	SoftDeleteExpense(context.Context, int) error
+	RestoreExpense(context.Context, int) error
+	GetExpense(context.Context, int) (model.Expense, bool, error)
+	ListTrash(context.Context) ([]model.Expense, error)
+	PurgeExpenses(context.Context, time.Time) (int, []string, error)
+	ReleaseStorageKey(context.Context, string, func() error) error
+}
+
+func DeleteExpense(ctx context.Context, id int, db Database) error {
+	if err := db.SoftDeleteExpense(ctx, id); err != nil {
+		return fmt.Errorf("failed to move expense to the trash, %w", err)
+	}
+	return nil
+}
+
+func RestoreExpense(ctx context.Context, id int, db Database) (model.Expense, error) {
+	if err := db.RestoreExpense(ctx, id); err != nil {
+		return model.Expense{}, fmt.Errorf("failed to restore expense, %w", err)
+	}
+	e, ok, err := db.GetExpense(ctx, id)
+	if err != nil {
+		return model.Expense{}, fmt.Errorf("failed to get restored expense, %w", err)
+	}
+	if !ok {
+		return model.Expense{}, &expense.NotFoundError{Id: id}
+	}
+	return e, nil
+}
+
+// ListTrash returns the ledger's deleted expenses, most recently deleted
+// first.
+func ListTrash(ctx context.Context, db Database) ([]*model.Expense, error) {
+	es, err := db.ListTrash(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trash, %w", err)
+	}
+	sort.SliceStable(es, func(i, j int) bool {
+		return *es[i].DeletedAt > *es[j].DeletedAt
+	})
+	out := make([]*model.Expense, len(es))
+	for i := range es {
+		out[i] = &es[i]
+	}
+	return out, nil
+}
+
+// Purge permanently deletes expenses that have been in the trash longer than
+// the retention, along with attachment contents nothing else uses. Each key
+// is checked again as its contents are deleted, in case an attachment of
+// the same contents was saved since the purge. It returns how many expenses
+// were purged.
+func Purge(ctx context.Context, retention time.Duration, db Database, bs blob.Store) (int, error) {
+	n, keys, err := db.PurgeExpenses(ctx, time.Now().UTC().Add(-retention))
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge trash, %w", err)
+	}
+	for _, k := range keys {
+		if err := db.ReleaseStorageKey(ctx, k, func() error { return bs.Delete(ctx, k) }); err != nil {
+			return n, fmt.Errorf("failed to delete purged attachment contents, %w", err)
+		}
+	}
+	return n, nil
+}
+
+// RunPurger purges the trash every interval until the context is done.
+func RunPurger(ctx context.Context, retention time.Duration, every time.Duration, db Database, bs blob.Store) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		n, err := Purge(ctx, retention, db, bs)
+		if err != nil {
+			logging.FromContext(ctx).Error("failed to purge trash", "err", err)
+		} else if n > 0 {
+			logging.FromContext(ctx).Info("purged expenses from the trash", "count", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package trash
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+)
+
+type MockDatabase struct {
+	exp     map[int]model.Expense
+	deleted map[int]time.Time
+	keys    map[int]string
+}
+
+func newMock() *MockDatabase {
+	return &MockDatabase{
+		exp: map[int]model.Expense{
+			1: {Id: 1, Description: "lunch"},
+			2: {Id: 2, Description: "coffee"},
+			3: {Id: 3, Description: "rent"},
+		},
+		deleted: make(map[int]time.Time),
+		keys:    map[int]string{1: "key1"},
+	}
+}
+
+func (mdb *MockDatabase) SoftDeleteExpense(ctx context.Context, id int) error {
+	if _, ok := mdb.exp[id]; !ok {
+		return fmt.Errorf("expense id=%v does not exist", id)
+	}
+	if _, ok := mdb.deleted[id]; ok {
+		return fmt.Errorf("expense id=%v does not exist", id)
+	}
+	mdb.deleted[id] = time.Now().UTC()
+	return nil
+}
+
+func (mdb *MockDatabase) RestoreExpense(ctx context.Context, id int) error {
+	if _, ok := mdb.deleted[id]; !ok {
+		return fmt.Errorf("expense id=%v is not in the trash", id)
+	}
+	delete(mdb.deleted, id)
+	return nil
+}
+
+func (mdb *MockDatabase) GetExpense(ctx context.Context, id int) (model.Expense, bool, error) {
+	if _, ok := mdb.deleted[id]; ok {
+		return model.Expense{}, false, nil
+	}
+	e, ok := mdb.exp[id]
+	return e, ok, nil
+}
+
+func (mdb *MockDatabase) ListTrash(ctx context.Context) ([]model.Expense, error) {
+	var es []model.Expense
+	for id, t := range mdb.deleted {
+		e := mdb.exp[id]
+		d := t.Format(time.RFC3339)
+		e.DeletedAt = &d
+		es = append(es, e)
+	}
+	return es, nil
+}
+
+func (mdb *MockDatabase) PurgeExpenses(ctx context.Context, before time.Time) (int, []string, error) {
+	var keys []string
+	n := 0
+	for id, t := range mdb.deleted {
+		if t.Before(before) {
+			delete(mdb.deleted, id)
+			delete(mdb.exp, id)
+			if k, ok := mdb.keys[id]; ok {
+				keys = append(keys, k)
+				delete(mdb.keys, id)
+			}
+			n++
+		}
+	}
+	return n, keys, nil
+}
+
+func (mdb *MockDatabase) ReleaseStorageKey(ctx context.Context, key string, del func() error) error {
+	for _, k := range mdb.keys {
+		if k == key {
+			return nil
+		}
+	}
+	return del()
+}
+
+type MockBlobs map[string]bool
+
+func (mb MockBlobs) Put(ctx context.Context, key string, r io.Reader) error {
+	mb[key] = true
+	return nil
+}
+
+func (mb MockBlobs) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (mb MockBlobs) Delete(ctx context.Context, key string) error {
+	delete(mb, key)
+	return nil
+}
+
+func TestDeleteRestore(t *testing.T) {
+	ctx := context.Background()
+	mock := newMock()
+	assert.Nil(t, DeleteExpense(ctx, 1, mock))
+	assert.Error(t, DeleteExpense(ctx, 1, mock))
+	assert.Error(t, DeleteExpense(ctx, 9, mock))
+	_, ok, _ := mock.GetExpense(ctx, 1)
+	assert.False(t, ok)
+	actual, err := RestoreExpense(ctx, 1, mock)
+	if err != nil {
+		t.Fatalf("error running RestoreExpense func, %v", err)
+	}
+	assert.Equal(t, model.Expense{Id: 1, Description: "lunch"}, actual)
+	_, err = RestoreExpense(ctx, 2, mock)
+	assert.Error(t, err)
+}
+
+func TestListTrash(t *testing.T) {
+	ctx := context.Background()
+	mock := newMock()
+	now := time.Now().UTC()
+	mock.deleted[1] = now.Add(-2 * time.Hour)
+	mock.deleted[3] = now.Add(-time.Hour)
+	actual, err := ListTrash(ctx, mock)
+	if err != nil {
+		t.Fatalf("error running ListTrash func, %v", err)
+	}
+	var ids []int
+	for _, e := range actual {
+		ids = append(ids, e.Id)
+	}
+	assert.Equal(t, []int{3, 1}, ids)
+}
+
+func TestPurge(t *testing.T) {
+	ctx := context.Background()
+	mock := newMock()
+	bs := MockBlobs{"key1": true, "key2": true}
+	now := time.Now().UTC()
+	mock.deleted[1] = now.Add(-31 * 24 * time.Hour)
+	mock.deleted[2] = now.Add(-time.Hour)
+	n, err := Purge(ctx, DefaultRetention, mock, bs)
+	if err != nil {
+		t.Fatalf("error running Purge func, %v", err)
+	}
+	assert.Equal(t, 1, n)
+	assert.Equal(t, MockBlobs{"key2": true}, bs)
+	assert.NotContains(t, mock.exp, 1)
+	assert.Contains(t, mock.deleted, 2)
+}
+
+func TestPurgeKeepsReusedContents(t *testing.T) {
+	ctx := context.Background()
+	mock := newMock()
+	bs := MockBlobs{"key1": true}
+	mock.deleted[1] = time.Now().UTC().Add(-31 * 24 * time.Hour)
+	mock.keys[3] = "key1"
+	n, err := Purge(ctx, DefaultRetention, mock, bs)
+	if err != nil {
+		t.Fatalf("error running Purge func, %v", err)
+	}
+	assert.Equal(t, 1, n)
+	assert.Equal(t, MockBlobs{"key1": true}, bs, "expense 3 saved the same contents")
+}
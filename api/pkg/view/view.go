@@ -0,0 +1,157 @@
+package view
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/expense"
+)
+
+var ErrNotFound = errors.New("saved view not found")
+
+type Database interface {
+	CreateSavedView(context.Context, model.SavedView) (int, error)
+	UpdateSavedView(context.Context, model.SavedView) error
+	DeleteSavedView(context.Context, int) error
+	GetSavedView(context.Context, int) (model.SavedView, bool, error)
+	ListSavedViews(context.Context) ([]model.SavedView, error)
+	FindExpenses(context.Context, model.ExpenseFilter) ([]model.Expense, error)
+	SummarizeExpenses(context.Context, model.SummaryGroupBy, model.ExpenseFilter) ([]model.SummaryRow, error)
+}
+
+func CreateView(ctx context.Context, nv model.NewSavedView, db Database) (model.SavedView, error) {
+	v, err := newView(nv)
+	if err != nil {
+		return model.SavedView{}, err
+	}
+	if v.Id, err = db.CreateSavedView(ctx, v); err != nil {
+		return model.SavedView{}, fmt.Errorf("failed to create saved view, %w", err)
+	}
+	return v, nil
+}
+
+func UpdateView(ctx context.Context, id int, nv model.NewSavedView, db Database) (model.SavedView, error) {
+	v, err := newView(nv)
+	if err != nil {
+		return model.SavedView{}, err
+	}
+	v.Id = id
+	if err := db.UpdateSavedView(ctx, v); err != nil {
+		return model.SavedView{}, fmt.Errorf("failed to update saved view, %w", err)
+	}
+	return v, nil
+}
+
+func DeleteView(ctx context.Context, id int, db Database) error {
+	if err := db.DeleteSavedView(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete saved view, %w", err)
+	}
+	return nil
+}
+
+func ListViews(ctx context.Context, db Database) ([]*model.SavedView, error) {
+	vs, err := db.ListSavedViews(ctx)
+	if err != nil {
+		return []*model.SavedView{}, fmt.Errorf("failed to list saved views, %w", err)
+	}
+	out := []*model.SavedView{}
+	for i := range vs {
+		out = append(out, &vs[i])
+	}
+	return out, nil
+}
+
+// RunView lists the expenses matching a saved view in its sort order, and
+// totals them when the view has a grouping. Periods are counted from today.
+func RunView(ctx context.Context, id int, db Database) (model.ViewResult, error) {
+	v, ok, err := db.GetSavedView(ctx, id)
+	if err != nil {
+		return model.ViewResult{}, fmt.Errorf("failed to get saved view id=%v, %w", id, err)
+	}
+	if !ok {
+		return model.ViewResult{}, ErrNotFound
+	}
+	f, err := expense.ResolveFilter(v.Filter, time.Now().UTC())
+	if err != nil {
+		return model.ViewResult{}, fmt.Errorf("failed to resolve filter of saved view %q, %w", v.Name, err)
+	}
+	found, err := db.FindExpenses(ctx, f)
+	if err != nil {
+		return model.ViewResult{}, fmt.Errorf("failed to find expenses of saved view %q, %w", v.Name, err)
+	}
+	res := model.ViewResult{View: v, Expenses: []*model.Expense{}}
+	for i := range found {
+		res.Expenses = append(res.Expenses, &found[i])
+	}
+	s := model.ExpenseSortDateDesc
+	if v.Sort != nil {
+		s = *v.Sort
+	}
+	Sort(res.Expenses, s)
+	if v.GroupBy != nil {
+		rows, err := db.SummarizeExpenses(ctx, *v.GroupBy, f)
+		if err != nil {
+			return model.ViewResult{}, fmt.Errorf("failed to summarize expenses of saved view %q, %w", v.Name, err)
+		}
+		res.Summary = []*model.SummaryRow{}
+		for i := range rows {
+			res.Summary = append(res.Summary, &rows[i])
+		}
+	}
+	return res, nil
+}
+
+// Sort orders expenses in place, breaking ties by id.
+func Sort(exps []*model.Expense, s model.ExpenseSort) {
+	dates := make(map[int]time.Time, len(exps))
+	for _, e := range exps {
+		dates[e.Id], _ = time.Parse(expense.DateLayout, e.Date)
+	}
+	sort.Slice(exps, func(i, j int) bool {
+		a, b := exps[i], exps[j]
+		var less, more bool
+		switch s {
+		case model.ExpenseSortAmountAsc, model.ExpenseSortAmountDesc:
+			less, more = a.Amount < b.Amount, a.Amount > b.Amount
+		default:
+			less, more = dates[a.Id].Before(dates[b.Id]), dates[a.Id].After(dates[b.Id])
+		}
+		if s == model.ExpenseSortDateDesc || s == model.ExpenseSortAmountDesc {
+			less, more = more, less
+		}
+		if less != more {
+			return less
+		}
+		return a.Id < b.Id
+	})
+}
+
+// newView checks the input of a saved view. Its filter is checked as it
+// would be when run, but saved as given so periods stay relative.
+func newView(nv model.NewSavedView) (model.SavedView, error) {
+	v := model.SavedView{Name: strings.TrimSpace(nv.Name), Sort: nv.Sort, GroupBy: nv.GroupBy}
+	if nv.Filter != nil {
+		v.Filter = *nv.Filter
+	}
+	var errs expense.ValidationErrors
+	if v.Name == "" {
+		errs = append(errs, &expense.ValidationError{Field: "name", Message: "must not be empty"})
+	}
+	if _, err := expense.ResolveFilter(v.Filter, time.Now().UTC()); err != nil {
+		var ves expense.ValidationErrors
+		if !errors.As(err, &ves) {
+			return model.SavedView{}, err
+		}
+		errs = append(errs, ves...)
+	}
+	if len(errs) > 0 {
+		return model.SavedView{}, errs
+	}
+	v.Filter.Tags = expense.NormalizeTags(v.Filter.Tags)
+	return v, nil
+}
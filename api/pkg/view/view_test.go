@@ -0,0 +1,129 @@
+package view
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/expense"
+)
+
+type MockDatabase struct {
+	views  map[int]model.SavedView
+	exps   []model.Expense
+	filter model.ExpenseFilter
+}
+
+func (mdb *MockDatabase) CreateSavedView(ctx context.Context, v model.SavedView) (int, error) {
+	v.Id = len(mdb.views) + 1
+	mdb.views[v.Id] = v
+	return v.Id, nil
+}
+
+func (mdb *MockDatabase) UpdateSavedView(ctx context.Context, v model.SavedView) error {
+	mdb.views[v.Id] = v
+	return nil
+}
+
+func (mdb *MockDatabase) DeleteSavedView(ctx context.Context, id int) error {
+	delete(mdb.views, id)
+	return nil
+}
+
+func (mdb *MockDatabase) GetSavedView(ctx context.Context, id int) (model.SavedView, bool, error) {
+	v, ok := mdb.views[id]
+	return v, ok, nil
+}
+
+func (mdb *MockDatabase) ListSavedViews(ctx context.Context) ([]model.SavedView, error) {
+	var vs []model.SavedView
+	for _, v := range mdb.views {
+		vs = append(vs, v)
+	}
+	return vs, nil
+}
+
+func (mdb *MockDatabase) FindExpenses(ctx context.Context, f model.ExpenseFilter) ([]model.Expense, error) {
+	mdb.filter = f
+	return mdb.exps, nil
+}
+
+func (mdb *MockDatabase) SummarizeExpenses(ctx context.Context, groupBy model.SummaryGroupBy, f model.ExpenseFilter) ([]model.SummaryRow, error) {
+	return []model.SummaryRow{{Key: "food", Total: 80, Count: 2}}, nil
+}
+
+func TestCreateView(t *testing.T) {
+	mock := &MockDatabase{views: make(map[int]model.SavedView)}
+	quarter := model.PeriodThisQuarter
+	min := 50.0
+	v, err := CreateView(context.Background(), model.NewSavedView{
+		Name:   " Restaurants this quarter ",
+		Filter: &model.ExpenseFilter{Categories: []string{"restaurants"}, Tags: []string{"Work"}, Period: &quarter, MinAmount: &min},
+	}, mock)
+	if err != nil {
+		t.Fatalf("error running CreateView func, %v", err)
+	}
+	want := model.SavedView{
+		Id:     1,
+		Name:   "Restaurants this quarter",
+		Filter: model.ExpenseFilter{Categories: []string{"restaurants"}, Tags: []string{"work"}, Period: &quarter, MinAmount: &min},
+	}
+	assert.Equal(t, want, v)
+	assert.Equal(t, want, mock.views[1], "periods are saved unresolved")
+
+	bad := "2022-01-01"
+	_, err = CreateView(context.Background(), model.NewSavedView{Name: "", Filter: &model.ExpenseFilter{From: &bad}}, mock)
+	var ves expense.ValidationErrors
+	if assert.ErrorAs(t, err, &ves) {
+		assert.Len(t, ves, 2)
+		assert.Equal(t, "name", ves[0].Field)
+		assert.Equal(t, "from", ves[1].Field)
+	}
+	assert.Len(t, mock.views, 1)
+}
+
+func TestRunView(t *testing.T) {
+	month := model.PeriodLastMonth
+	amountAsc := model.ExpenseSortAmountAsc
+	byCategory := model.SummaryGroupByCategory
+	mock := &MockDatabase{
+		views: map[int]model.SavedView{
+			1: {Id: 1, Name: "last month", Filter: model.ExpenseFilter{Period: &month}},
+			2: {Id: 2, Name: "cheapest", Sort: &amountAsc, GroupBy: &byCategory},
+		},
+		exps: []model.Expense{
+			{Id: 1, Date: "03-02-2022", Amount: 30},
+			{Id: 2, Date: "03-09-2022", Amount: 50},
+			{Id: 3, Date: "02-27-2022", Amount: 10},
+			{Id: 4, Date: "03-09-2022", Amount: 10},
+		},
+	}
+	ids := func(exps []*model.Expense) []int {
+		var out []int
+		for _, e := range exps {
+			out = append(out, e.Id)
+		}
+		return out
+	}
+
+	res, err := RunView(context.Background(), 1, mock)
+	if err != nil {
+		t.Fatalf("error running RunView func, %v", err)
+	}
+	assert.Equal(t, []int{2, 4, 1, 3}, ids(res.Expenses), "newest first by default")
+	assert.Nil(t, res.Summary)
+	assert.Nil(t, mock.filter.Period, "periods are resolved to dates")
+	assert.NotNil(t, mock.filter.From)
+	assert.NotNil(t, mock.filter.To)
+
+	res, err = RunView(context.Background(), 2, mock)
+	if err != nil {
+		t.Fatalf("error running RunView func, %v", err)
+	}
+	assert.Equal(t, []int{3, 4, 1, 2}, ids(res.Expenses))
+	assert.Equal(t, []*model.SummaryRow{{Key: "food", Total: 80, Count: 2}}, res.Summary)
+
+	_, err = RunView(context.Background(), 3, mock)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
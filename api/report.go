@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/vapor05/financeview/graph/model"
+	"github.com/vapor05/financeview/pkg/config"
+	"github.com/vapor05/financeview/pkg/ledger"
+	"github.com/vapor05/financeview/pkg/report"
+	"github.com/vapor05/financeview/pkg/store"
+)
+
+// runReport is the report subcommand, writing a ledger's monthly report to
+// a file as HTML or PDF by its extension:
+//
+//	server report -ledger 1 -month 03-2022 -out march.pdf
+//
+// The database is taken from the server's config, -config file and
+// environment, or -db-url.
+func runReport(ctx context.Context, args []string, getenv func(string) string, stderr io.Writer) error {
+	var cfgPath, dbURL, month, out string
+	var lid int
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&cfgPath, "config", "", "path to a YAML or TOML config file")
+	fs.StringVar(&dbURL, "db-url", "", "database connection URL")
+	fs.IntVar(&lid, "ledger", 0, "id of the ledger to report on")
+	fs.StringVar(&month, "month", time.Now().UTC().AddDate(0, -1, 0).Format(report.MonthLayout), "month to report on, as MM-YYYY")
+	fs.StringVar(&out, "out", "", "file to write, ending in .html or .pdf")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if lid <= 0 {
+		return errors.New("-ledger must be the id of a ledger")
+	}
+	write := map[string]func(io.Writer, report.Monthly) error{".html": report.WriteHTML, ".pdf": report.WritePDF}[filepath.Ext(out)]
+	if write == nil {
+		return errors.New("-out must be a file ending in .html or .pdf")
+	}
+	m, err := report.ParseMonth(month)
+	if err != nil {
+		return fmt.Errorf("invalid -month, %w", err)
+	}
+	var cargs []string
+	if cfgPath != "" {
+		cargs = append(cargs, "-config", cfgPath)
+	}
+	if dbURL != "" {
+		cargs = append(cargs, "-db-url", dbURL)
+	}
+	cfg, _, err := config.Load(cargs, getenv)
+	if err != nil {
+		return fmt.Errorf("failed to load config, %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config, %w", err)
+	}
+	db, err := store.NewDatabase(ctx, cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database, %w", err)
+	}
+	defer db.Close()
+	r, err := report.Build(ledger.WithLedger(ctx, lid, model.RoleOwner), m, db)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := write(&buf, r); err != nil {
+		return err
+	}
+	if err := os.WriteFile(out, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write report, %w", err)
+	}
+	return nil
+}
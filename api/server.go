@@ -3,35 +3,106 @@ package main
 import (
 	"bytes"
 	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"io"
-	"io/ioutil"
-	"log"
+	"log/slog"
+	"math"
+	"mime"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 	"github.com/vapor05/financeview/graph"
 	"github.com/vapor05/financeview/graph/generated"
+	"github.com/vapor05/financeview/pkg/attachment"
+	"github.com/vapor05/financeview/pkg/auth"
+	"github.com/vapor05/financeview/pkg/blob"
+	"github.com/vapor05/financeview/pkg/changes"
+	"github.com/vapor05/financeview/pkg/config"
+	"github.com/vapor05/financeview/pkg/expense"
+	"github.com/vapor05/financeview/pkg/gqlguard"
+	"github.com/vapor05/financeview/pkg/ledger"
+	"github.com/vapor05/financeview/pkg/logging"
+	"github.com/vapor05/financeview/pkg/metrics"
+	"github.com/vapor05/financeview/pkg/ratelimit"
+	"github.com/vapor05/financeview/pkg/report"
 	"github.com/vapor05/financeview/pkg/store"
+	"github.com/vapor05/financeview/pkg/suggest"
+	"github.com/vapor05/financeview/pkg/tax"
+	"github.com/vapor05/financeview/pkg/tracing"
+	"github.com/vapor05/financeview/pkg/trash"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Defining the Graphql handler
-func graphqlHandler() (gin.HandlerFunc, error) {
+func graphqlHandler(db *store.Database, bs blob.Store, cb *changes.Broker, cfg config.GraphQL, allow gqlguard.Allowlist, limiter ratelimit.Limiter, origins []string) (gin.HandlerFunc, error) {
 	// NewExecutableSchema and Config are in the generated.go file
 	// Resolver is in the resolver.go file
-	db, err := store.NewDatabase(context.Background(), os.Getenv("DBURL"))
+	sg := suggest.NewRegistry(db)
+	complexity, err := graph.Complexity(cfg.ListCosts)
 	if err != nil {
-		return func(c *gin.Context) {}, fmt.Errorf("failed to connect to database, %w", err)
+		return nil, fmt.Errorf("invalid graphql list costs, %w", err)
 	}
-	h := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{Resolvers: &graph.Resolver{Db: db}}))
-	ghf := func(c *gin.Context) {
-		h.ServeHTTP(c.Writer, c.Request)
+	h := handler.New(generated.NewExecutableSchema(generated.Config{
+		Resolvers:  &graph.Resolver{Db: db, Suggester: sg, Blobs: bs, Changes: cb},
+		Directives: generated.DirectiveRoot{HasRole: graph.HasRole},
+		Complexity: complexity,
+	}))
+	h.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+		Upgrader:              websocket.Upgrader{CheckOrigin: checkOrigin(origins)},
+		InitFunc:              websocketInit(db),
+	})
+	h.AddTransport(transport.Options{})
+	h.AddTransport(transport.GET{})
+	h.AddTransport(transport.POST{})
+	h.AddTransport(transport.MultipartForm{})
+	h.SetErrorPresenter(graph.PresentError)
+	h.SetQueryCache(lru.New(1000))
+	h.Use(extension.Introspection{})
+	// Queries registered by clients are only remembered when they may run
+	// queries outside the allowlist anyway.
+	apq := gqlguard.Cache{Allowlist: allow}
+	if !cfg.AllowlistOnly {
+		apq.Fallback = lru.New(100)
+	}
+	h.Use(extension.AutomaticPersistedQuery{Cache: apq})
+	if cfg.AllowlistOnly {
+		h.Use(gqlguard.AllowlistOnly{Allowlist: allow})
 	}
-	return ghf, nil
+	if cfg.ComplexityLimit > 0 {
+		h.Use(extension.FixedComplexityLimit(cfg.ComplexityLimit))
+	}
+	h.Use(gqlguard.DepthLimit{Max: cfg.MaxDepth})
+	h.Use(ratelimit.Websocket{Limiter: limiter})
+	h.Use(tracing.GraphQL{})
+	h.AroundResponses(logOperation)
+	h.AroundResponses(metrics.Operations)
+	h.AroundFields(metrics.Resolvers)
+	h.AroundFields(graph.SplitValidationErrors)
+	return func(c *gin.Context) {
+		h.ServeHTTP(c.Writer, c.Request)
+	}, nil
 }
 
 // Defining the Playground handler
@@ -43,31 +114,535 @@ func playgroundHandler() gin.HandlerFunc {
 	}
 }
 
-func LogRequest(c *gin.Context) {
-	b, err := io.ReadAll(ioutil.NopCloser(c.Request.Body))
-	if err != nil {
-		log.Printf("error reading request body, %v", err)
-		c.AbortWithStatus(http.StatusInternalServerError)
+// Defining the attachment download handler
+func attachmentHandler(db *store.Database, bs blob.Store) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, _, ok := ledger.FromContext(c.Request.Context()); !ok {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		a, r, err := attachment.Open(c.Request.Context(), id, db, bs)
+		if err != nil {
+			if errors.Is(err, attachment.ErrNotFound) {
+				c.AbortWithStatus(http.StatusNotFound)
+				return
+			}
+			logging.FromContext(c.Request.Context()).Error("failed to open attachment", "attachment_id", id, "err", err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		defer r.Close()
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename}))
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.DataFromReader(http.StatusOK, int64(a.Size), a.ContentType, r, nil)
+	}
+}
+
+// Defining the tax summary export handler, serving /exports/tax/2023.csv or
+// /exports/tax/2023.pdf
+func taxExportHandler(db *store.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, _, ok := ledger.FromContext(c.Request.Context()); !ok {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		file := c.Param("file")
+		ext := path.Ext(file)
+		year, err := strconv.Atoi(strings.TrimSuffix(file, ext))
+		if err != nil || (ext != ".csv" && ext != ".pdf") {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		r, err := tax.Load(c.Request.Context(), year, db)
+		if err != nil {
+			var ves expense.ValidationErrors
+			if errors.As(err, &ves) {
+				c.AbortWithStatus(http.StatusNotFound)
+				return
+			}
+			logging.FromContext(c.Request.Context()).Error("failed to load tax summary", "year", year, "err", err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		var buf bytes.Buffer
+		contentType := "text/csv; charset=utf-8"
+		write := tax.WriteCSV
+		if ext == ".pdf" {
+			contentType, write = "application/pdf", tax.WritePDF
+		}
+		if err := write(&buf, r); err != nil {
+			logging.FromContext(c.Request.Context()).Error("failed to export tax summary", "year", year, "err", err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "tax-summary-" + file}))
+		c.Data(http.StatusOK, contentType, buf.Bytes())
+	}
+}
+
+// Defining the monthly report handler, serving /reports/monthly/03-2022.html
+// to view or print in a browser, or /reports/monthly/03-2022.pdf
+func monthlyReportHandler(db *store.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, _, ok := ledger.FromContext(c.Request.Context()); !ok {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		file := c.Param("file")
+		ext := path.Ext(file)
+		month, err := report.ParseMonth(strings.TrimSuffix(file, ext))
+		if err != nil || (ext != ".html" && ext != ".pdf") {
+			c.AbortWithStatus(http.StatusNotFound)
+			return
+		}
+		r, err := report.Build(c.Request.Context(), month, db)
+		if err != nil {
+			logging.FromContext(c.Request.Context()).Error("failed to build monthly report", "month", file, "err", err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		var buf bytes.Buffer
+		contentType, disposition := "text/html; charset=utf-8", "inline"
+		write := report.WriteHTML
+		if ext == ".pdf" {
+			contentType, disposition, write = "application/pdf", "attachment", report.WritePDF
+		}
+		if err := write(&buf, r); err != nil {
+			logging.FromContext(c.Request.Context()).Error("failed to render monthly report", "month", file, "err", err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		c.Header("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": "report-" + file}))
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Data(http.StatusOK, contentType, buf.Bytes())
 	}
-	log.Printf("Request: %s", b)
-	c.Request.Body = ioutil.NopCloser(bytes.NewReader(b))
+}
+
+// Authenticate puts the user of a bearer session or API token into the
+// request context. Requests without a token pass through unauthenticated so
+// they can register or log in; requests with a bad token are rejected.
+func Authenticate(db *store.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		h := c.GetHeader("Authorization")
+		if h == "" {
+			c.Next()
+			return
+		}
+		ctx, ok, err := authenticate(c.Request.Context(), h, db)
+		if err != nil {
+			logging.FromContext(ctx).Error("failed to authenticate request", "err", err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// authenticate returns ctx carrying the user of an "Authorization: Bearer"
+// value's session or API token, reporting false when the token is bad.
+func authenticate(ctx context.Context, header string, db *store.Database) (context.Context, bool, error) {
+	tok := strings.TrimPrefix(header, "Bearer ")
+	if tok == header || tok == "" {
+		return ctx, false, nil
+	}
+	if auth.IsAPIToken(tok) {
+		u, s, ok, err := auth.AuthenticateAPIToken(ctx, tok, db)
+		if err != nil || !ok {
+			return ctx, false, err
+		}
+		return auth.WithUser(auth.WithScope(ctx, s), u, tok), true, nil
+	}
+	u, ok, err := auth.Authenticate(ctx, tok, db)
+	if err != nil || !ok {
+		return ctx, false, err
+	}
+	return auth.WithUser(ctx, u, tok), true, nil
+}
+
+// websocketInit authenticates subscriptions from the connection_init
+// payload, since browsers can't set headers on websockets. It reads the
+// same Authorization and X-Ledger-Id values as the headers; without them
+// the upgrade request's headers are used.
+func websocketInit(db *store.Database) transport.WebsocketInitFunc {
+	return func(ctx context.Context, p transport.InitPayload) (context.Context, error) {
+		h := p.Authorization()
+		if h == "" {
+			return ctx, nil
+		}
+		ctx, ok, err := authenticate(ctx, h, db)
+		if err != nil {
+			logging.FromContext(ctx).Error("failed to authenticate websocket", "err", err)
+			return nil, errors.New("failed to authenticate")
+		}
+		if !ok {
+			return nil, errors.New("invalid token")
+		}
+		var id int
+		if s := p.GetString("X-Ledger-Id"); s != "" {
+			if id, err = strconv.Atoi(s); err != nil || id <= 0 {
+				return nil, errors.New("invalid X-Ledger-Id")
+			}
+		}
+		ctx, err = ledger.Select(ctx, id, db)
+		if err != nil {
+			if errors.Is(err, ledger.ErrNotMember) {
+				return nil, err
+			}
+			logging.FromContext(ctx).Error("failed to select ledger", "err", err)
+			return nil, errors.New("failed to select ledger")
+		}
+		return ctx, nil
+	}
+}
+
+// checkOrigin lets websockets connect from the server's own origin and the
+// origins CORS allows.
+func checkOrigin(origins []string) func(*http.Request) bool {
+	return func(r *http.Request) bool {
+		o := r.Header.Get("Origin")
+		if o == "" {
+			return true
+		}
+		if u, err := url.Parse(o); err == nil && strings.EqualFold(u.Host, r.Host) {
+			return true
+		}
+		for _, a := range origins {
+			if a == "*" || a == o {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// SelectLedger picks the ledger an authenticated request acts on from the
+// X-Ledger-Id header, falling back to the user's own ledger.
+func SelectLedger(db *store.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := auth.UserFromContext(c.Request.Context()); !ok {
+			c.Next()
+			return
+		}
+		var id int
+		if h := c.GetHeader("X-Ledger-Id"); h != "" {
+			var err error
+			if id, err = strconv.Atoi(h); err != nil || id <= 0 {
+				c.AbortWithStatus(http.StatusBadRequest)
+				return
+			}
+		}
+		ctx, err := ledger.Select(c.Request.Context(), id, db)
+		if err != nil {
+			if errors.Is(err, ledger.ErrNotMember) {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			logging.FromContext(c.Request.Context()).Error("failed to select ledger", "err", err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// RateLimit rejects GraphQL requests over the caller's query or mutation
+// limit with 429 Too Many Requests, saying when to retry. Websocket
+// upgrades are marked so each operation sent over them is charged too. If
+// the limiter's backend fails requests are let through rather than taking
+// the API down.
+func RateLimit(l ratelimit.Limiter) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		op, err := ratelimit.Operation(c.Writer, c.Request, l.Allowlist)
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				c.AbortWithStatus(http.StatusRequestEntityTooLarge)
+				return
+			}
+			c.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		if c.IsWebsocket() {
+			c.Request = c.Request.WithContext(ratelimit.WithWebsocket(ctx, c.ClientIP()))
+		}
+		ok, wait, err := l.Allow(ctx, ratelimit.Key(ctx, c.ClientIP()), op)
+		if err != nil {
+			logging.FromContext(ctx).Error("failed to check rate limit", "err", err)
+			c.Next()
+			return
+		}
+		if !ok {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"errors": []gin.H{{
+				"message":    "rate limit exceeded, retry later",
+				"extensions": gin.H{"code": "RATE_LIMITED"},
+			}}})
+			return
+		}
+		c.Next()
+	}
+}
+
+// healthzHandler reports that the process is up.
+func healthzHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// readyzHandler reports whether the server can take requests: the database
+// is reachable and its schema is the version this build expects.
+func readyzHandler(db *store.Database) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+		defer cancel()
+		if err := db.Ready(ctx); err != nil {
+			logging.FromContext(ctx).Warn("not ready", "err", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	}
+}
+
+// LogRequests gives each request an id, reusing a valid X-Request-Id from
+// the client, and a logger tagged with it, then logs the request once it
+// is done. Bodies are never logged; they hold financial data.
+func LogRequests(l *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		id := c.GetHeader("X-Request-Id")
+		if !logging.ValidRequestId(id) {
+			id = logging.NewRequestId()
+		}
+		c.Header("X-Request-Id", id)
+		ctx := logging.WithRequestId(logging.WithLogger(c.Request.Context(), l), id)
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("trace_id", sc.TraceID().String()))
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.FullPath()),
+			slog.Int("status", c.Writer.Status()),
+			slog.Duration("duration", time.Since(start)),
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
+		level := slog.LevelInfo
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logging.FromContext(ctx).LogAttrs(ctx, level, "request", attrs...)
+	}
+}
+
+// Trace continues the caller's trace from the W3C traceparent header, or
+// starts a new one, and wraps the request in a server span.
+func Trace(c *gin.Context) {
+	ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	ctx, span := otel.Tracer("github.com/vapor05/financeview").Start(ctx, c.Request.Method+" "+route,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", c.Request.Method),
+			attribute.String("http.route", route),
+		),
+	)
+	defer span.End()
+	c.Request = c.Request.WithContext(ctx)
 	c.Next()
+	span.SetAttributes(attribute.Int("http.response.status_code", c.Writer.Status()))
+	if c.Writer.Status() >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(c.Writer.Status()))
+	}
+}
+
+// RecordMetrics counts and times requests by route.
+func RecordMetrics(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+	metrics.ObserveRequest(c.Request.Method, c.FullPath(), c.Writer.Status(), time.Since(start))
+}
+
+// logOperation logs each GraphQL response with its operation name, timing
+// and errors. Variables hold financial data and are only logged at debug
+// level.
+func logOperation(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	resp := next(ctx)
+	l := logging.FromContext(ctx)
+	var attrs []slog.Attr
+	if graphql.HasOperationContext(ctx) {
+		oc := graphql.GetOperationContext(ctx)
+		attrs = append(attrs,
+			slog.String("operation", oc.OperationName),
+			slog.Duration("duration", time.Since(oc.Stats.OperationStart)),
+		)
+		if l.Enabled(ctx, slog.LevelDebug) {
+			attrs = append(attrs, slog.Any("variables", oc.Variables))
+		}
+	}
+	level := slog.LevelInfo
+	if resp != nil && len(resp.Errors) > 0 {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.String("errors", resp.Errors.Error()))
+	}
+	l.LogAttrs(ctx, level, "graphql", attrs...)
+	return resp
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "report" {
+		if err := runReport(context.Background(), os.Args[2:], os.Getenv, os.Stderr); err != nil {
+			// The flag package already printed the usage asked for.
+			if !errors.Is(err, flag.ErrHelp) {
+				fmt.Fprintln(os.Stderr, err)
+			}
+			os.Exit(1)
+		}
+		return
+	}
+	if err := run(os.Args[1:]); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			slog.Error("server stopped", "err", err)
+		}
+		os.Exit(1)
+	}
+}
+
+// run serves the API until it is interrupted, returning once every deferred
+// clean up has run.
+func run(args []string) error {
+	cfg, opts, err := config.Load(args, os.Getenv)
+	if err != nil {
+		return fmt.Errorf("failed to load config, %w", err)
+	}
+	if opts.PrintConfig {
+		fmt.Print(cfg)
+		return nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	logger := logging.New(os.Stderr, cfg.LogLevel)
+	slog.SetDefault(logger)
+	if cfg.LogLevel != "debug" {
+		gin.SetMode(gin.ReleaseMode)
+	}
 	// Setting up Gin
-	r := gin.Default()
-	r.Use(LogRequest)
-	r.Use(cors.New(cors.Config{
-		AllowOrigins: []string{"*"},
-		AllowMethods: []string{"OPTIONS", "POST"},
-		AllowHeaders: []string{"Origin", "Content-Type"},
-	}))
-	gh, err := graphqlHandler()
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.Use(Trace)
+	r.Use(LogRequests(logger))
+	r.Use(RecordMetrics)
+	// Without any allowed origins browsers keep to the same origin.
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		r.Use(cors.New(cors.Config{
+			AllowOrigins: cfg.CORS.AllowedOrigins,
+			AllowMethods: []string{"OPTIONS", "GET", "POST"},
+			AllowHeaders: []string{"Origin", "Content-Type", "Authorization", "X-Ledger-Id"},
+		}))
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing, os.Stdout)
+	if err != nil {
+		return fmt.Errorf("failed to setup tracing, %w", err)
+	}
+	defer func() {
+		sctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(sctx); err != nil {
+			slog.Error("failed to flush traces", "err", err)
+		}
+	}()
+	db, err := store.NewDatabase(ctx, cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database, %w", err)
+	}
+	defer db.Close()
+	bs, err := blob.NewLocalStore(cfg.AttachmentDir)
 	if err != nil {
-		log.Fatalf("failed to setup graphql handler, %v", err)
+		return fmt.Errorf("failed to setup attachment storage, %w", err)
+	}
+	if cfg.Features.TrashPurger {
+		go trash.RunPurger(ctx, time.Duration(cfg.TrashRetention), time.Hour, db, bs)
+	}
+	r.GET("/healthz", healthzHandler)
+	r.GET("/readyz", readyzHandler(db))
+	metrics.Registry.MustRegister(metrics.NewPoolCollector(db.Stat))
+	go metrics.CountExpensesCreatedToday(ctx, time.Minute, db.CountExpensesCreatedSince)
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
+	cb := changes.NewBroker(nil)
+	if cfg.Features.ListenNotify {
+		cb = changes.NewBroker(db)
+		go cb.Listen(ctx, 5*time.Second)
+	}
+	var allow gqlguard.Allowlist
+	if cfg.GraphQL.PersistedQueries != "" {
+		if allow, err = gqlguard.LoadAllowlist(cfg.GraphQL.PersistedQueries); err != nil {
+			return err
+		}
+	}
+	limiter := ratelimit.Limiter{
+		Backend:   ratelimit.NewMemory(),
+		Queries:   ratelimit.PerMinute(cfg.RateLimit.QueriesPerMinute, cfg.RateLimit.QueryBurst),
+		Mutations: ratelimit.PerMinute(cfg.RateLimit.MutationsPerMinute, cfg.RateLimit.MutationBurst),
+		Allowlist: allow,
+	}
+	gql, err := graphqlHandler(db, bs, cb, cfg.GraphQL, allow, limiter, cfg.CORS.AllowedOrigins)
+	if err != nil {
+		return fmt.Errorf("failed to setup graphql, %w", err)
+	}
+	r.Use(Authenticate(db))
+	r.Use(SelectLedger(db))
+	r.POST("/query", RateLimit(limiter), gql)
+	// Subscriptions connect with a websocket upgrade.
+	r.GET("/query", RateLimit(limiter), gql)
+	r.GET("/attachments/:id", attachmentHandler(db, bs))
+	r.GET("/exports/tax/:file", taxExportHandler(db))
+	r.GET("/reports/monthly/:file", monthlyReportHandler(db))
+	if cfg.Features.Playground {
+		r.GET("/", playgroundHandler())
+	}
+
+	srv := &http.Server{Addr: cfg.Listen, Handler: r}
+	errc := make(chan error, 1)
+	go func() {
+		if cfg.TLS.Enabled() {
+			errc <- srv.ListenAndServeTLS(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		} else {
+			errc <- srv.ListenAndServe()
+		}
+	}()
+	slog.Info("listening", "addr", cfg.Listen, "tls", cfg.TLS.Enabled())
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	// Stop catching signals so a second one kills the process outright.
+	stop()
+	slog.Info("shutting down, draining requests", "timeout", cfg.ShutdownTimeout.String())
+	sctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeout))
+	defer cancel()
+	if err := srv.Shutdown(sctx); err != nil {
+		slog.Error("failed to drain requests", "err", err)
 	}
-	r.POST("/query", gh)
-	r.GET("/", playgroundHandler())
-	r.Run()
+	return nil
 }
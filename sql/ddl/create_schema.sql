@@ -1,25 +1,91 @@
 CREATE SCHEMA financeview;
 
+CREATE TABLE financeview.app_user (
+    id SERIAL PRIMARY KEY NOT NULL,
+    email TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    createdate TIMESTAMP,
+    updatedate TIMESTAMP
+);
+
+CREATE TABLE financeview.session (
+    id SERIAL PRIMARY KEY NOT NULL,
+    user_id INT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    expiresat TIMESTAMP NOT NULL,
+    createdate TIMESTAMP
+);
+
+CREATE TABLE financeview.api_token (
+    id SERIAL PRIMARY KEY NOT NULL,
+    user_id INT NOT NULL,
+    name TEXT,
+    scope TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    expiresat TIMESTAMP,
+    lastusedat TIMESTAMP,
+    createdate TIMESTAMP
+);
+
+CREATE TABLE financeview.ledger (
+    id SERIAL PRIMARY KEY NOT NULL,
+    name TEXT,
+    createdate TIMESTAMP,
+    updatedate TIMESTAMP
+);
+
+CREATE TABLE financeview.ledger_member (
+    id SERIAL PRIMARY KEY NOT NULL,
+    ledger_id INT NOT NULL,
+    user_id INT NOT NULL,
+    role TEXT NOT NULL,
+    createdate TIMESTAMP,
+    updatedate TIMESTAMP,
+    UNIQUE (ledger_id, user_id)
+);
+
+CREATE TABLE financeview.ledger_invitation (
+    id SERIAL PRIMARY KEY NOT NULL,
+    ledger_id INT NOT NULL,
+    email TEXT NOT NULL,
+    role TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    invited_by INT,
+    expiresat TIMESTAMP NOT NULL,
+    acceptedat TIMESTAMP,
+    createdate TIMESTAMP
+);
+
 CREATE TABLE financeview.description (
     id SERIAL PRIMARY KEY NOT NULL,
+    ledger_id INT NOT NULL,
     description TEXT,
+    payee_id INT,
     createdate TIMESTAMP,
     updatedate TIMESTAMP
 );
 
 CREATE TABLE financeview.expense (
     id SERIAL PRIMARY KEY NOT NULL,
+    ledger_id INT NOT NULL,
     date DATE,
     description_id INT,
     amount MONEY,
     comment TEXT,
+    reimbursable BOOLEAN,
+    reimbursement_status TEXT,
+    reimbursement_income_id INT,
+    tax_category TEXT,
+    deletedat TIMESTAMP,
     createdate TIMESTAMP,
     updatedate TIMESTAMP
 );
 
 CREATE TABLE financeview.category (
     id SERIAL PRIMARY KEY NOT NULL,
+    ledger_id INT NOT NULL,
     name TEXT,
+    tax_category TEXT,
     createdate TIMESTAMP,
     updatedate TIMESTAMP
 );
@@ -29,4 +95,201 @@ CREATE TABLE financeview.expense_category (
     expense_id INT NOT NULL,
     category_id INT NOT NULL,
     createdate TIMESTAMP
-);
\ No newline at end of file
+);
+
+CREATE TABLE financeview.payee (
+    id SERIAL PRIMARY KEY NOT NULL,
+    ledger_id INT NOT NULL,
+    name TEXT,
+    createdate TIMESTAMP,
+    updatedate TIMESTAMP
+);
+
+CREATE TABLE financeview.payee_alias (
+    id SERIAL PRIMARY KEY NOT NULL,
+    payee_id INT NOT NULL,
+    pattern TEXT,
+    createdate TIMESTAMP
+);
+
+CREATE TABLE financeview.attachment (
+    id SERIAL PRIMARY KEY NOT NULL,
+    ledger_id INT NOT NULL,
+    expense_id INT NOT NULL,
+    filename TEXT,
+    content_type TEXT,
+    size INT,
+    sha256 TEXT,
+    storage_key TEXT,
+    createdate TIMESTAMP
+);
+
+CREATE TABLE financeview.tag (
+    id SERIAL PRIMARY KEY NOT NULL,
+    ledger_id INT NOT NULL,
+    name TEXT,
+    createdate TIMESTAMP,
+    updatedate TIMESTAMP
+);
+
+CREATE TABLE financeview.expense_tag (
+    id SERIAL PRIMARY KEY NOT NULL,
+    expense_id INT NOT NULL,
+    tag_id INT NOT NULL,
+    createdate TIMESTAMP
+);
+
+CREATE TABLE financeview.income (
+    id SERIAL PRIMARY KEY NOT NULL,
+    ledger_id INT NOT NULL,
+    date DATE,
+    description TEXT,
+    amount MONEY,
+    comment TEXT,
+    createdate TIMESTAMP,
+    updatedate TIMESTAMP
+);
+
+CREATE TABLE financeview.audit_log (
+    id SERIAL PRIMARY KEY NOT NULL,
+    ledger_id INT NOT NULL,
+    actor_id INT,
+    entity TEXT NOT NULL,
+    entity_id INT NOT NULL,
+    expense_id INT,
+    action TEXT NOT NULL,
+    before JSONB,
+    after JSONB,
+    createdate TIMESTAMP
+);
+
+CREATE INDEX audit_log_ledger_idx ON financeview.audit_log (ledger_id, id);
+CREATE INDEX audit_log_expense_idx ON financeview.audit_log (expense_id);
+
+CREATE FUNCTION financeview.audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'financeview.audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+BEFORE UPDATE OR DELETE ON financeview.audit_log
+FOR EACH ROW EXECUTE FUNCTION financeview.audit_log_append_only();
+
+-- expense_search holds the full-text search document of each expense, kept
+-- current by triggers on the tables it is built from. Descriptions weigh the
+-- most, then categories and tags, then comments and last the month of the
+-- expense, so "plumber march" finds March's plumber.
+CREATE TABLE financeview.expense_search (
+    expense_id INT PRIMARY KEY NOT NULL,
+    document TSVECTOR NOT NULL
+);
+
+CREATE INDEX expense_search_document_idx ON financeview.expense_search USING GIN (document);
+
+CREATE FUNCTION financeview.expense_search_text(eid INT) RETURNS TABLE (description TEXT, labels TEXT, comment TEXT, month TEXT) AS $$
+    SELECT d.description,
+        concat_ws(' ',
+            (SELECT string_agg(c.name, ' ') FROM financeview.expense_category AS ec
+                INNER JOIN financeview.category AS c ON ec.category_id = c.id
+                WHERE ec.expense_id = e.id),
+            (SELECT string_agg(t.name, ' ') FROM financeview.expense_tag AS et
+                INNER JOIN financeview.tag AS t ON et.tag_id = t.id
+                WHERE et.expense_id = e.id)),
+        e.comment,
+        to_char(e.date, 'FMMonth YYYY')
+    FROM financeview.expense AS e
+    LEFT JOIN financeview.description AS d
+    ON e.description_id = d.id
+    WHERE e.id = eid;
+$$ LANGUAGE sql STABLE;
+
+CREATE FUNCTION financeview.refresh_expense_search(eid INT) RETURNS void AS $$
+    INSERT INTO financeview.expense_search (expense_id, document)
+    SELECT eid,
+        setweight(to_tsvector('english', coalesce(t.description, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(t.labels, '')), 'B') ||
+        setweight(to_tsvector('english', coalesce(t.comment, '')), 'C') ||
+        setweight(to_tsvector('english', coalesce(t.month, '')), 'D')
+    FROM financeview.expense_search_text(eid) AS t
+    ON CONFLICT (expense_id) DO UPDATE SET document = EXCLUDED.document;
+$$ LANGUAGE sql;
+
+CREATE FUNCTION financeview.expense_search_changed() RETURNS trigger AS $$
+BEGIN
+    IF TG_TABLE_NAME = 'expense' AND TG_OP = 'DELETE' THEN
+        DELETE FROM financeview.expense_search WHERE expense_id = OLD.id;
+    ELSIF TG_TABLE_NAME = 'expense' THEN
+        PERFORM financeview.refresh_expense_search(NEW.id);
+    ELSIF TG_TABLE_NAME = 'description' THEN
+        PERFORM financeview.refresh_expense_search(e.id) FROM financeview.expense AS e WHERE e.description_id = NEW.id;
+    ELSIF TG_TABLE_NAME = 'category' THEN
+        PERFORM financeview.refresh_expense_search(ec.expense_id) FROM financeview.expense_category AS ec WHERE ec.category_id = NEW.id;
+    ELSIF TG_TABLE_NAME = 'tag' THEN
+        PERFORM financeview.refresh_expense_search(et.expense_id) FROM financeview.expense_tag AS et WHERE et.tag_id = NEW.id;
+    ELSIF TG_OP = 'DELETE' THEN
+        PERFORM financeview.refresh_expense_search(OLD.expense_id);
+    ELSE
+        PERFORM financeview.refresh_expense_search(NEW.expense_id);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER expense_search_changed
+AFTER INSERT OR UPDATE OR DELETE ON financeview.expense
+FOR EACH ROW EXECUTE FUNCTION financeview.expense_search_changed();
+
+CREATE TRIGGER expense_search_changed
+AFTER UPDATE OF description ON financeview.description
+FOR EACH ROW EXECUTE FUNCTION financeview.expense_search_changed();
+
+CREATE TRIGGER expense_search_changed
+AFTER UPDATE OF name ON financeview.category
+FOR EACH ROW EXECUTE FUNCTION financeview.expense_search_changed();
+
+CREATE TRIGGER expense_search_changed
+AFTER UPDATE OF name ON financeview.tag
+FOR EACH ROW EXECUTE FUNCTION financeview.expense_search_changed();
+
+CREATE TRIGGER expense_search_changed
+AFTER INSERT OR DELETE ON financeview.expense_category
+FOR EACH ROW EXECUTE FUNCTION financeview.expense_search_changed();
+
+CREATE TRIGGER expense_search_changed
+AFTER INSERT OR DELETE ON financeview.expense_tag
+FOR EACH ROW EXECUTE FUNCTION financeview.expense_search_changed();
+
+-- saved_view holds named expense filters, stored as the JSON of their
+-- ExpenseFilter input, with the sort and grouping to show them in.
+CREATE TABLE financeview.saved_view (
+    id SERIAL PRIMARY KEY NOT NULL,
+    ledger_id INT NOT NULL,
+    name TEXT NOT NULL,
+    filter JSONB NOT NULL,
+    sort TEXT,
+    group_by TEXT,
+    createdate TIMESTAMP,
+    updatedate TIMESTAMP,
+    CONSTRAINT saved_view_name_key UNIQUE (ledger_id, name)
+);
+
+-- budget holds how much a ledger means to spend on a category each month.
+CREATE TABLE financeview.budget (
+    id SERIAL PRIMARY KEY NOT NULL,
+    ledger_id INT NOT NULL,
+    category_id INT NOT NULL,
+    amount MONEY NOT NULL,
+    createdate TIMESTAMP,
+    updatedate TIMESTAMP,
+    CONSTRAINT budget_category_key UNIQUE (category_id)
+);
+
+-- schema_version holds the version of this schema, which must match
+-- store.SchemaVersion for the API to report itself ready. Bump both
+-- together whenever the schema changes.
+CREATE TABLE financeview.schema_version (
+    version INT NOT NULL
+);
+
+INSERT INTO financeview.schema_version (version) VALUES (5);
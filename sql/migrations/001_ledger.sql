@@ -0,0 +1,193 @@
+-- Migrates a database created before schema versions, holding only
+-- descriptions, expenses and categories, to version 1: user accounts,
+-- ledgers, payees, attachments, tags, reimbursements, the trash and the
+-- audit log. Run it once with psql, in a transaction, naming the user who
+-- will own the existing expenses and their bcrypt password hash:
+--
+--   psql -v owner_email=you@example.com \
+--     -v owner_password_hash="$(htpasswd -nbBC 10 '' 'password' | cut -d: -f2)" \
+--     -f sql/migrations/001_ledger.sql
+--
+-- Every existing row is moved into that user's Personal ledger, so nothing
+-- is hidden from them once queries are scoped to ledgers.
+BEGIN;
+
+CREATE TABLE financeview.app_user (
+    id SERIAL PRIMARY KEY NOT NULL,
+    email TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    createdate TIMESTAMP,
+    updatedate TIMESTAMP
+);
+
+CREATE TABLE financeview.session (
+    id SERIAL PRIMARY KEY NOT NULL,
+    user_id INT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    expiresat TIMESTAMP NOT NULL,
+    createdate TIMESTAMP
+);
+
+CREATE TABLE financeview.api_token (
+    id SERIAL PRIMARY KEY NOT NULL,
+    user_id INT NOT NULL,
+    name TEXT,
+    scope TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    expiresat TIMESTAMP,
+    lastusedat TIMESTAMP,
+    createdate TIMESTAMP
+);
+
+CREATE TABLE financeview.ledger (
+    id SERIAL PRIMARY KEY NOT NULL,
+    name TEXT,
+    createdate TIMESTAMP,
+    updatedate TIMESTAMP
+);
+
+CREATE TABLE financeview.ledger_member (
+    id SERIAL PRIMARY KEY NOT NULL,
+    ledger_id INT NOT NULL,
+    user_id INT NOT NULL,
+    role TEXT NOT NULL,
+    createdate TIMESTAMP,
+    updatedate TIMESTAMP,
+    UNIQUE (ledger_id, user_id)
+);
+
+CREATE TABLE financeview.ledger_invitation (
+    id SERIAL PRIMARY KEY NOT NULL,
+    ledger_id INT NOT NULL,
+    email TEXT NOT NULL,
+    role TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    invited_by INT,
+    expiresat TIMESTAMP NOT NULL,
+    acceptedat TIMESTAMP,
+    createdate TIMESTAMP
+);
+
+ALTER TABLE financeview.description
+    ADD COLUMN ledger_id INT,
+    ADD COLUMN payee_id INT;
+
+ALTER TABLE financeview.expense
+    ADD COLUMN ledger_id INT,
+    ADD COLUMN reimbursable BOOLEAN,
+    ADD COLUMN reimbursement_status TEXT,
+    ADD COLUMN reimbursement_income_id INT,
+    ADD COLUMN deletedat TIMESTAMP;
+
+ALTER TABLE financeview.category ADD COLUMN ledger_id INT;
+
+CREATE TABLE financeview.payee (
+    id SERIAL PRIMARY KEY NOT NULL,
+    ledger_id INT NOT NULL,
+    name TEXT,
+    createdate TIMESTAMP,
+    updatedate TIMESTAMP
+);
+
+CREATE TABLE financeview.payee_alias (
+    id SERIAL PRIMARY KEY NOT NULL,
+    payee_id INT NOT NULL,
+    pattern TEXT,
+    createdate TIMESTAMP
+);
+
+CREATE TABLE financeview.attachment (
+    id SERIAL PRIMARY KEY NOT NULL,
+    ledger_id INT NOT NULL,
+    expense_id INT NOT NULL,
+    filename TEXT,
+    content_type TEXT,
+    size INT,
+    sha256 TEXT,
+    storage_key TEXT,
+    createdate TIMESTAMP
+);
+
+CREATE TABLE financeview.tag (
+    id SERIAL PRIMARY KEY NOT NULL,
+    ledger_id INT NOT NULL,
+    name TEXT,
+    createdate TIMESTAMP,
+    updatedate TIMESTAMP
+);
+
+CREATE TABLE financeview.expense_tag (
+    id SERIAL PRIMARY KEY NOT NULL,
+    expense_id INT NOT NULL,
+    tag_id INT NOT NULL,
+    createdate TIMESTAMP
+);
+
+CREATE TABLE financeview.income (
+    id SERIAL PRIMARY KEY NOT NULL,
+    ledger_id INT NOT NULL,
+    date DATE,
+    description TEXT,
+    amount MONEY,
+    comment TEXT,
+    createdate TIMESTAMP,
+    updatedate TIMESTAMP
+);
+
+CREATE TABLE financeview.audit_log (
+    id SERIAL PRIMARY KEY NOT NULL,
+    ledger_id INT NOT NULL,
+    actor_id INT,
+    entity TEXT NOT NULL,
+    entity_id INT NOT NULL,
+    expense_id INT,
+    action TEXT NOT NULL,
+    before JSONB,
+    after JSONB,
+    createdate TIMESTAMP
+);
+
+CREATE INDEX audit_log_ledger_idx ON financeview.audit_log (ledger_id, id);
+CREATE INDEX audit_log_expense_idx ON financeview.audit_log (expense_id);
+
+CREATE FUNCTION financeview.audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'financeview.audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_log_append_only
+BEFORE UPDATE OR DELETE ON financeview.audit_log
+FOR EACH ROW EXECUTE FUNCTION financeview.audit_log_append_only();
+
+-- The owner gets the Personal ledger every user is given when they
+-- register, and it takes in every existing row.
+WITH owner AS (
+    INSERT INTO financeview.app_user (email, password_hash, createdate)
+    VALUES (lower(trim(:'owner_email')), :'owner_password_hash', now() AT TIME ZONE 'UTC')
+    RETURNING id
+), personal AS (
+    INSERT INTO financeview.ledger (name, createdate)
+    VALUES ('Personal', now() AT TIME ZONE 'UTC')
+    RETURNING id
+)
+INSERT INTO financeview.ledger_member (ledger_id, user_id, role, createdate)
+SELECT personal.id, owner.id, 'OWNER', now() AT TIME ZONE 'UTC' FROM owner, personal;
+
+UPDATE financeview.description SET ledger_id = (SELECT id FROM financeview.ledger);
+UPDATE financeview.expense SET ledger_id = (SELECT id FROM financeview.ledger);
+UPDATE financeview.category SET ledger_id = (SELECT id FROM financeview.ledger);
+
+ALTER TABLE financeview.description ALTER COLUMN ledger_id SET NOT NULL;
+ALTER TABLE financeview.expense ALTER COLUMN ledger_id SET NOT NULL;
+ALTER TABLE financeview.category ALTER COLUMN ledger_id SET NOT NULL;
+
+-- schema_version holds the version of the schema, which must match
+-- store.SchemaVersion for the API to report itself ready.
+CREATE TABLE financeview.schema_version (
+    version INT NOT NULL
+);
+
+INSERT INTO financeview.schema_version (version) VALUES (1);
+
+COMMIT;
@@ -0,0 +1,94 @@
+-- Migrates a version 1 schema to version 2, adding full-text search of
+-- expenses. Run it once, in a transaction, against databases created before
+-- expense_search was added to sql/ddl/create_schema.sql.
+BEGIN;
+
+-- expense_search holds the full-text search document of each expense, kept
+-- current by triggers on the tables it is built from. Descriptions weigh the
+-- most, then categories and tags, then comments and last the month of the
+-- expense, so "plumber march" finds March's plumber.
+CREATE TABLE financeview.expense_search (
+    expense_id INT PRIMARY KEY NOT NULL,
+    document TSVECTOR NOT NULL
+);
+
+CREATE INDEX expense_search_document_idx ON financeview.expense_search USING GIN (document);
+
+CREATE FUNCTION financeview.expense_search_text(eid INT) RETURNS TABLE (description TEXT, labels TEXT, comment TEXT, month TEXT) AS $$
+    SELECT d.description,
+        concat_ws(' ',
+            (SELECT string_agg(c.name, ' ') FROM financeview.expense_category AS ec
+                INNER JOIN financeview.category AS c ON ec.category_id = c.id
+                WHERE ec.expense_id = e.id),
+            (SELECT string_agg(t.name, ' ') FROM financeview.expense_tag AS et
+                INNER JOIN financeview.tag AS t ON et.tag_id = t.id
+                WHERE et.expense_id = e.id)),
+        e.comment,
+        to_char(e.date, 'FMMonth YYYY')
+    FROM financeview.expense AS e
+    LEFT JOIN financeview.description AS d
+    ON e.description_id = d.id
+    WHERE e.id = eid;
+$$ LANGUAGE sql STABLE;
+
+CREATE FUNCTION financeview.refresh_expense_search(eid INT) RETURNS void AS $$
+    INSERT INTO financeview.expense_search (expense_id, document)
+    SELECT eid,
+        setweight(to_tsvector('english', coalesce(t.description, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(t.labels, '')), 'B') ||
+        setweight(to_tsvector('english', coalesce(t.comment, '')), 'C') ||
+        setweight(to_tsvector('english', coalesce(t.month, '')), 'D')
+    FROM financeview.expense_search_text(eid) AS t
+    ON CONFLICT (expense_id) DO UPDATE SET document = EXCLUDED.document;
+$$ LANGUAGE sql;
+
+CREATE FUNCTION financeview.expense_search_changed() RETURNS trigger AS $$
+BEGIN
+    IF TG_TABLE_NAME = 'expense' AND TG_OP = 'DELETE' THEN
+        DELETE FROM financeview.expense_search WHERE expense_id = OLD.id;
+    ELSIF TG_TABLE_NAME = 'expense' THEN
+        PERFORM financeview.refresh_expense_search(NEW.id);
+    ELSIF TG_TABLE_NAME = 'description' THEN
+        PERFORM financeview.refresh_expense_search(e.id) FROM financeview.expense AS e WHERE e.description_id = NEW.id;
+    ELSIF TG_TABLE_NAME = 'category' THEN
+        PERFORM financeview.refresh_expense_search(ec.expense_id) FROM financeview.expense_category AS ec WHERE ec.category_id = NEW.id;
+    ELSIF TG_TABLE_NAME = 'tag' THEN
+        PERFORM financeview.refresh_expense_search(et.expense_id) FROM financeview.expense_tag AS et WHERE et.tag_id = NEW.id;
+    ELSIF TG_OP = 'DELETE' THEN
+        PERFORM financeview.refresh_expense_search(OLD.expense_id);
+    ELSE
+        PERFORM financeview.refresh_expense_search(NEW.expense_id);
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER expense_search_changed
+AFTER INSERT OR UPDATE OR DELETE ON financeview.expense
+FOR EACH ROW EXECUTE FUNCTION financeview.expense_search_changed();
+
+CREATE TRIGGER expense_search_changed
+AFTER UPDATE OF description ON financeview.description
+FOR EACH ROW EXECUTE FUNCTION financeview.expense_search_changed();
+
+CREATE TRIGGER expense_search_changed
+AFTER UPDATE OF name ON financeview.category
+FOR EACH ROW EXECUTE FUNCTION financeview.expense_search_changed();
+
+CREATE TRIGGER expense_search_changed
+AFTER UPDATE OF name ON financeview.tag
+FOR EACH ROW EXECUTE FUNCTION financeview.expense_search_changed();
+
+CREATE TRIGGER expense_search_changed
+AFTER INSERT OR DELETE ON financeview.expense_category
+FOR EACH ROW EXECUTE FUNCTION financeview.expense_search_changed();
+
+CREATE TRIGGER expense_search_changed
+AFTER INSERT OR DELETE ON financeview.expense_tag
+FOR EACH ROW EXECUTE FUNCTION financeview.expense_search_changed();
+
+SELECT financeview.refresh_expense_search(id) FROM financeview.expense;
+
+INSERT INTO financeview.schema_version (version) VALUES (2);
+
+COMMIT;
@@ -0,0 +1,20 @@
+-- Migrates a version 2 schema to version 3, adding saved views.
+BEGIN;
+
+-- saved_view holds named expense filters, stored as the JSON of their
+-- ExpenseFilter input, with the sort and grouping to show them in.
+CREATE TABLE financeview.saved_view (
+    id SERIAL PRIMARY KEY NOT NULL,
+    ledger_id INT NOT NULL,
+    name TEXT NOT NULL,
+    filter JSONB NOT NULL,
+    sort TEXT,
+    group_by TEXT,
+    createdate TIMESTAMP,
+    updatedate TIMESTAMP,
+    CONSTRAINT saved_view_name_key UNIQUE (ledger_id, name)
+);
+
+INSERT INTO financeview.schema_version (version) VALUES (3);
+
+COMMIT;
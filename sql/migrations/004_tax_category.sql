@@ -0,0 +1,11 @@
+-- Migrates a version 3 schema to version 4, adding tax categories.
+BEGIN;
+
+-- A category's tax category marks its expenses deductible. An expense's own
+-- tax category takes precedence over its categories'.
+ALTER TABLE financeview.category ADD COLUMN tax_category TEXT;
+ALTER TABLE financeview.expense ADD COLUMN tax_category TEXT;
+
+INSERT INTO financeview.schema_version (version) VALUES (4);
+
+COMMIT;
@@ -0,0 +1,17 @@
+-- Migrates a version 4 schema to version 5, adding monthly category budgets.
+BEGIN;
+
+-- budget holds how much a ledger means to spend on a category each month.
+CREATE TABLE financeview.budget (
+    id SERIAL PRIMARY KEY NOT NULL,
+    ledger_id INT NOT NULL,
+    category_id INT NOT NULL,
+    amount MONEY NOT NULL,
+    createdate TIMESTAMP,
+    updatedate TIMESTAMP,
+    CONSTRAINT budget_category_key UNIQUE (category_id)
+);
+
+INSERT INTO financeview.schema_version (version) VALUES (5);
+
+COMMIT;